@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/rbroggi/crawler/crawler"
+	log "github.com/sirupsen/logrus"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// aiPolicyUsage is printed when the ai-policy sub-command is wrongly invoked
+const aiPolicyUsage = "usage: web-crawler ai-policy [-url=<url_to_be_crawled>]"
+
+// runAIPolicy implements the `ai-policy` sub-command: it reports the AI-crawl
+// policy files published by the site and crawls it to find the pages declaring
+// AI opt-out directives
+func runAIPolicy(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("ai-policy", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), aiPolicyUsage)
+		fs.PrintDefaults()
+	}
+	rootURL := fs.String("url", "http://localhost:8080/index.html", "URL to be recursively crawled")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if err := loadConfig(fs, os.LookupEnv); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 1
+	}
+
+	baseURL, err := url.Parse(*rootURL)
+	if err != nil {
+		log.Errorf("Error while parsing root URL: [%v]", err)
+		return 1
+	}
+
+	policy, err := crawler.GetAIPolicy(ctx, baseURL)
+	if err != nil {
+		log.Errorf("Error while getting AI policy files: [%v]", err)
+		return 2
+	}
+
+	var mut sync.Mutex
+	pages := make(map[string][]string)
+	visit := func(p *crawler.Page) {
+		if directives := crawler.AIDirectives(p); len(directives) > 0 {
+			mut.Lock()
+			pages[p.URL.String()] = directives
+			mut.Unlock()
+		}
+	}
+	if err := crawler.NewCrawler().CrawlPages(ctx, baseURL, visit); err != nil {
+		log.Printf("Error while crawling: [%v]\n", err)
+		return 2
+	}
+
+	WriteAIPolicyToStdOut(policy, pages)
+	return 0
+}
+
+// WriteAIPolicyToStdOut writes to stdout the AI-crawl policy report: the presence
+// of the policy files and the pages declaring AI opt-out directives
+func WriteAIPolicyToStdOut(policy *crawler.AIPolicy, pages map[string][]string) {
+	var b strings.Builder
+	for _, f := range []struct{ name, content string }{
+		{"llms.txt", policy.LLMsTxt},
+		{"ai.txt", policy.AITxt},
+	} {
+		status := "absent"
+		if f.content != "" {
+			status = fmt.Sprintf("present (%d bytes)", len(f.content))
+		}
+		if _, err := fmt.Fprintf(&b, "%s: %s\n", f.name, status); err != nil {
+			log.Errorf("Error while writing into strings.Builder")
+			return
+		}
+	}
+
+	urls := make([]string, 0, len(pages))
+	for u := range pages {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	if _, err := fmt.Fprintf(&b, "pages with ai directives: %d\n", len(urls)); err != nil {
+		log.Errorf("Error while writing into strings.Builder")
+		return
+	}
+	for _, u := range urls {
+		if _, err := fmt.Fprintf(&b, "page: %s | directives: %s\n", u, strings.Join(pages[u], ",")); err != nil {
+			log.Errorf("Error while writing into strings.Builder")
+			return
+		}
+	}
+	fmt.Print(b.String())
+}
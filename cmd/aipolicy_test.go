@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/rbroggi/crawler/crawler"
+)
+
+func ExampleWriteAIPolicyToStdOut() {
+	WriteAIPolicyToStdOut(&crawler.AIPolicy{LLMsTxt: "# my site\n"}, map[string][]string{
+		"https://my-web-site.com/b": {"noai"},
+		"https://my-web-site.com/a": {"noai", "noimageai"},
+	})
+
+	// Output:
+	// llms.txt: present (10 bytes)
+	// ai.txt: absent
+	// pages with ai directives: 2
+	// page: https://my-web-site.com/a | directives: noai,noimageai
+	// page: https://my-web-site.com/b | directives: noai
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"github.com/rbroggi/crawler/crawler"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// formFields are the fields of a form set with a repeated flag.
+// It implements flag.Value
+type formFields url.Values
+
+func (f formFields) String() string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// the values are credentials, they are not printed
+	return strings.Join(names, ",")
+}
+
+// Set adds a field in the `<name>=<value>` syntax, the value can contain commas
+func (f formFields) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("invalid field %q, expected name=value", value)
+	}
+	url.Values(f).Add(value[:i], value[i+1:])
+	return nil
+}
+
+// authRealms are the realms set with the repeated -auth-realm flag.
+// It implements flag.Value
+type authRealms []crawler.AuthRealm
+
+func (a *authRealms) String() string {
+	if a == nil {
+		return ""
+	}
+	realms := make([]string, 0, len(*a))
+	for _, r := range *a {
+		// the header values are credentials, they are not printed
+		names := make([]string, 0, len(r.Header))
+		for name := range r.Header {
+			names = append(names, name)
+		}
+		realms = append(realms, r.Host+r.PathPrefix+"="+strings.Join(names, ","))
+	}
+	return strings.Join(realms, " ")
+}
+
+// Set adds a header to the realm of a path prefix, optionally preceded by a host, in
+// the `<[host]/prefix>=<name>: <value>` syntax (e.g. `/api/=Authorization: Bearer t0k3n`)
+func (a *authRealms) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("invalid realm %q, expected <prefix>=<name>: <value>", value)
+	}
+	scope := value[:i]
+	name, v, err := parseHeader(value[i+1:])
+	if err != nil {
+		return err
+	}
+
+	host, prefix := "", scope
+	if !strings.HasPrefix(scope, "/") {
+		host, prefix = scope, "/"
+		if k := strings.Index(scope, "/"); k >= 0 {
+			host, prefix = scope[:k], scope[k:]
+		}
+	}
+	for k := range *a {
+		if r := &(*a)[k]; r.Host == host && r.PathPrefix == prefix {
+			r.Header.Add(name, v)
+			return nil
+		}
+	}
+	*a = append(*a, crawler.AuthRealm{Host: host, PathPrefix: prefix, Header: http.Header{}})
+	(*a)[len(*a)-1].Header.Add(name, v)
+	return nil
+}
+
+// scoped returns the realms with the realms without host restricted to each of
+// hosts, the realms are kept as is without hosts. The restricted realms come last
+// so that the realms of the command line restricted to a host still win over them
+func (a authRealms) scoped(hosts []string) []crawler.AuthRealm {
+	if len(hosts) == 0 {
+		return a
+	}
+	realms := make([]crawler.AuthRealm, 0, len(a))
+	var unrestricted []crawler.AuthRealm
+	for _, r := range a {
+		if r.Host != "" {
+			realms = append(realms, r)
+			continue
+		}
+		for _, host := range hosts {
+			r.Host = host
+			unrestricted = append(unrestricted, r)
+		}
+	}
+	return append(realms, unrestricted...)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix is the prefix of the environment variables that can be used
+// to configure the command line flags
+const envPrefix = "CRAWLER_"
+
+// envName returns the environment variable name bound to a flag name:
+// the flag name is upper-cased, dashes are replaced by underscores and
+// the result is prefixed with envPrefix (e.g. `user-agent` -> `CRAWLER_USER_AGENT`)
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// configFlag is the name of the flag of the configuration file completing the
+// flags that are neither set in the command line nor in the environment
+const configFlag = "config"
+
+// loadConfig completes the configuration of an already parsed flag set. The
+// precedence is: explicitly set flag > environment variable > flags of the
+// configuration file set with -config, if the flag set has one > default value.
+// lookup is used to retrieve the environment variables (os.LookupEnv in production)
+func loadConfig(fs *flag.FlagSet, lookup func(string) (string, bool)) error {
+	// collect the flags explicitly set in the command line
+	set := make(map[string]struct{})
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = struct{}{}
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		// command line flags have precedence over the environment
+		if _, ok := set[f.Name]; ok {
+			return
+		}
+		v, ok := lookup(envName(f.Name))
+		if !ok {
+			return
+		}
+		if e := fs.Set(f.Name, v); e != nil {
+			err = fmt.Errorf("invalid value %q for environment variable %s - %v", v, envName(f.Name), e)
+		}
+		set[f.Name] = struct{}{}
+	})
+	if err != nil {
+		return err
+	}
+
+	// the environment variables have precedence over the configuration file
+	f := fs.Lookup(configFlag)
+	if f == nil || f.Value.String() == "" {
+		return nil
+	}
+	config, err := readConfigFile(f.Value.String())
+	if err != nil {
+		return fmt.Errorf("invalid configuration file %s - %v", f.Value, err)
+	}
+	return config.setFlags(fs, set)
+}
+
+// mustLoadConfig parses the program command line and completes it with the
+// environment variables, exiting the program if the configuration is invalid
+func mustLoadConfig() {
+	flag.Parse()
+	if err := loadConfig(flag.CommandLine, os.LookupEnv); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
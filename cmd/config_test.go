@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_envName(t *testing.T) {
+	assert.Equal(t, "CRAWLER_URL", envName("url"))
+	assert.Equal(t, "CRAWLER_USER_AGENT", envName("user-agent"))
+}
+
+func Test_loadConfig(t *testing.T) {
+	tests := map[string]struct {
+		args []string
+		env  map[string]string
+		want string
+	}{
+		"default_value_when_nothing_set": {
+			args: []string{},
+			env:  map[string]string{},
+			want: "default",
+		},
+		"env_overrides_default": {
+			args: []string{},
+			env:  map[string]string{"CRAWLER_URL": "from-env"},
+			want: "from-env",
+		},
+		"flag_overrides_env": {
+			args: []string{"-url=from-flag"},
+			env:  map[string]string{"CRAWLER_URL": "from-env"},
+			want: "from-flag",
+		},
+		"unrelated_env_is_ignored": {
+			args: []string{},
+			env:  map[string]string{"CRAWLER_BASE_URL": "from-env"},
+			want: "default",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			fs := flag.NewFlagSet(name, flag.ContinueOnError)
+			u := fs.String("url", "default", "")
+			assert.Nil(t, fs.Parse(tt.args))
+
+			lookup := func(k string) (string, bool) {
+				v, ok := tt.env[k]
+				return v, ok
+			}
+			assert.Nil(t, loadConfig(fs, lookup))
+			assert.Equal(t, tt.want, *u)
+		})
+	}
+}
+
+func Test_loadConfig_InvalidEnvValue(t *testing.T) {
+	fs := flag.NewFlagSet("invalid", flag.ContinueOnError)
+	fs.Int("depth", 0, "")
+	assert.Nil(t, fs.Parse([]string{}))
+
+	lookup := func(k string) (string, bool) {
+		return "not-a-number", k == "CRAWLER_DEPTH"
+	}
+	assert.NotNil(t, loadConfig(fs, lookup))
+}
+
+func Test_loadConfig_ConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawler.yaml")
+	config := `
+flags:
+  url: from-file
+  depth: 2
+  header: ["Accept-Language: fr-CH", "X-Api-Key: t0k3n"]
+`
+	assert.Nil(t, os.WriteFile(path, []byte(config), 0o600))
+
+	tests := map[string]struct {
+		args      []string
+		env       map[string]string
+		wantURL   string
+		wantDepth int
+	}{
+		"file_overrides_default": {
+			args:      []string{"-config=" + path},
+			wantURL:   "from-file",
+			wantDepth: 2,
+		},
+		"env_overrides_file": {
+			args:      []string{"-config=" + path},
+			env:       map[string]string{"CRAWLER_URL": "from-env"},
+			wantURL:   "from-env",
+			wantDepth: 2,
+		},
+		"flag_overrides_file": {
+			args:      []string{"-config=" + path, "-depth=5"},
+			wantURL:   "from-file",
+			wantDepth: 5,
+		},
+		"file_from_env": {
+			env:       map[string]string{"CRAWLER_CONFIG": path},
+			wantURL:   "from-file",
+			wantDepth: 2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			fs := flag.NewFlagSet(name, flag.ContinueOnError)
+			fs.String("config", "", "")
+			u := fs.String("url", "default", "")
+			depth := fs.Int("depth", 0, "")
+			h := headers{}
+			fs.Var(h, "header", "")
+			assert.Nil(t, fs.Parse(tt.args))
+
+			lookup := func(k string) (string, bool) {
+				v, ok := tt.env[k]
+				return v, ok
+			}
+			assert.Nil(t, loadConfig(fs, lookup))
+			assert.Equal(t, tt.wantURL, *u)
+			assert.Equal(t, tt.wantDepth, *depth)
+			assert.Equal(t, headers{"Accept-Language": {"fr-CH"}, "X-Api-Key": {"t0k3n"}}, h)
+		})
+	}
+}
+
+func Test_loadConfig_InvalidConfigFile(t *testing.T) {
+	tests := map[string]string{
+		"unknown_flag":  "flags:\n  unknown: value\n",
+		"nested_config": "flags:\n  config: other.yaml\n",
+		"invalid_value": "flags:\n  depth: not-a-number\n",
+		"mapping_value": "flags:\n  depth: {a: 1}\n",
+		"unknown_key":   "unknown: value\n",
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "crawler.yaml")
+			assert.Nil(t, os.WriteFile(path, []byte(config), 0o600))
+			fs := flag.NewFlagSet(name, flag.ContinueOnError)
+			fs.String("config", "", "")
+			fs.Int("depth", 0, "")
+			assert.Nil(t, fs.Parse([]string{"-config=" + path}))
+			assert.NotNil(t, loadConfig(fs, func(string) (string, bool) { return "", false }))
+		})
+	}
+}
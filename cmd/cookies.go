@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"github.com/rbroggi/crawler/crawler"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// cookies are the cookies set with the repeated -cookie flag.
+// It implements flag.Value
+type cookies []*http.Cookie
+
+func (c *cookies) String() string {
+	if c == nil {
+		return ""
+	}
+	names := make([]string, 0, len(*c))
+	for _, cookie := range *c {
+		// the cookie values might be credentials, they are not printed
+		names = append(names, cookie.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// Set adds a cookie in the syntax of a Set-Cookie header (e.g. `session=s3ss10n; Domain=my-web-site.com`)
+func (c *cookies) Set(value string) error {
+	parsed := (&http.Response{Header: http.Header{"Set-Cookie": {value}}}).Cookies()
+	if len(parsed) != 1 {
+		return fmt.Errorf("invalid cookie %q, expected name=value[; attributes]", value)
+	}
+	*c = append(*c, parsed[0])
+	return nil
+}
+
+// seedCookies returns the options seeding cookies in the cookie jar of the
+// crawler: the cookies with a Domain are seeded for it, the other ones for
+// each of the comma separated URLs of rootURLs
+func seedCookies(cookies cookies, rootURLs string) []crawler.Option {
+	var opts []crawler.Option
+	var hostOnly []*http.Cookie
+	for _, cookie := range cookies {
+		if cookie.Domain == "" {
+			hostOnly = append(hostOnly, cookie)
+			continue
+		}
+		u := &url.URL{Scheme: "https", Host: strings.TrimPrefix(cookie.Domain, "."), Path: "/"}
+		opts = append(opts, crawler.WithCookies(u, cookie))
+	}
+	if len(hostOnly) == 0 {
+		return opts
+	}
+	for _, rawURL := range parseList(rootURLs) {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		opts = append(opts, crawler.WithCookies(u, hostOnly...))
+	}
+	return opts
+}
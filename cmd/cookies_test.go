@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_cookies_Set(t *testing.T) {
+	var c cookies
+	assert.Nil(t, c.Set("session=s3ss10n; Path=/intranet"))
+	assert.Nil(t, c.Set("lang=fr; Domain=my-web-site.com"))
+	assert.NotNil(t, c.Set("; Path=/"))
+	assert.Len(t, c, 2)
+	assert.Equal(t, "/intranet", c[0].Path)
+	assert.Equal(t, "my-web-site.com", c[1].Domain)
+	assert.Equal(t, "session,lang", c.String())
+}
+
+func Test_seedCookies(t *testing.T) {
+	var c cookies
+	assert.Nil(t, c.Set("session=s3ss10n"))
+	assert.Nil(t, c.Set("lang=fr; Domain=my-web-site.com"))
+	// one option for the domain cookie, one per site for the host cookie
+	assert.Len(t, seedCookies(c, "https://site-a.com/,https://site-b.com/"), 3)
+	assert.Len(t, seedCookies(c[1:], "https://site-a.com/"), 1)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/rbroggi/crawler/crawler"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+)
+
+// fileConfig is the YAML configuration file set with -config
+type fileConfig struct {
+	// Flags are the values of the command line flags, by flag name, they
+	// apply to the flags set neither in the command line nor in the environment
+	Flags map[string]flagValues `yaml:"flags"`
+	// DomainGroups are the settings shared by groups of domains
+	DomainGroups []domainGroupConfig `yaml:"domain_groups"`
+}
+
+// flagValues are the values of a flag in the configuration file, a scalar or,
+// for the flags that can be repeated, a sequence of scalars
+type flagValues []string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (v *flagValues) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*v = flagValues{node.Value}
+		return nil
+	case yaml.SequenceNode:
+		values := make(flagValues, 0, len(node.Content))
+		for _, n := range node.Content {
+			if n.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: a flag value must be a scalar or a sequence of scalars", n.Line)
+			}
+			values = append(values, n.Value)
+		}
+		*v = values
+		return nil
+	}
+	return fmt.Errorf("line %d: a flag value must be a scalar or a sequence of scalars", node.Line)
+}
+
+// setFlags sets on fs the flags of the configuration that are not in set,
+// the flags of the configuration must be flags of fs
+func (c *fileConfig) setFlags(fs *flag.FlagSet, set map[string]struct{}) error {
+	names := make([]string, 0, len(c.Flags))
+	for name := range c.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == configFlag || fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown flag %q in the configuration file", name)
+		}
+		if _, ok := set[name]; ok {
+			continue
+		}
+		for _, v := range c.Flags[name] {
+			if err := fs.Set(name, v); err != nil {
+				return fmt.Errorf("invalid value %q for flag %s of the configuration file - %v", v, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// domainGroupConfig holds the settings of the domains matching one of
+// its patterns, an entry of the domain_groups of the configuration file
+type domainGroupConfig struct {
+	Domains   []string `yaml:"domains"`
+	RateLimit float64  `yaml:"rate_limit"`
+	RateBurst int      `yaml:"rate_burst"`
+	BasicAuth *struct {
+		User     string `yaml:"user"`
+		Password string `yaml:"password"`
+	} `yaml:"basic_auth"`
+	BearerToken string            `yaml:"bearer_token"`
+	Headers     map[string]string `yaml:"headers"`
+}
+
+// readConfigFile reads the configuration file at path
+func readConfigFile(path string) (*fileConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseConfigFile(f)
+}
+
+// parseConfigFile parses a configuration file, the unknown keys are errors
+func parseConfigFile(r io.Reader) (*fileConfig, error) {
+	var config fileConfig
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// domainGroups returns the crawler.DomainGroup of every domain group of the configuration
+func (c *fileConfig) domainGroups() ([]crawler.DomainGroup, error) {
+	groups := make([]crawler.DomainGroup, 0, len(c.DomainGroups))
+	for i, g := range c.DomainGroups {
+		if len(g.Domains) == 0 {
+			return nil, fmt.Errorf("domain group %d has no domains", i+1)
+		}
+		for _, pattern := range g.Domains {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid domain pattern %q of domain group %d - %v", pattern, i+1, err)
+			}
+		}
+		if g.BasicAuth != nil && g.BearerToken != "" {
+			return nil, fmt.Errorf("domain group %d has both basic_auth and bearer_token", i+1)
+		}
+		header := http.Header{}
+		for name, v := range g.Headers {
+			header.Set(name, v)
+		}
+		if g.BasicAuth != nil {
+			header.Set("Authorization", crawler.BasicAuthHeader(g.BasicAuth.User, g.BasicAuth.Password).Get("Authorization"))
+		}
+		if g.BearerToken != "" {
+			header.Set("Authorization", crawler.BearerAuthHeader(g.BearerToken).Get("Authorization"))
+		}
+		groups = append(groups, crawler.DomainGroup{Patterns: g.Domains, Header: header, RateLimit: g.RateLimit, RateBurst: g.RateBurst})
+	}
+	return groups, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/rbroggi/crawler/crawler"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_fileConfig_domainGroups(t *testing.T) {
+	tests := map[string]struct {
+		config  string
+		want    []crawler.DomainGroup
+		wantErr bool
+	}{
+		"groups": {
+			config: `
+domain_groups:
+  - domains: ["*.intranet.my-company.com", "intranet.my-company.com"]
+    rate_limit: 2
+    rate_burst: 4
+    basic_auth:
+      user: alice
+      password: s3cr3t
+  - domains: ["api.my-company.com"]
+    bearer_token: t0k3n
+    headers:
+      accept-language: fr-CH
+`,
+			want: []crawler.DomainGroup{
+				{
+					Patterns:  []string{"*.intranet.my-company.com", "intranet.my-company.com"},
+					Header:    http.Header{"Authorization": {"Basic YWxpY2U6czNjcjN0"}},
+					RateLimit: 2,
+					RateBurst: 4,
+				},
+				{
+					Patterns: []string{"api.my-company.com"},
+					Header:   http.Header{"Authorization": {"Bearer t0k3n"}, "Accept-Language": {"fr-CH"}},
+				},
+			},
+		},
+		"empty": {
+			want: []crawler.DomainGroup{},
+		},
+		"unknown_key": {
+			config:  "domain_groups:\n  - domain: [my-company.com]\n",
+			wantErr: true,
+		},
+		"no_domains": {
+			config:  "domain_groups:\n  - rate_limit: 2\n",
+			wantErr: true,
+		},
+		"invalid_pattern": {
+			config:  "domain_groups:\n  - domains: ['[my-company.com']\n",
+			wantErr: true,
+		},
+		"both_credentials": {
+			config:  "domain_groups:\n  - domains: [my-company.com]\n    bearer_token: t0k3n\n    basic_auth: {user: alice, password: s3cr3t}\n",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			config, err := parseConfigFile(strings.NewReader(tt.config))
+			var groups []crawler.DomainGroup
+			if err == nil {
+				groups, err = config.domainGroups()
+			}
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, groups)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/rbroggi/crawler/crawler"
+	log "github.com/sirupsen/logrus"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// grepUsage is printed when the grep sub-command is wrongly invoked
+const grepUsage = "usage: web-crawler grep [-url=<url_to_be_crawled>] <regex>"
+
+// runGrep implements the `grep` sub-command: it crawls the input url and
+// prints, for each page, the lines of the page body matching a regex
+func runGrep(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("grep", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), grepUsage)
+		fs.PrintDefaults()
+	}
+	rootURL := fs.String("url", "http://localhost:8080/index.html", "URL to be recursively crawled")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if err := loadConfig(fs, os.LookupEnv); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	re, err := regexp.Compile(fs.Arg(0))
+	if err != nil {
+		log.Errorf("Error while compiling regex: [%v]", err)
+		return 1
+	}
+
+	baseURL, err := url.Parse(*rootURL)
+	if err != nil {
+		log.Errorf("Error while parsing root URL: [%v]", err)
+		return 1
+	}
+
+	// the lines are matched against the page source as it was served
+	c := crawler.NewCrawler(crawler.WithRawBody())
+	if err := c.CrawlPages(ctx, baseURL, WritePageMatchingLinesToStdOut(re)); err != nil {
+		log.Printf("Error while crawling: [%v]\n", err)
+		return 2
+	}
+	return 0
+}
+
+// WritePageMatchingLinesToStdOut builds a visit function that writes to stdout
+// the lines of the raw page body (see crawler.WithRawBody) that match re. Each
+// matching line is prefixed with the page url and the line number of the body
+// (e.g. `<url>:<line>: <content>`), pages without any matching line produce no output
+func WritePageMatchingLinesToStdOut(re *regexp.Regexp) func(p *crawler.Page) {
+	return func(p *crawler.Page) {
+		u := p.URL
+		var b strings.Builder
+		scanner := bufio.NewScanner(bytes.NewReader(p.Body))
+		for n := 1; scanner.Scan(); n++ {
+			line := scanner.Text()
+			if !re.MatchString(line) {
+				continue
+			}
+			_, err := fmt.Fprintf(&b, "%s:%d: %s\n", u.String(), n, strings.TrimSpace(line))
+			if err != nil {
+				log.Errorf("Error while writing into strings.Builder")
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Errorf("Error while scanning page: [%s]", u)
+			return
+		}
+		if b.Len() > 0 {
+			fmt.Print(b.String())
+		}
+	}
+}
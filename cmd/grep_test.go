@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/rbroggi/crawler/crawler"
+	"net/url"
+	"regexp"
+)
+
+func ExampleWritePageMatchingLinesToStdOut() {
+	u, err := url.Parse("https://my-web-site.com/contact")
+	if err != nil {
+		panic("error parsing url")
+	}
+
+	htmlStr := `<html><head></head><body>
+<p>call us at +1 555 0100</p>
+<p>or write to us</p>
+<P>fax: +1 555 0199</P>
+</body></html>`
+
+	WritePageMatchingLinesToStdOut(regexp.MustCompile(`\+1 555 01\d\d`))(&crawler.Page{URL: u, Body: []byte(htmlStr)})
+
+	// Output:
+	// https://my-web-site.com/contact:2: <p>call us at +1 555 0100</p>
+	// https://my-web-site.com/contact:4: <P>fax: +1 555 0199</P>
+}
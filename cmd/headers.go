@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// headers are the request headers set with the repeated -header flag.
+// It implements flag.Value
+type headers http.Header
+
+func (h headers) String() string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// the header values might be credentials, they are not printed
+	return strings.Join(names, ",")
+}
+
+// Set adds a header in the `<name>: <value>` syntax (e.g. `Accept-Language: fr-CH`)
+func (h headers) Set(value string) error {
+	name, v, err := parseHeader(value)
+	if err != nil {
+		return err
+	}
+	http.Header(h).Add(name, v)
+	return nil
+}
+
+// parseHeader parses a header in the `<name>: <value>` syntax
+func parseHeader(header string) (string, string, error) {
+	i := strings.Index(header, ":")
+	if i <= 0 || strings.TrimSpace(header[:i]) == "" {
+		return "", "", fmt.Errorf("invalid header %q, expected <name>: <value>", header)
+	}
+	return strings.TrimSpace(header[:i]), strings.TrimSpace(header[i+1:]), nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_headers_Set(t *testing.T) {
+	tests := map[string]struct {
+		values  []string
+		want    headers
+		wantErr bool
+	}{
+		"single_header": {
+			values: []string{"Accept-Language: fr-CH"},
+			want:   headers{"Accept-Language": {"fr-CH"}},
+		},
+		"canonical_name": {
+			values: []string{"x-api-key:t0k3n"},
+			want:   headers{"X-Api-Key": {"t0k3n"}},
+		},
+		"repeated_header": {
+			values: []string{"X-Forwarded-For: 10.0.0.1", "X-Forwarded-For: 10.0.0.2"},
+			want:   headers{"X-Forwarded-For": {"10.0.0.1", "10.0.0.2"}},
+		},
+		"empty_value": {
+			values: []string{"X-Debug:"},
+			want:   headers{"X-Debug": {""}},
+		},
+		"missing_colon": {
+			values:  []string{"X-Debug"},
+			wantErr: true,
+		},
+		"missing_name": {
+			values:  []string{" : value"},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := headers{}
+			var err error
+			for _, v := range tt.values {
+				if err = h.Set(v); err != nil {
+					break
+				}
+			}
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, h)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// labels are user-defined key=value pairs attached to every output
+// record of a crawl so that results can be segmented downstream.
+// It implements flag.Value so that it can be set with a repeated flag
+type labels map[string]string
+
+func (l labels) String() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+l[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set adds one or several comma separated key=value pairs
+func (l labels) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.Index(pair, "=")
+		if i <= 0 {
+			return fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		l[pair[:i]] = pair[i+1:]
+	}
+	return nil
+}
+
+// fields returns the labels as logrus fields
+func (l labels) fields() map[string]interface{} {
+	fields := make(map[string]interface{}, len(l))
+	for k, v := range l {
+		fields[k] = v
+	}
+	return fields
+}
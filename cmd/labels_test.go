@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_labels_Set(t *testing.T) {
+	tests := map[string]struct {
+		values  []string
+		wantErr bool
+		want    string
+	}{
+		"single_label": {
+			values: []string{"site=clientA"},
+			want:   "site=clientA",
+		},
+		"repeated_flag": {
+			values: []string{"site=clientA", "env=prod"},
+			want:   "env=prod,site=clientA",
+		},
+		"comma_separated": {
+			values: []string{"site=clientA, env=prod"},
+			want:   "env=prod,site=clientA",
+		},
+		"last_value_wins": {
+			values: []string{"env=dev", "env=prod"},
+			want:   "env=prod",
+		},
+		"empty_value_allowed": {
+			values: []string{"env="},
+			want:   "env=",
+		},
+		"missing_equal": {
+			values:  []string{"env"},
+			wantErr: true,
+		},
+		"missing_key": {
+			values:  []string{"=prod"},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			l := labels{}
+			var err error
+			for _, v := range tt.values {
+				if err = l.Set(v); err != nil {
+					break
+				}
+			}
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, l.String())
+		})
+	}
+}
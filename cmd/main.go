@@ -1,12 +1,18 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/rbroggi/crawler/crawler"
+	"github.com/rbroggi/crawler/scanner"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/html"
+	"io"
 	"net/url"
 	"os"
 	"os/signal"
@@ -15,44 +21,536 @@ import (
 	"time"
 )
 
+// userAgent is the product token identifying the program, matched against
+// the user-agent groups of the robots.txt files when no -user-agent is sent
+const userAgent = "web-crawler"
+
+// subCommands are the sub-commands of the program, each one
+// receives the command line arguments following its name
+var subCommands = map[string]func(ctx context.Context, args []string) int{
+	"ai-policy":    runAIPolicy,
+	"grep":         runGrep,
+	"redirect-map": runRedirectMap,
+	"sitemap":      runSitemap,
+}
+
 func main() {
+	// sub-commands
+	if len(os.Args) > 1 {
+		if run, ok := subCommands[os.Args[1]]; ok {
+			os.Exit(run(signalContext(context.Background()), os.Args[2:]))
+		}
+	}
+
 	// cmd line flags
-	rootURL := flag.String("url", "http://localhost:8080/index.html", "URL to be recursively crawled")
-	flag.Parse()
+	rootURL := flag.String("url", "http://localhost:8080/index.html", "URL to be recursively crawled, several comma separated URLs crawl several sites concurrently")
+	requests := newRequestFlags(flag.CommandLine)
+	maxConcurrency := flag.Int("max-concurrency", 0, "maximum number of pages concurrently scraped across all the sites (0 means no limit)")
+	retries := flag.Int("retries", 0, "number of retries, with exponential backoff and jitter, of the page requests failing with a network error")
+	retryDelay := flag.Duration("retry-delay", 500*time.Millisecond, "delay before the first retry of a failed request, doubled at each retry")
+	retryMaxDelay := flag.Duration("retry-max-delay", 10*time.Second, "maximum delay between two retries of a failed request")
+	maxRedirects := flag.Int("max-redirects", 10, "maximum number of redirects followed for a page, a redirect not followed is reported with its 3xx status code (0 means none is followed)")
+	crossDomainRedirects := flag.Bool("cross-domain-redirects", true, "follow the redirects to another host")
+	redirectFinalURL := flag.Bool("redirect-final-url", false, "report a redirected page under the URL it was redirected to, which is not crawled again and against which its links are resolved")
+	hostStats := flag.Bool("host-stats", false, "log the pages, errors, average latency and bytes of the requests to each host at the end of the crawl")
+	contentTypes := flag.Bool("content-types", false, "print at the end of the crawl the number of responses and bytes of each content type along with the URLs of the non-HTML resources")
+	largest := flag.Int("largest", 0, "print at the end of the crawl the given number of largest pages and of heaviest sections (path prefixes) of the crawled sites")
+	contentTypeAllowlist := flag.String("content-type-allowlist", strings.Join(crawler.DefaultContentTypeAllowlist, ","), "comma separated media types of the pages parsed, the pages of other content types (e.g. PDFs, images) are not read and their links are not followed (empty means all the pages are parsed)")
+	headFirst := flag.Bool("head-first", false, "issue a HEAD request before getting each page, the pages whose Content-Type is not in -content-type-allowlist or whose Content-Length exceeds -max-body-bytes are not downloaded")
+	maxBodyBytes := flag.Int64("max-body-bytes", 0, "maximum number of bytes read out of each page, longer pages are truncated (0 means no limit)")
+	maxDuration := flag.Duration("max-duration", 0, "maximum duration of the crawl (e.g. 10m), once expired the in-flight requests are cancelled and the results gathered so far are reported (0 means no limit)")
+	maxDepth := flag.Int("max-depth", -1, "maximum number of links followed from the crawled URL, the links of the pages at this depth are not followed (-1 means no limit)")
+	parseConcurrency := flag.Int("parse-concurrency", 0, "maximum number of pages concurrently parsed, separating the parsing from the fetching limited by -max-concurrency (0 means pages are fetched and parsed in the same slot)")
+	parseQueue := flag.Int("parse-queue", 1, "maximum number of fetched pages waiting to be parsed when -parse-concurrency is set")
+	prefetch := flag.Int("prefetch", 0, "number of links of a page, in document order, fetched while the page is still being visited (0 means links are fetched once the visit returned)")
+	spider := flag.Bool("spider", false, "only print the URL of each crawled page, one per line")
+	tmplText := flag.String("template", "", "Go text/template executed over each crawled page (e.g. '{{.URL}} {{.Title}}')")
+	scanSecrets := flag.Bool("scan-secrets", false, "scan pages and same-domain javascript files for leaked secrets and print a findings report")
+	outputLabels := labels{}
+	flag.Var(outputLabels, "label", "key=value label attached to every output record, can be repeated (e.g. -label=site=clientA -label=env=prod)")
+	canonicalize := flag.String("canonicalize", "", "comma separated URL canonicalization transforms (e.g. 'strip-fragment,sort-query,lowercase-host,drop-params:utm_*')")
+	trackingParams := flag.String("tracking-params", strings.Join(crawler.DefaultTrackingParams, ","), "comma separated patterns of the query parameters removed from the crawled URLs, an empty list keeps them all")
+	validate := flag.String("validate", "", "comma separated validators run on each page, printing a findings report (status-ok, title-present, canonical-present, tls, structured-data, unique-title, social-metadata, icons, hreflang, canonical-chain, click-depth)")
+	maxClickDepth := flag.Int("max-click-depth", crawler.DefaultMaxClickDepth, "click depth from the base URL past which the click-depth validator flags the pages")
+	suppressionsFile := flag.String("suppressions", "", "file of the accepted validation findings not to be reported, one finding ID or '<validator> <url>' pair per line (a trailing * in the url matches a prefix)")
+	baselineFile := flag.String("baseline", "", "baseline file of the known validation findings, only the new findings are reported")
+	updateBaseline := flag.Bool("update-baseline", false, "write all the validation findings of the crawl, but the suppressed ones, to the -baseline file")
+	failOn := flag.String("fail-on", "", "exit with status 4 if a reported validation finding has at least this severity (info, warning, error)")
+	coverage := flag.String("coverage", "", "comma separated prefix:min section coverage targets (e.g. '/docs/:100'), the program exits with status 3 if a target is not met")
+	honorAIOptOut := flag.Bool("honor-ai-opt-out", false, "do not visit pages declaring AI opt-out directives (noai, noimageai), their links are still followed")
+	sitemapFile := flag.String("sitemap", "", "file a sitemap.xml of the crawled pages answered with a 200 status code is written to at the end of the crawl ('-' for stdout), only for a single -url crawl")
+	urlsFile := flag.String("urls-file", "", "file with one URL per line to be fetched without following links ('-' for stdin)")
+	// flags not set in the command line can be set through CRAWLER_* env
+	// variables, and else through the -config file
+	mustLoadConfig()
 
 	// Create a new context that can be cancelled with ctrl+c
 	ctx := signalContext(context.Background())
 
-	// Parsing input URL
-	baseURL, err := url.Parse(*rootURL)
+	pipeline, err := crawler.ParseTransforms(*canonicalize)
+	if err != nil {
+		log.Errorf("Error while parsing canonicalization transforms: [%v]", err)
+		os.Exit(1)
+	}
+	// the patterns are validated as the crawler ignores the malformed ones
+	if _, err := crawler.DropParams(parseList(*trackingParams)...); err != nil {
+		log.Errorf("Error while parsing tracking parameters: [%v]", err)
+		os.Exit(1)
+	}
+	suppressions, err := readSuppressions(*suppressionsFile)
+	if err != nil {
+		log.Errorf("Error while reading suppressions: [%v]", err)
+		os.Exit(1)
+	}
+	if *updateBaseline && *baselineFile == "" {
+		log.Errorf("The -update-baseline flag requires a -baseline file")
+		os.Exit(1)
+	}
+	var baseline *crawler.Suppressions
+	if *baselineFile != "" && !*updateBaseline {
+		if baseline, err = readBaseline(*baselineFile); err != nil {
+			log.Errorf("Error while reading baseline: [%v]", err)
+			os.Exit(1)
+		}
+	}
+	var failSeverity crawler.Severity
+	if *failOn != "" {
+		if failSeverity, err = crawler.ParseSeverity(*failOn); err != nil {
+			log.Errorf("Error while parsing fail-on severity: [%v]", err)
+			os.Exit(1)
+		}
+	}
+	// the crawled pages are printed by a single output mode
+	modes := 0
+	for _, set := range []bool{*spider, *tmplText != "", *scanSecrets} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		log.Errorf("The -spider, -template and -scan-secrets flags cannot be combined")
+		os.Exit(1)
+	}
+	opts := []crawler.Option{
+		crawler.WithCanonicalization(pipeline...),
+		crawler.WithTrackingParams(parseList(*trackingParams)...),
+		crawler.WithMaxConcurrency(*maxConcurrency),
+		crawler.WithParseConcurrency(*parseConcurrency, *parseQueue),
+		crawler.WithPrefetch(*prefetch),
+		crawler.WithMaxDepth(*maxDepth),
+		crawler.WithMaxDuration(*maxDuration),
+		crawler.WithRetry(crawler.RetryPolicy{
+			Attempts:  *retries + 1,
+			BaseDelay: *retryDelay,
+			MaxDelay:  *retryMaxDelay,
+			Jitter:    0.5,
+		}),
+		crawler.WithMaxBodyBytes(*maxBodyBytes),
+		crawler.WithContentTypeAllowlist(parseList(*contentTypeAllowlist)...),
+		crawler.WithRedirectPolicy(redirectPolicy(*maxRedirects, *crossDomainRedirects, *redirectFinalURL)),
+	}
+	// the spider only needs the links of the pages, unless they are validated
+	if *spider && *validate == "" {
+		opts = append(opts, crawler.WithLinksOnly())
+	}
+	// the credentials and headers are only sent to the crawled sites, not
+	// to the sites they redirect to
+	var scope []string
+	if *urlsFile == "" {
+		scope = siteHosts(*rootURL)
+	}
+	reqOpts, err := requests.options(*rootURL, scope)
 	if err != nil {
-		log.Errorf("Error while parsing root URL: [%v]", err)
+		log.Errorf("Error while configuring the requests: [%v]", err)
 		os.Exit(1)
 	}
+	opts = append(opts, reqOpts...)
+	if *headFirst {
+		opts = append(opts, crawler.WithHeadFirst())
+	}
+	if *honorAIOptOut {
+		opts = append(opts, crawler.WithAIOptOut())
+	}
+	var perHost *crawler.HostStats
+	if *hostStats {
+		perHost = &crawler.HostStats{}
+		opts = append(opts, crawler.WithHostStats(perHost))
+	}
+	var types *crawler.ContentTypes
+	if *contentTypes {
+		types = &crawler.ContentTypes{}
+		opts = append(opts, crawler.WithContentTypes(types))
+	}
+	var weights *crawler.Weights
+	if *largest > 0 {
+		weights = crawler.NewWeights(*largest)
+		opts = append(opts, crawler.WithWeights(weights))
+	}
+	// the login is issued the way the crawler issues its requests, once all
+	// the options affecting them are set (transport, proxies, signer...)
+	if err := requests.login(ctx, opts); err != nil {
+		log.Errorf("Error while logging in: [%v]", err)
+		os.Exit(2)
+	}
+	// the reports issue their requests the way the crawler does
+	vs, rs, err := parseValidators(*validate, reportSettings{ctx: ctx, client: crawler.NewHTTPClient(opts...), maxClickDepth: *maxClickDepth})
+	if err != nil {
+		log.Errorf("Error while parsing validators: [%v]", err)
+		os.Exit(1)
+	}
+	var validationFindings *findingsCollector
+	if len(vs) > 0 || len(rs) > 0 {
+		validationFindings = &findingsCollector{}
+		opts = append(opts, crawler.WithValidators(validationFindings.add, vs...), crawler.WithReports(rs...))
+	}
+	c := crawler.NewCrawler(opts...)
 
-	c := crawler.NewCrawler()
-	// Crawl input URL and for each page prints url + links
-	err = c.Crawl(ctx, baseURL, WritePageURLAndLinksToStdOut)
+	// visit is applied to every page, by default it prints url + links
+	visit := WritePageURLAndLinksWithLabelsToStdOut(outputLabels)
+	var sc *scanner.Scanner
+	switch {
+	case *spider:
+		visit = WritePageURLToStdOut
+	case *tmplText != "":
+		tmpl, err := parseTemplate(*tmplText)
+		if err != nil {
+			log.Errorf("Error while parsing template: [%v]", err)
+			os.Exit(1)
+		}
+		visit = WritePageTemplateToStdOut(tmpl)
+	case *scanSecrets:
+		// the scripts are requested the way the pages are
+		sc = scanner.NewScanner(ctx, crawler.NewHTTPClient(opts...))
+		visit = sc.Visit
+	}
+	targets, err := crawler.ParseCoverageTargets(*coverage)
 	if err != nil {
-		log.Printf("Error while crawling: [%v]\n", err)
+		log.Errorf("Error while parsing coverage targets: [%v]", err)
+		os.Exit(1)
+	}
+	var cov *crawler.Coverage
+	if len(targets) > 0 {
+		cov = crawler.NewCoverage(targets)
+		visit = chainVisits(visit, cov.Visit)
+	}
+
+	var sitemap *crawler.Sitemap
+	if *sitemapFile != "" {
+		if *urlsFile != "" || strings.Contains(*rootURL, ",") {
+			log.Errorf("The -sitemap flag requires the crawl of a single -url")
+			os.Exit(1)
+		}
+		sitemap = crawler.NewSitemap()
+	}
+
+	if *urlsFile != "" {
+		// Fetch the URLs listed in the file without crawling them, the URLs
+		// are fetched as soon as they are read so that stdin can be streamed
+		urls := make(chan *url.URL)
+		go func() {
+			if err := readURLsFile(ctx, *urlsFile, urls); err != nil {
+				log.Errorf("Error while reading URLs file: [%v]", err)
+			}
+		}()
+		exitOnCrawlError("fetching", c.FetchStream(ctx, urls, visit))
+	} else if strings.Contains(*rootURL, ",") {
+		// Parsing input URLs, one per site
+		var baseURLs []*url.URL
+		for _, rawURL := range strings.Split(*rootURL, ",") {
+			baseURL, err := url.Parse(strings.TrimSpace(rawURL))
+			if err != nil {
+				log.Errorf("Error while parsing root URL: [%v]", err)
+				os.Exit(1)
+			}
+			baseURLs = append(baseURLs, baseURL)
+		}
+
+		// Crawl the sites concurrently and log the stats of each site
+		stats, err := c.CrawlSites(ctx, baseURLs, visit)
+		exitOnCrawlError("crawling", err)
+		for site, s := range stats {
+			log.WithFields(outputLabels.fields()).Infof("site: %s | pages: %d | errors: %d | skipped: %d", site, s.Pages, s.Errors, s.Skipped)
+		}
+	} else {
+		// Parsing input URL
+		baseURL, err := url.Parse(*rootURL)
+		if err != nil {
+			log.Errorf("Error while parsing root URL: [%v]", err)
+			os.Exit(1)
+		}
+
+		// Crawl input URL and apply the visit function on each page,
+		// the sitemap also needs the status code and headers of the pages
+		if sitemap != nil {
+			err = c.CrawlPages(ctx, baseURL, func(p *crawler.Page) {
+				visit(p.URL, p.Node)
+				sitemap.Visit(p)
+			})
+		} else {
+			err = c.Crawl(ctx, baseURL, visit)
+		}
+		exitOnCrawlError("crawling", err)
+	}
+
+	// reports are written once all pages were visited
+	var failedFindings bool
+	if perHost != nil {
+		for _, s := range perHost.Results() {
+			log.WithFields(outputLabels.fields()).Infof("host: %s | pages: %d | errors: %d | avg latency: %s | bytes: %d", s.Host, s.Pages, s.Errors, s.AvgLatency(), s.Bytes)
+		}
+	}
+	if sc != nil {
+		WriteFindingsToStdOut(sc.Findings(), outputLabels)
+	}
+	if validationFindings != nil {
+		if err := validationFindings.finalize(rs); err != nil {
+			log.Errorf("Error while finalizing reports: [%v]", err)
+			os.Exit(2)
+		}
+		findings := validationFindings.sorted()
+		reported := unsuppressed(findings, suppressions)
+		if suppressed := len(findings) - len(reported); suppressed > 0 {
+			log.Infof("Suppressed %d validation findings", suppressed)
+		}
+		if *updateBaseline {
+			// the baselined findings are accepted, they do not fail the run
+			if err := writeBaseline(*baselineFile, reported); err != nil {
+				log.Errorf("Error while writing baseline: [%v]", err)
+				os.Exit(2)
+			}
+			log.Infof("Baseline %s updated with %d validation findings", *baselineFile, len(reported))
+			WriteValidationFindingsToStdOut(reported, outputLabels)
+		} else {
+			if baseline != nil {
+				known := len(reported)
+				reported = unsuppressed(reported, baseline)
+				log.Infof("%d validation findings already in the baseline", known-len(reported))
+			}
+			WriteValidationFindingsToStdOut(reported, outputLabels)
+			failedFindings = *failOn != "" && failing(reported, failSeverity)
+		}
+	}
+	if types != nil {
+		WriteContentTypesToStdOut(types.Results(), outputLabels)
+	}
+	if weights != nil {
+		WriteWeightsToStdOut(weights.Largest(), weights.Heaviest(), outputLabels)
+	}
+	if sitemap != nil {
+		if err := writeSitemap(sitemap, *sitemapFile); err != nil {
+			log.Errorf("Error while writing sitemap: [%v]", err)
+			os.Exit(2)
+		}
+	}
+	if cov != nil {
+		results := cov.Results()
+		WriteCoverageToStdOut(results, outputLabels)
+		for _, r := range results {
+			if !r.Met() {
+				os.Exit(3)
+			}
+		}
+	}
+	if failedFindings {
+		os.Exit(4)
+	}
+}
+
+// exitOnCrawlError exits the program on a crawl error. A crawl stopped by
+// its maximum duration is not an error: its partial results are reported
+func exitOnCrawlError(action string, err error) {
+	if errors.Is(err, crawler.ErrMaxDuration) {
+		log.Warnf("Stopped %s: [%v]", action, err)
+		return
+	}
+	if err != nil {
+		log.Printf("Error while %s: [%v]\n", action, err)
 		os.Exit(2)
 	}
 }
 
+// redirectPolicy builds the crawler.RedirectPolicy of the redirect flags,
+// 0 maxRedirects means no redirect is followed
+func redirectPolicy(maxRedirects int, crossDomain, finalURL bool) crawler.RedirectPolicy {
+	if maxRedirects <= 0 {
+		maxRedirects = -1
+	}
+	return crawler.RedirectPolicy{MaxRedirects: maxRedirects, CrossDomain: crossDomain, FinalURL: finalURL}
+}
+
+// loginForm builds the crawler.LoginForm of the login flags
+func loginForm(loginURL, action, csrfSelector, csrfField string, fields formFields) (crawler.LoginForm, error) {
+	form := crawler.LoginForm{CSRFSelector: csrfSelector, CSRFField: csrfField, Fields: url.Values(fields)}
+	var err error
+	if form.URL, err = url.Parse(loginURL); err != nil {
+		return form, err
+	}
+	if action != "" {
+		if form.Action, err = url.Parse(action); err != nil {
+			return form, err
+		}
+	}
+	return form, nil
+}
+
+// tlsOptions returns the options trusting the certificate authorities of the
+// caCert file, presenting the client certificate of the certFile and keyFile
+// files and skipping the verification of the server certificates if insecure
+func tlsOptions(caCert, certFile, keyFile string, insecure bool) ([]crawler.Option, error) {
+	var opts []crawler.Option
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caCert)
+		}
+		opts = append(opts, crawler.WithRootCAs(pool))
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, crawler.WithClientCertificates(cert))
+	}
+	if insecure {
+		log.Warnf("The server certificates are not verified")
+		opts = append(opts, crawler.WithInsecureSkipVerify())
+	}
+	return opts, nil
+}
+
+// siteHosts returns the hosts of the comma separated URLs of rootURLs
+func siteHosts(rootURLs string) []string {
+	var hosts []string
+	for _, rawURL := range parseList(rootURLs) {
+		if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+			hosts = append(hosts, u.Host)
+		}
+	}
+	return hosts
+}
+
+// robotsUserAgent returns the user-agent matched against the groups of the
+// robots.txt files for the requests sent with the userAgentHeader User-Agent
+func robotsUserAgent(userAgentHeader string) string {
+	if userAgentHeader == "" {
+		return userAgent
+	}
+	return userAgentHeader
+}
+
+// rotateUserAgents returns the option rotating the User-Agents read, one per
+// line, from the file at path. Blank lines and lines starting with '#' are ignored
+func rotateUserAgents(path, rotationName string) (crawler.Option, error) {
+	rotation, err := crawler.ParseUserAgentRotation(rotationName)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var agents []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		agents = append(agents, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no user-agent in %s", path)
+	}
+	return crawler.WithUserAgents(rotation, agents...), nil
+}
+
+// newProxyPool builds the crawler.ProxyPool of a comma separated list of proxy URLs
+func newProxyPool(spec, rotationName string) (*crawler.ProxyPool, error) {
+	rotation, err := crawler.ParseProxyRotation(rotationName)
+	if err != nil {
+		return nil, err
+	}
+	var urls []*url.URL
+	for _, rawURL := range parseList(spec) {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", rawURL)
+		}
+		urls = append(urls, u)
+	}
+	return crawler.NewProxyPool(urls, rotation), nil
+}
+
+// parseList splits a comma separated list, the blank items are ignored
+func parseList(spec string) []string {
+	var items []string
+	for _, item := range strings.Split(spec, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// chainVisits builds a visit function applying all the visits functions in order
+func chainVisits(visits ...func(u *url.URL, page *html.Node)) func(u *url.URL, page *html.Node) {
+	return func(u *url.URL, page *html.Node) {
+		for _, visit := range visits {
+			visit(u, page)
+		}
+	}
+}
+
 // WritePageURLAndLinksToStdOut takes a page url and it's html content
 // and writes to stdout the url of the page along with all the
 // links in the page in both the raw form (the one in found in the html)
 // and in it's absolute form
 func WritePageURLAndLinksToStdOut(u *url.URL, page *html.Node) {
+	writePageURLAndLinksToStdOut(u, page, nil)
+}
+
+// WritePageURLAndLinksWithLabelsToStdOut builds a visit function behaving like
+// WritePageURLAndLinksToStdOut which also writes the labels of the crawl after
+// the url of the page. When there are no labels the output is unchanged
+func WritePageURLAndLinksWithLabelsToStdOut(l labels) func(u *url.URL, page *html.Node) {
+	return func(u *url.URL, page *html.Node) {
+		writePageURLAndLinksToStdOut(u, page, l)
+	}
+}
+
+func writePageURLAndLinksToStdOut(u *url.URL, page *html.Node, l labels) {
 	var b strings.Builder
 	_, err := fmt.Fprintf(&b, "url: %s\n", u.String())
 	if err != nil {
 		log.Errorf("Error while writing into strings.Builder")
 		return
 	}
+	if len(l) > 0 {
+		_, err = fmt.Fprintf(&b, "labels: %s\n", l)
+		if err != nil {
+			log.Errorf("Error while writing into strings.Builder")
+			return
+		}
+	}
 	links := crawler.GetPageLinks(page)
+	resolver := crawler.NewLinkResolver(u)
 	for link := range links {
-		absLink, err := crawler.GetLinkAbsoluteUrl(u, link)
+		absLink, err := resolver.Resolve(link)
 		if err != nil {
 			log.Errorf("Error while parsing link: [%s]", link)
 		}
@@ -65,6 +563,181 @@ func WritePageURLAndLinksToStdOut(u *url.URL, page *html.Node) {
 	fmt.Println(b.String())
 }
 
+// WritePageURLToStdOut writes to stdout the url of the page only, its
+// content is ignored. The output can be piped into other tools
+func WritePageURLToStdOut(u *url.URL, _ *html.Node) {
+	fmt.Println(u.String())
+}
+
+// WriteFindingsToStdOut writes to stdout the secrets scanner findings report,
+// one finding per line. The labels of the crawl, if any, are appended to each finding
+func WriteFindingsToStdOut(findings []scanner.Finding, l labels) {
+	var b strings.Builder
+	_, err := fmt.Fprintf(&b, "findings: %d\n", len(findings))
+	if err != nil {
+		log.Errorf("Error while writing into strings.Builder")
+		return
+	}
+	for _, f := range findings {
+		_, err = fmt.Fprintf(&b, "page: %s | source: %s | rule: %s | match: %s", f.Page, f.Source, f.Rule, f.Match)
+		if err == nil && len(l) > 0 {
+			_, err = fmt.Fprintf(&b, " | labels: %s", l)
+		}
+		if err == nil {
+			_, err = b.WriteString("\n")
+		}
+		if err != nil {
+			log.Errorf("Error while writing into strings.Builder")
+			return
+		}
+	}
+	fmt.Print(b.String())
+}
+
+// WriteCoverageToStdOut writes to stdout the section coverage report, one
+// section per line. The labels of the crawl, if any, are appended to each line
+func WriteCoverageToStdOut(results []crawler.CoverageResult, l labels) {
+	var b strings.Builder
+	for _, r := range results {
+		status := "ok"
+		if !r.Met() {
+			status = "below target"
+		}
+		_, err := fmt.Fprintf(&b, "section: %s | pages: %d | target: %d | status: %s", r.Prefix, r.Pages, r.Min, status)
+		if err == nil && len(l) > 0 {
+			_, err = fmt.Fprintf(&b, " | labels: %s", l)
+		}
+		if err == nil {
+			_, err = b.WriteString("\n")
+		}
+		if err != nil {
+			log.Errorf("Error while writing into strings.Builder")
+			return
+		}
+	}
+	fmt.Print(b.String())
+}
+
+// WriteContentTypesToStdOut writes to stdout the content types report, one content
+// type per line followed by the URLs of its resources when they are not HTML. The
+// labels of the crawl, if any, are appended to each content type line
+func WriteContentTypesToStdOut(results []crawler.ContentTypeStat, l labels) {
+	var b strings.Builder
+	for _, r := range results {
+		_, err := fmt.Fprintf(&b, "content type: %s | pages: %d | bytes: %d", r.ContentType, r.Pages, r.Bytes)
+		if err == nil && len(l) > 0 {
+			_, err = fmt.Fprintf(&b, " | labels: %s", l)
+		}
+		if err == nil {
+			_, err = b.WriteString("\n")
+		}
+		for _, u := range r.URLs {
+			if err == nil {
+				_, err = fmt.Fprintf(&b, "resource: %s\n", u)
+			}
+		}
+		if err != nil {
+			log.Errorf("Error while writing into strings.Builder")
+			return
+		}
+	}
+	fmt.Print(b.String())
+}
+
+// WriteWeightsToStdOut writes to stdout the largest pages report followed by the
+// heaviest sections report, one page or section per line. The labels of the crawl,
+// if any, are appended to each line
+func WriteWeightsToStdOut(pages []crawler.PageWeight, sections []crawler.SectionWeight, l labels) {
+	var lines []string
+	for _, p := range pages {
+		lines = append(lines, fmt.Sprintf("page: %s | bytes: %d", p.URL, p.Bytes))
+	}
+	for _, s := range sections {
+		lines = append(lines, fmt.Sprintf("section: %s | pages: %d | bytes: %d", s.Prefix, s.Pages, s.Bytes))
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		_, err := b.WriteString(line)
+		if err == nil && len(l) > 0 {
+			_, err = fmt.Fprintf(&b, " | labels: %s", l)
+		}
+		if err == nil {
+			_, err = b.WriteString("\n")
+		}
+		if err != nil {
+			log.Errorf("Error while writing into strings.Builder")
+			return
+		}
+	}
+	fmt.Print(b.String())
+}
+
+// newSigV4Signer builds a SigV4Signer out of a <region>:<service> specification,
+// the credentials are read with lookup from the standard AWS environment variables
+func newSigV4Signer(spec string, lookup func(string) (string, bool)) (*crawler.SigV4Signer, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid value %q, expected <region>:<service>", spec)
+	}
+	accessKeyID, _ := lookup("AWS_ACCESS_KEY_ID")
+	secretAccessKey, _ := lookup("AWS_SECRET_ACCESS_KEY")
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	sessionToken, _ := lookup("AWS_SESSION_TOKEN")
+	return &crawler.SigV4Signer{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		SessionToken:    sessionToken,
+		Region:          parts[0],
+		Service:         parts[1],
+	}, nil
+}
+
+// readURLsFile reads URLs, one per line, from the file at path (or from
+// stdin if path is "-") and sends them to urls. The urls channel is closed
+// once the whole input has been read or ctx is done
+func readURLsFile(ctx context.Context, path string, urls chan<- *url.URL) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			close(urls)
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	return readURLs(ctx, r, urls)
+}
+
+// readURLs reads URLs, one per line, from r and sends them to urls as soon as
+// they are read. Blank lines and lines starting with '#' are ignored, lines that
+// cannot be parsed as URLs are logged and skipped. The urls channel is closed
+// once r is exhausted, or once ctx is done, its error being then returned, so
+// that the reading stops when the URLs are no longer received
+func readURLs(ctx context.Context, r io.Reader, urls chan<- *url.URL) error {
+	defer close(urls)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil {
+			log.Errorf("Error while parsing URL: [%s]", line)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case urls <- u:
+		}
+	}
+	return scanner.Err()
+}
+
 // signalContext takes a parentCtx and returns
 // a ctx decorated with cancelation through SIGINT (ctrl+c)
 // feature
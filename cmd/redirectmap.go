@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/rbroggi/crawler/crawler"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/url"
+	"os"
+)
+
+// redirectMapUsage is printed when the redirect-map sub-command is wrongly invoked
+const redirectMapUsage = "usage: web-crawler redirect-map [-url=<url_to_be_crawled>] [-o=<csv_file>] [request flags]"
+
+// runRedirectMap implements the `redirect-map` sub-command: it crawls the input url,
+// without following the redirects, and writes a CSV listing the crawled pages along
+// with the URL they are finally redirected to and the status codes. The pages are
+// requested the way the main command requests them, through the same request flags
+func runRedirectMap(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("redirect-map", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), redirectMapUsage)
+		fs.PrintDefaults()
+	}
+	rootURL := fs.String("url", "http://localhost:8080/index.html", "URL to be recursively crawled")
+	output := fs.String("o", "-", "file the redirect map is written to ('-' for stdout)")
+	requests := newRequestFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if err := loadConfig(fs, os.LookupEnv); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 1
+	}
+
+	baseURL, err := url.Parse(*rootURL)
+	if err != nil {
+		log.Errorf("Error while parsing root URL: [%v]", err)
+		return 1
+	}
+
+	// the response of every page is visited, its redirects are followed by the map
+	opts := []crawler.Option{crawler.WithRedirectPolicy(crawler.RedirectPolicy{MaxRedirects: -1})}
+	// the credentials and headers are only sent to the crawled site
+	reqOpts, err := requests.options(*rootURL, siteHosts(*rootURL))
+	if err != nil {
+		log.Errorf("Error while configuring the requests: [%v]", err)
+		return 1
+	}
+	opts = append(opts, reqOpts...)
+	if err := requests.login(ctx, opts); err != nil {
+		log.Errorf("Error while logging in: [%v]", err)
+		return 2
+	}
+	redirects := crawler.NewRedirectMap(crawler.NewHTTPClient(opts...))
+	c := crawler.NewCrawler(opts...)
+	if err := c.CrawlPages(ctx, baseURL, redirects.Visit); err != nil {
+		log.Printf("Error while crawling: [%v]\n", err)
+		return 2
+	}
+	if err := redirects.Resolve(ctx); err != nil {
+		log.Errorf("Error while resolving redirect destinations: [%v]", err)
+		return 2
+	}
+
+	if err := writeRedirectMap(redirects, *output); err != nil {
+		log.Errorf("Error while writing redirect map: [%v]", err)
+		return 2
+	}
+	return 0
+}
+
+// writeRedirectMap writes the redirect map CSV into the file at path (or to stdout if path is "-")
+func writeRedirectMap(redirects *crawler.RedirectMap, path string) error {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	_, err := redirects.WriteTo(w)
+	return err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_runRedirectMap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the site is only mapped with the credentials and User-Agent of the request flags
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "s3cr3t" || r.UserAgent() != "my-bot/1.0" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, `<html><body><a href="/old.html">old</a><a href="/new.html">new</a></body></html>`)
+		case "/old.html":
+			http.Redirect(w, r, "/new.html", http.StatusMovedPermanently)
+		default:
+			fmt.Fprint(w, `<html><body></body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	output := filepath.Join(t.TempDir(), "redirects.csv")
+	code := runRedirectMap(context.Background(), []string{
+		"-url=" + srv.URL + "/index.html",
+		"-o=" + output,
+		"-user-agent=my-bot/1.0",
+		"-basic-auth=user:s3cr3t",
+		"-rate-limit=100",
+		"-robots-txt",
+	})
+	assert.Equal(t, 0, code)
+	csv, err := os.ReadFile(output)
+	assert.Nil(t, err)
+	assert.Equal(t, "source,destination,status,final_status,hops\n"+
+		srv.URL+"/index.html,"+srv.URL+"/index.html,200,200,0\n"+
+		srv.URL+"/new.html,"+srv.URL+"/new.html,200,200,0\n"+
+		srv.URL+"/old.html,"+srv.URL+"/new.html,301,200,1\n", string(csv))
+}
+
+func Test_runRedirectMap_InvalidRequestFlags(t *testing.T) {
+	tests := map[string][]string{
+		"basic_auth":     {"-basic-auth=user"},
+		"tls_version":    {"-tls-min=1.4"},
+		"crawl_delay":    {"-robots-txt", "-max-crawl-delay=1s"},
+		"unknown_flag":   {"-unknown"},
+		"positional_arg": {"https://my-web-site.com"},
+		"aws_sigv4":      {"-aws-sigv4=eu-west-1"},
+	}
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, 1, runRedirectMap(context.Background(), args))
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/rbroggi/crawler/crawler"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/url"
+	"os"
+)
+
+// sitemapUsage is printed when the sitemap sub-command is wrongly invoked
+const sitemapUsage = "usage: web-crawler sitemap [-url=<url_to_be_crawled>] [-o=<sitemap_file>]"
+
+// runSitemap implements the `sitemap` sub-command: it crawls the input url and
+// writes a sitemap.xml listing the crawled pages answered with a 200 status code
+func runSitemap(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("sitemap", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), sitemapUsage)
+		fs.PrintDefaults()
+	}
+	rootURL := fs.String("url", "http://localhost:8080/index.html", "URL to be recursively crawled")
+	output := fs.String("o", "-", "file the sitemap is written to ('-' for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if err := loadConfig(fs, os.LookupEnv); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 1
+	}
+
+	baseURL, err := url.Parse(*rootURL)
+	if err != nil {
+		log.Errorf("Error while parsing root URL: [%v]", err)
+		return 1
+	}
+
+	sitemap := crawler.NewSitemap()
+	if err := crawler.NewCrawler().CrawlPages(ctx, baseURL, sitemap.Visit); err != nil {
+		log.Printf("Error while crawling: [%v]\n", err)
+		return 2
+	}
+
+	if err := writeSitemap(sitemap, *output); err != nil {
+		log.Errorf("Error while writing sitemap: [%v]", err)
+		return 2
+	}
+	return 0
+}
+
+// writeSitemap writes the sitemap.xml document into the file at path (or to stdout if path is "-")
+func writeSitemap(sitemap *crawler.Sitemap, path string) error {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	_, err := sitemap.WriteTo(w)
+	return err
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"github.com/rbroggi/crawler/crawler"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// WritePageTemplateToStdOut builds a visit function that executes tmpl over
+// the crawled crawler.Page and writes the result to stdout followed by a new line.
+// The template can access the Page fields and methods (e.g. `{{.URL}} {{.Title}}`)
+func WritePageTemplateToStdOut(tmpl *template.Template) func(u *url.URL, page *html.Node) {
+	return func(u *url.URL, page *html.Node) {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, &crawler.Page{URL: u, Node: page}); err != nil {
+			log.Errorf("Error while executing template on page %s: [%v]", u, err)
+			return
+		}
+		fmt.Println(b.String())
+	}
+}
+
+// parseTemplate parses the -template flag, the sequences `\n` and
+// `\t` are interpreted as new line and tab to ease its writing in a shell
+func parseTemplate(text string) (*template.Template, error) {
+	text = strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(text)
+	return template.New("page").Parse(text)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"golang.org/x/net/html"
+	"net/url"
+	"strings"
+)
+
+func ExampleWritePageTemplateToStdOut() {
+	u, err := url.Parse("https://my-web-site.com/root/parent")
+	if err != nil {
+		panic("error parsing url")
+	}
+
+	htmlStr := `<!doctype html>
+		        <html>
+			    	<head><title>parent</title></head>
+			    	<body>
+						<p><a href="/index.html">index</a></p>
+						<p><a href="index.html">index</a></p>
+			  		</body>
+			    </html>`
+
+	node, err := html.Parse(strings.NewReader(htmlStr))
+	if err != nil {
+		panic("error parsing html")
+	}
+
+	tmpl, err := parseTemplate(`{{.URL}} [{{.Title}}]{{range .AbsoluteLinks}}\n\t{{.}}{{end}}`)
+	if err != nil {
+		panic("error parsing template")
+	}
+
+	WritePageTemplateToStdOut(tmpl)(u, node)
+
+	// Output:
+	// https://my-web-site.com/root/parent [parent]
+	// 	https://my-web-site.com/index.html
+	// 	https://my-web-site.com/root/index.html
+}
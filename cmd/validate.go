@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/rbroggi/crawler/crawler"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// validators are the built-in validators that can be enabled from the command line,
+// a validator is built for every crawl as some of them hold the crawl state
+var validators = map[string]func() crawler.Validator{
+	"status-ok":         func() crawler.Validator { return crawler.StatusOK },
+	"title-present":     func() crawler.Validator { return crawler.TitlePresent },
+	"canonical-present": func() crawler.Validator { return crawler.CanonicalPresent },
+	"tls":               crawler.TLSInventory,
+	"structured-data":   func() crawler.Validator { return crawler.StructuredData },
+}
+
+// reports are the built-in reports that can be enabled from the command line
+// along with the validators, their findings are part of the validation report
+var reports = map[string]func(s reportSettings) crawler.Report{
+	"unique-title":    func(reportSettings) crawler.Report { return crawler.UniqueTitle() },
+	"social-metadata": func(s reportSettings) crawler.Report { return crawler.SocialMetadata(s.ctx, s.client) },
+	"icons":           func(s reportSettings) crawler.Report { return crawler.Icons(s.ctx, s.client) },
+	"hreflang":        func(s reportSettings) crawler.Report { return crawler.Hreflang(s.ctx, s.client) },
+	"canonical-chain": func(s reportSettings) crawler.Report { return crawler.CanonicalChain(s.ctx, s.client) },
+	"click-depth":     func(s reportSettings) crawler.Report { return crawler.ClickDepth(s.maxClickDepth) },
+}
+
+// reportSettings are the settings the reports are built with
+type reportSettings struct {
+	// ctx bounds the requests issued by the reports once the crawl is done
+	ctx context.Context
+	// client issues the requests of the reports, it is built
+	// out of the options of the crawler
+	client *http.Client
+	// maxClickDepth is the click depth past which the click-depth report
+	// flags the pages, it is set with the -max-click-depth flag
+	maxClickDepth int
+}
+
+// parseValidators parses a comma separated list of built-in validator and report
+// names, the reports being built with settings
+func parseValidators(spec string, settings reportSettings) ([]crawler.Validator, []crawler.Report, error) {
+	var vs []crawler.Validator
+	var rs []crawler.Report
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if newReport, ok := reports[name]; ok {
+			rs = append(rs, newReport(settings))
+			continue
+		}
+		newValidator, ok := validators[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown validator %q", name)
+		}
+		vs = append(vs, newValidator())
+	}
+	return vs, rs, nil
+}
+
+// findingsCollector collects concurrently reported validation findings
+type findingsCollector struct {
+	mut      sync.Mutex
+	findings []crawler.Finding
+}
+
+// add is used as the onFinding callback of the crawler
+func (c *findingsCollector) add(f crawler.Finding) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.findings = append(c.findings, f)
+}
+
+// finalize adds the findings of the reports, once the crawl is done
+func (c *findingsCollector) finalize(reports []crawler.Report) error {
+	for _, r := range reports {
+		findings, err := r.Finalize()
+		if err != nil {
+			return err
+		}
+		for _, f := range findings {
+			c.add(f)
+		}
+	}
+	return nil
+}
+
+// sorted returns the collected findings sorted by url and validator
+func (c *findingsCollector) sorted() []crawler.Finding {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	findings := append([]crawler.Finding(nil), c.findings...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].URL != findings[j].URL {
+			return findings[i].URL < findings[j].URL
+		}
+		return findings[i].Validator < findings[j].Validator
+	})
+	return findings
+}
+
+// unsuppressed returns the findings that are not suppressed
+func unsuppressed(findings []crawler.Finding, s *crawler.Suppressions) []crawler.Finding {
+	var kept []crawler.Finding
+	for _, f := range findings {
+		if !s.Suppressed(f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// failing reports whether a finding has at least the severity min
+func failing(findings []crawler.Finding, min crawler.Severity) bool {
+	for _, f := range findings {
+		if f.Severity >= min {
+			return true
+		}
+	}
+	return false
+}
+
+// readSuppressions reads the suppression file at path, no path means no suppression
+func readSuppressions(path string) (*crawler.Suppressions, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return crawler.ParseSuppressions(f)
+}
+
+// readBaseline reads the baseline file at path, a missing file is an empty baseline
+func readBaseline(path string) (*crawler.Suppressions, error) {
+	baseline, err := readSuppressions(path)
+	if os.IsNotExist(err) {
+		log.Warnf("Baseline %s not found, all the validation findings are new", path)
+		return nil, nil
+	}
+	return baseline, err
+}
+
+// writeBaseline writes findings as the baseline file at path
+func writeBaseline(path string, findings []crawler.Finding) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := crawler.WriteBaseline(f, findings); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteValidationFindingsToStdOut writes to stdout the validation findings report,
+// one finding per line. The labels of the crawl, if any, are appended to each finding
+func WriteValidationFindingsToStdOut(findings []crawler.Finding, l labels) {
+	var b strings.Builder
+	_, err := fmt.Fprintf(&b, "validation findings: %d\n", len(findings))
+	if err != nil {
+		log.Errorf("Error while writing into strings.Builder")
+		return
+	}
+	for _, f := range findings {
+		_, err = fmt.Fprintf(&b, "page: %s | validator: %s | severity: %s | id: %s | message: %s", f.URL, f.Validator, f.Severity, f.ID(), f.Message)
+		if err == nil && len(l) > 0 {
+			_, err = fmt.Fprintf(&b, " | labels: %s", l)
+		}
+		if err == nil {
+			_, err = b.WriteString("\n")
+		}
+		if err != nil {
+			log.Errorf("Error while writing into strings.Builder")
+			return
+		}
+	}
+	fmt.Print(b.String())
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/rbroggi/crawler/crawler"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func Test_parseValidators(t *testing.T) {
+	vs, rs, err := parseValidators("status-ok, title-present,canonical-present,tls,unique-title", reportSettings{})
+	assert.Nil(t, err)
+	assert.Len(t, vs, 4)
+	assert.Len(t, rs, 1)
+
+	vs, rs, err = parseValidators("", reportSettings{})
+	assert.Nil(t, err)
+	assert.Empty(t, vs)
+	assert.Empty(t, rs)
+
+	_, _, err = parseValidators("status-ok,unknown", reportSettings{})
+	assert.NotNil(t, err)
+}
+
+func Test_parseValidators_ClickDepth(t *testing.T) {
+	_, rs, err := parseValidators("click-depth", reportSettings{maxClickDepth: 0})
+	assert.Nil(t, err)
+	assert.Len(t, rs, 1)
+	for u, body := range map[string]string{
+		"https://my-web-site.com/":  `<a href="/a">a</a>`,
+		"https://my-web-site.com/a": "a",
+	} {
+		pageURL, err := url.Parse(u)
+		assert.Nil(t, err)
+		node, err := html.Parse(strings.NewReader(body))
+		assert.Nil(t, err)
+		depth := len(pageURL.Path) - 1
+		rs[0].Consume(&crawler.Page{URL: pageURL, Node: node, StatusCode: 200, Header: http.Header{"Content-Type": {"text/html"}}, Depth: depth})
+	}
+	findings, err := rs[0].Finalize()
+	assert.Nil(t, err)
+	// the maximum click depth is the one of the settings
+	assert.Contains(t, findings, crawler.Finding{URL: "https://my-web-site.com/a", Validator: "click-depth", Message: "click depth 1 exceeds 0", Severity: crawler.SeverityWarning})
+}
+
+func Test_findingsCollector_finalize(t *testing.T) {
+	r := crawler.UniqueTitle()
+	for _, u := range []string{"https://my-web-site.com/b", "https://my-web-site.com/a"} {
+		pageURL, err := url.Parse(u)
+		assert.Nil(t, err)
+		node, err := html.Parse(strings.NewReader("<title>page</title>"))
+		assert.Nil(t, err)
+		r.Consume(&crawler.Page{URL: pageURL, Node: node, StatusCode: 200})
+	}
+
+	c := &findingsCollector{}
+	c.add(crawler.Finding{URL: "https://my-web-site.com/a", Validator: "status-ok", Message: "unexpected status code 404"})
+	assert.Nil(t, c.finalize([]crawler.Report{r}))
+	assert.Equal(t, []crawler.Finding{
+		{URL: "https://my-web-site.com/a", Validator: "status-ok", Message: "unexpected status code 404"},
+		{URL: "https://my-web-site.com/a", Validator: "unique-title", Message: `title "page" shared with 1 other pages`, Severity: crawler.SeverityWarning},
+		{URL: "https://my-web-site.com/b", Validator: "unique-title", Message: `title "page" shared with 1 other pages`, Severity: crawler.SeverityWarning},
+	}, c.sorted())
+}
+
+func ExampleWriteValidationFindingsToStdOut() {
+	c := &findingsCollector{}
+	c.add(crawler.Finding{URL: "https://my-web-site.com/b", Validator: "title-present", Message: "missing or empty <title>", Severity: crawler.SeverityWarning})
+	c.add(crawler.Finding{URL: "https://my-web-site.com/a", Validator: "status-ok", Message: "unexpected status code 404", Severity: crawler.SeverityError})
+
+	WriteValidationFindingsToStdOut(c.sorted(), nil)
+
+	// Output:
+	// validation findings: 2
+	// page: https://my-web-site.com/a | validator: status-ok | severity: error | id: c4a93e0b004b | message: unexpected status code 404
+	// page: https://my-web-site.com/b | validator: title-present | severity: warning | id: 100a2b01ee37 | message: missing or empty <title>
+}
+
+func Test_unsuppressed(t *testing.T) {
+	s, err := crawler.ParseSuppressions(strings.NewReader("title-present https://my-web-site.com/*"))
+	assert.Nil(t, err)
+	findings := []crawler.Finding{
+		{URL: "https://my-web-site.com/a", Validator: "status-ok", Severity: crawler.SeverityError},
+		{URL: "https://my-web-site.com/a", Validator: "title-present", Severity: crawler.SeverityWarning},
+	}
+	assert.Equal(t, findings[:1], unsuppressed(findings, s))
+	assert.Equal(t, findings, unsuppressed(findings, nil))
+
+	assert.True(t, failing(findings, crawler.SeverityWarning))
+	assert.False(t, failing(findings[1:], crawler.SeverityError))
+}
@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// aiDirectives are the robots directives used by sites to opt out of AI crawlers
+var aiDirectives = map[string]struct{}{
+	"noai":      {},
+	"noimageai": {},
+}
+
+// AIPolicy holds the AI-crawl policy files published at the root of a site
+type AIPolicy struct {
+	// LLMsTxt is the content of /llms.txt, empty if the site does not publish it
+	LLMsTxt string
+	// AITxt is the content of /ai.txt, empty if the site does not publish it
+	AITxt string
+}
+
+// GetAIPolicy retrieves the AI-crawl policy files (llms.txt and ai.txt) of
+// the site hosting base. A missing file is not an error
+func GetAIPolicy(ctx context.Context, base *url.URL) (*AIPolicy, error) {
+	llms, err := getRootFile(ctx, base, "/llms.txt")
+	if err != nil {
+		return nil, err
+	}
+	ai, err := getRootFile(ctx, base, "/ai.txt")
+	if err != nil {
+		return nil, err
+	}
+	return &AIPolicy{LLMsTxt: llms, AITxt: ai}, nil
+}
+
+// AIDirectives returns the AI opt-out directives (e.g. noai, noimageai) that a page
+// declares in its robots meta tags or in its X-Robots-Tag headers
+func AIDirectives(p *Page) []string {
+	var found []string
+	seen := make(map[string]struct{})
+	add := func(directives string) {
+		for _, d := range strings.Split(directives, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if _, ok := aiDirectives[d]; !ok {
+				continue
+			}
+			if _, ok := seen[d]; !ok {
+				seen[d] = struct{}{}
+				found = append(found, d)
+			}
+		}
+	}
+	for _, v := range p.Header.Values("X-Robots-Tag") {
+		add(v)
+	}
+	add(getMeta(p.Node, "robots"))
+	return found
+}
+
+// getRootFile retrieves the content of a file at the root of the site hosting base,
+// it returns an empty string if the file does not exist
+func getRootFile(ctx context.Context, base *url.URL, path string) (string, error) {
+	u := &url.URL{Scheme: base.Scheme, Host: base.Host, Path: path}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("error while preparing request - %v", err)
+	}
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error while getting %s - %v", u, err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return "", nil
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return "", fmt.Errorf("error while reading %s - %v", u, err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func Test_AIDirectives(t *testing.T) {
+	tests := map[string]struct {
+		header  http.Header
+		htmlStr string
+		want    []string
+	}{
+		"no_directives": {
+			htmlStr: `<html><head><meta name="robots" content="index, follow"></head></html>`,
+		},
+		"meta_directives": {
+			htmlStr: `<html><head><meta name="robots" content="noai, NoImageAI"></head></html>`,
+			want:    []string{"noai", "noimageai"},
+		},
+		"header_and_meta_without_duplicates": {
+			header:  http.Header{"X-Robots-Tag": {"noai"}},
+			htmlStr: `<html><head><meta name="robots" content="noai,noimageai"></head></html>`,
+			want:    []string{"noai", "noimageai"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newPage(t, "https://my-web-site.com/", 200, tt.header, tt.htmlStr)
+			assert.Equal(t, tt.want, AIDirectives(p))
+		})
+	}
+}
+
+func Test_GetAIPolicy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/llms.txt" {
+			w.Write([]byte("# my site\n"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	policy, err := GetAIPolicy(context.Background(), getURL(srv.URL+"/some/page.html"))
+	assert.Nil(t, err)
+	assert.Equal(t, &AIPolicy{LLMsTxt: "# my site\n"}, policy)
+}
+
+func Test_crawler_Crawl_WithAIOptOut(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html": `<html><head><title>index</title><meta name="robots" content="noai"></head>
+						<body><a href="page.html">page</a></body></html>`,
+		"/page.html": linksPage("page", "index.html"),
+	})
+
+	var mut sync.Mutex
+	var titles []string
+	visit := func(u *url.URL, page *html.Node) {
+		mut.Lock()
+		titles = append(titles, pageTitle(page))
+		mut.Unlock()
+	}
+
+	// the opted-out index is not visited but its links are followed
+	err := NewCrawler(WithAIOptOut()).Crawl(context.Background(), getURL(site.URL+"/index.html"), visit)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"page"}, titles)
+
+	titles = nil
+	err = NewCrawler().Crawl(context.Background(), getURL(site.URL+"/index.html"), visit)
+	assert.Nil(t, err)
+	sort.Strings(titles)
+	assert.Equal(t, []string{"index", "page"}, titles)
+}
@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AuthRealm holds the credentials of the pages under a path prefix, e.g. a
+// token for `/api/` and basic authentication for `/intranet/`
+type AuthRealm struct {
+	// Host, when set, restricts the realm to the pages of a host (e.g.
+	// `my-web-site.com` or `localhost:8080`)
+	Host string
+	// PathPrefix is the prefix of the paths of the pages of the realm,
+	// an empty prefix matches all the pages
+	PathPrefix string
+	// Header are the headers set on the requests of the realm (e.g. Authorization)
+	Header http.Header
+}
+
+// BasicAuthHeader returns the header of the HTTP basic authentication of user
+func BasicAuthHeader(user, password string) http.Header {
+	credentials := base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+	return http.Header{"Authorization": {"Basic " + credentials}}
+}
+
+// BearerAuthHeader returns the header of the authentication with a bearer token
+func BearerAuthHeader(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+// matches reports whether u belongs to the realm
+func (r AuthRealm) matches(u *url.URL) bool {
+	return (r.Host == "" || strings.EqualFold(r.Host, u.Host)) && strings.HasPrefix(u.Path, r.PathPrefix)
+}
+
+// authRealm returns the realm of u out of realms, the one with the longest
+// matching path prefix, nil if u belongs to none of them. crossHost, for a
+// request redirected from another host, excludes the realms without host
+func authRealm(realms []AuthRealm, u *url.URL, crossHost bool) *AuthRealm {
+	var best *AuthRealm
+	for i, r := range realms {
+		if !r.matches(u) || crossHost && r.Host == "" {
+			continue
+		}
+		// a realm restricted to the host of u wins over an unrestricted one
+		if best == nil || len(r.PathPrefix) > len(best.PathPrefix) ||
+			len(r.PathPrefix) == len(best.PathPrefix) && best.Host == "" && r.Host != "" {
+			best = &realms[i]
+		}
+	}
+	return best
+}
+
+// authRealmsMiddleware returns a Middleware setting on every request the
+// headers of its realm, if any. The realms without host are not applied to
+// the redirect hops to another host than the one of the first request, so
+// that their credentials do not leak to the hosts a site redirects to
+func authRealmsMiddleware(realms []AuthRealm) Middleware {
+	return OnRequest(func(req *http.Request) error {
+		crossHost := !strings.EqualFold(redirectOrigin(req).Host, req.URL.Host)
+		realm := authRealm(realms, req.URL, crossHost)
+		if realm == nil {
+			return nil
+		}
+		setHeaders(req, realm.Header)
+		return nil
+	})
+}
+
+// redirectOrigin returns the URL of the first request of the redirect chain of req
+func redirectOrigin(req *http.Request) *url.URL {
+	for req.Response != nil && req.Response.Request != nil {
+		req = req.Response.Request
+	}
+	return req.URL
+}
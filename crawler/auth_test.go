@@ -0,0 +1,147 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func Test_authRealm(t *testing.T) {
+	realms := []AuthRealm{
+		{PathPrefix: "/api/", Header: BearerAuthHeader("token")},
+		{PathPrefix: "/api/admin/", Header: BearerAuthHeader("admin-token")},
+		{Host: "other-web-site.com", PathPrefix: "/api/", Header: BearerAuthHeader("other-token")},
+		{PathPrefix: "/intranet/", Header: BasicAuthHeader("user", "password")},
+	}
+	tests := map[string]struct {
+		url       string
+		crossHost bool
+		want      string
+	}{
+		"prefix":                {url: "https://my-web-site.com/api/pages", want: "Bearer token"},
+		"cross_host":            {url: "https://my-web-site.com/api/pages", crossHost: true},
+		"cross_host_restricted": {url: "https://other-web-site.com/api/pages", crossHost: true, want: "Bearer other-token"},
+		"longest_prefix":        {url: "https://my-web-site.com/api/admin/users", want: "Bearer admin-token"},
+		"host_restricted":       {url: "https://Other-Web-Site.com/api/pages", want: "Bearer other-token"},
+		"basic":                 {url: "https://my-web-site.com/intranet/", want: "Basic dXNlcjpwYXNzd29yZA=="},
+		"no_realm":              {url: "https://my-web-site.com/index.html"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			realm := authRealm(realms, getURL(tt.url), tt.crossHost)
+			if tt.want == "" {
+				assert.Nil(t, realm)
+				return
+			}
+			assert.Equal(t, tt.want, realm.Header.Get("Authorization"))
+		})
+	}
+}
+
+func Test_crawler_CrawlPages_WithAuthRealms(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, linksPage(r.Header.Get("Authorization"), "/api/pages", "/intranet/", "/redirected"))
+	})
+	mux.Handle("/redirected", http.RedirectHandler("/api/redirected", http.StatusFound))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var mu sync.Mutex
+	titles := make(map[string]string)
+	c := NewCrawler(WithAuthRealms(
+		AuthRealm{PathPrefix: "/api/", Header: BearerAuthHeader("token")},
+		AuthRealm{PathPrefix: "/intranet/", Header: BasicAuthHeader("user", "password")},
+	))
+	err := c.CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+		mu.Lock()
+		defer mu.Unlock()
+		titles[p.URL.Path] = p.Title()
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"/index.html": "",
+		"/api/pages":  "Bearer token",
+		"/intranet/":  "Basic dXNlcjpwYXNzd29yZA==",
+		// the redirected request gets the headers of its realm
+		"/redirected": "Bearer token",
+	}, titles)
+}
+
+func Test_crawler_CrawlPages_WithAuthRealms_CrossHostRedirect(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	var leaked []string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		for _, name := range []string{"Authorization", "X-Api-Key"} {
+			if v := r.Header.Get(name); v != "" {
+				leaked = append(leaked, v)
+			}
+		}
+		fmt.Fprint(w, linksPage("other"))
+	}))
+	defer other.Close()
+	srv := httptest.NewServer(http.RedirectHandler(other.URL+"/landing.html", http.StatusFound))
+	defer srv.Close()
+
+	opts := []Option{
+		WithAuthRealms(AuthRealm{Header: BearerAuthHeader("token")}),
+		WithBasicAuth("user", "password"),
+		WithHeaders(http.Header{"X-Api-Key": {"t0k3n"}}),
+	}
+	for _, opt := range opts {
+		err := NewCrawler(opt).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(*Page) {})
+		assert.Nil(t, err)
+	}
+	// the unscoped headers are not sent to the host the site redirects to
+	assert.Equal(t, len(opts), requests)
+	assert.Empty(t, leaked)
+}
+
+func Test_crawler_CrawlPages_WithBasicAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, linksPage(r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+	host := getURL(srv.URL).Host
+
+	tests := map[string]struct {
+		opt  Option
+		want string
+	}{
+		"basic_auth": {
+			opt:  WithBasicAuth("user", "password"),
+			want: "Basic dXNlcjpwYXNzd29yZA==",
+		},
+		"basic_auth_in_scope": {
+			opt:  WithBasicAuth("user", "password", "other-web-site.com", host),
+			want: "Basic dXNlcjpwYXNzd29yZA==",
+		},
+		"basic_auth_out_of_scope": {
+			opt: WithBasicAuth("user", "password", "other-web-site.com"),
+		},
+		"bearer_token": {
+			opt:  WithBearerToken("t0k3n", host),
+			want: "Bearer t0k3n",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var title string
+			err := NewCrawler(tt.opt).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+				title = p.Title()
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, title)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Transform rewrites in place a URL as one step of the canonicalization pipeline
+type Transform func(u *url.URL)
+
+// TransformFactory builds a Transform given the argument that follows the
+// transform name in a pipeline specification (e.g. `utm_*` in `drop-params:utm_*`).
+// The argument is empty when the specification has none
+type TransformFactory func(arg string) (Transform, error)
+
+var (
+	transformsMut sync.RWMutex
+	// transforms is the registry of the named transforms
+	transforms = map[string]TransformFactory{
+		"strip-fragment": noArg(StripFragment),
+		"sort-query":     noArg(SortQuery),
+		"lowercase-host": noArg(LowercaseHost),
+		"drop-params":    dropParamsFactory,
+	}
+)
+
+// RegisterTransform makes a transform available under name to ParseTransforms.
+// Registering an already registered name replaces the previous factory
+func RegisterTransform(name string, factory TransformFactory) {
+	transformsMut.Lock()
+	defer transformsMut.Unlock()
+	transforms[name] = factory
+}
+
+// ParseTransforms builds a canonicalization pipeline from a comma separated list of
+// registered transform names. A transform accepting an argument is written as
+// `name:arg` (e.g. `strip-fragment,sort-query,lowercase-host,drop-params:utm_*|fbclid`)
+func ParseTransforms(spec string) ([]Transform, error) {
+	var pipeline []Transform
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, arg := item, ""
+		if i := strings.Index(item, ":"); i >= 0 {
+			name, arg = item[:i], item[i+1:]
+		}
+		transformsMut.RLock()
+		factory, ok := transforms[name]
+		transformsMut.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown canonicalization transform %q, available transforms: %s", name, strings.Join(sortedKeys(), ", "))
+		}
+		t, err := factory(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid canonicalization transform %q - %v", item, err)
+		}
+		pipeline = append(pipeline, t)
+	}
+	return pipeline, nil
+}
+
+// Canonicalize applies the transforms, in order, to a copy of u
+func Canonicalize(u *url.URL, transforms []Transform) *url.URL {
+	c := *u
+	if u.User != nil {
+		user := *u.User
+		c.User = &user
+	}
+	for _, t := range transforms {
+		t(&c)
+	}
+	return &c
+}
+
+// StripFragment removes the fragment (`#section`) of a URL
+func StripFragment(u *url.URL) {
+	u.Fragment = ""
+	u.RawFragment = ""
+}
+
+// SortQuery sorts the query parameters of a URL by key
+func SortQuery(u *url.URL) {
+	if u.RawQuery == "" {
+		return
+	}
+	// url.Values.Encode sorts by key
+	u.RawQuery = u.Query().Encode()
+}
+
+// LowercaseHost lower-cases the host of a URL
+func LowercaseHost(u *url.URL) {
+	u.Host = strings.ToLower(u.Host)
+}
+
+// DropParams builds a transform removing the query parameters whose key
+// matches one of the patterns (path.Match syntax, e.g. `utm_*`)
+func DropParams(patterns ...string) (Transform, error) {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("bad pattern %q - %v", p, err)
+		}
+	}
+	return func(u *url.URL) {
+		if u.RawQuery == "" {
+			return
+		}
+		q := u.Query()
+		for key := range q {
+			for _, p := range patterns {
+				if ok, _ := path.Match(p, key); ok {
+					q.Del(key)
+					break
+				}
+			}
+		}
+		u.RawQuery = q.Encode()
+	}, nil
+}
+
+// dropParamsFactory builds DropParams from a `|` separated list of patterns
+func dropParamsFactory(arg string) (Transform, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("missing parameter patterns")
+	}
+	return DropParams(strings.Split(arg, "|")...)
+}
+
+// noArg adapts a transform without argument to a TransformFactory
+func noArg(t Transform) TransformFactory {
+	return func(arg string) (Transform, error) {
+		if arg != "" {
+			return nil, fmt.Errorf("unexpected argument %q", arg)
+		}
+		return t, nil
+	}
+}
+
+// sortedKeys returns the registered transform names, it is used in error messages
+func sortedKeys() []string {
+	transformsMut.RLock()
+	defer transformsMut.RUnlock()
+	keys := make([]string, 0, len(transforms))
+	for k := range transforms {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,187 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxCanonicalHops is the maximum number of canonical links followed from a page
+const maxCanonicalHops = 10
+
+// canonicalPage is the status and the canonical URL of a crawled or fetched page
+type canonicalPage struct {
+	status int
+	// canonical is the normalized URL of the canonical link, empty without one
+	canonical string
+	// err is the error fetching the page
+	err error
+}
+
+// canonicalChain is the Report built by CanonicalChain
+type canonicalChain struct {
+	ctx    context.Context
+	client *http.Client
+
+	mut      sync.Mutex
+	findings []Finding
+	// pages are the crawled pages, by normalized URL
+	pages map[string]*canonicalPage
+}
+
+// CanonicalChain builds a Report auditing the canonical links of the crawled HTML
+// pages: it flags the canonical targets outside the host of the page and, once the
+// crawl is done, the targets that are not answered with a 200 status code and the
+// targets that are themselves canonicalized to another page, reporting the whole
+// chain or loop. The targets that were not crawled are fetched with client, e.g. the
+// one built by NewHTTPClient, without following the redirects, and with ctx,
+// Finalize returning its error once it is done. A nil client means http.DefaultClient
+func CanonicalChain(ctx context.Context, client *http.Client) Report {
+	// a redirected target is reported rather than followed
+	return &canonicalChain{ctx: ctx, client: auditClient(client, true), pages: make(map[string]*canonicalPage)}
+}
+
+func (r *canonicalChain) Consume(p *Page) {
+	page := &canonicalPage{status: p.StatusCode}
+	var findings []Finding
+	if isHTMLPage(p) {
+		href := strings.TrimSpace(getCanonical(p.Node))
+		if href != "" {
+			if u, err := NewLinkResolver(p.URL).Resolve(href); err != nil {
+				findings = append(findings, Finding{URL: p.URL.String(), Validator: "canonical-chain", Message: fmt.Sprintf("invalid canonical URL %q", href), Severity: SeverityError})
+			} else {
+				page.canonical = Normalize(u).String()
+				if !isSameDomain(Normalize(p.URL), parseURL(page.canonical)) {
+					findings = append(findings, Finding{URL: p.URL.String(), Validator: "canonical-chain", Message: fmt.Sprintf("canonical target %s is out of scope", page.canonical), Severity: SeverityWarning})
+				}
+			}
+		}
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.findings = append(r.findings, findings...)
+	r.pages[Normalize(p.URL).String()] = page
+}
+
+func (r *canonicalChain) Finalize() ([]Finding, error) {
+	r.mut.Lock()
+	findings := append([]Finding(nil), r.findings...)
+	// the crawled pages are not modified once consumed
+	pages := make(map[string]*canonicalPage, len(r.pages))
+	for u, page := range r.pages {
+		pages[u] = page
+	}
+	r.mut.Unlock()
+
+	// the targets are fetched once all the crawled pages are known,
+	// without holding the lock
+	crawled := make([]string, 0, len(pages))
+	for u, page := range pages {
+		if page.status >= http.StatusOK && page.status < http.StatusMultipleChoices && page.canonical != "" && page.canonical != u {
+			crawled = append(crawled, u)
+		}
+	}
+	sort.Strings(crawled)
+	for _, u := range crawled {
+		finding, ok := r.check(u, pages)
+		if r.ctx.Err() != nil {
+			return nil, r.ctx.Err()
+		}
+		if ok {
+			findings = append(findings, finding)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].URL != findings[j].URL {
+			return findings[i].URL < findings[j].URL
+		}
+		return findings[i].Message < findings[j].Message
+	})
+	return findings, nil
+}
+
+// check follows the canonical links from the page at u, within its host, and
+// returns the finding describing a broken target, a chain or a loop. The targets
+// missing from pages are fetched and added to it
+func (r *canonicalChain) check(u string, pages map[string]*canonicalPage) (Finding, bool) {
+	origin := parseURL(u)
+	chain := []string{u}
+	seen := map[string]bool{u: true}
+	for next := pages[u].canonical; len(chain) <= maxCanonicalHops; {
+		// the targets out of scope are reported by Consume
+		if !isSameDomain(origin, parseURL(next)) {
+			break
+		}
+		target := r.get(next, pages)
+		if len(chain) == 1 {
+			switch {
+			case target.err != nil:
+				return Finding{URL: u, Validator: "canonical-chain", Message: fmt.Sprintf("broken canonical target %s: %v", next, target.err), Severity: SeverityError}, true
+			case target.status != http.StatusOK:
+				return Finding{URL: u, Validator: "canonical-chain", Message: fmt.Sprintf("broken canonical target %s: status %d", next, target.status), Severity: SeverityError}, true
+			}
+		}
+		chain = append(chain, next)
+		if seen[next] {
+			return Finding{URL: u, Validator: "canonical-chain", Message: "canonical loop " + strings.Join(chain, " -> "), Severity: SeverityError}, true
+		}
+		seen[next] = true
+		if target.canonical == "" || target.canonical == next {
+			break
+		}
+		next = target.canonical
+	}
+	if len(chain) > 2 {
+		return Finding{URL: u, Validator: "canonical-chain", Message: "canonical chain " + strings.Join(chain, " -> "), Severity: SeverityWarning}, true
+	}
+	return Finding{}, false
+}
+
+// get returns the page at the normalized URL u out of pages, fetching it if it is missing
+func (r *canonicalChain) get(u string, pages map[string]*canonicalPage) *canonicalPage {
+	if page, ok := pages[u]; ok {
+		return page
+	}
+	page := r.fetch(u)
+	pages[u] = page
+	return page
+}
+
+// fetch gets the page at u and its canonical URL
+func (r *canonicalChain) fetch(u string) *canonicalPage {
+	resp, err := doRequest(r.ctx, r.client, http.MethodGet, u)
+	if err != nil {
+		return &canonicalPage{err: err}
+	}
+	defer resp.Body.Close()
+	page := &canonicalPage{status: resp.StatusCode}
+	if resp.StatusCode != http.StatusOK {
+		return page
+	}
+	node, err := html.Parse(io.LimitReader(resp.Body, maxAuditPageSize))
+	if err != nil {
+		return &canonicalPage{err: err}
+	}
+	if href := strings.TrimSpace(getCanonical(node)); href != "" {
+		if c, err := NewLinkResolver(resp.Request.URL).Resolve(href); err == nil {
+			page.canonical = Normalize(c).String()
+		}
+	}
+	return page
+}
+
+// parseURL parses the URL u, returning nil if it is malformed
+func parseURL(u string) *url.URL {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil
+	}
+	return parsed
+}
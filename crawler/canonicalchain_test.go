@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCanonicalChain(t *testing.T) {
+	canonical := func(href string) string {
+		return fmt.Sprintf(`<html><head><link rel="canonical" href="%s"></head></html>`, href)
+	}
+	// the targets that were not crawled are fetched
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/excluded":
+			fmt.Fprint(w, canonical("/final"))
+		case "/moved":
+			http.Redirect(w, r, "/final", http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	u := func(path string) string { return srv.URL + path }
+	r := CanonicalChain(context.Background(), srv.Client())
+	r.Consume(newPage(t, u("/self"), 200, nil, canonical(u("/self"))))
+	r.Consume(newPage(t, u("/final"), 200, nil, canonical("/final")))
+	r.Consume(newPage(t, u("/direct"), 200, nil, canonical("final")))
+	r.Consume(newPage(t, u("/chain"), 200, nil, canonical("/direct")))
+	r.Consume(newPage(t, u("/fetched-chain"), 200, nil, canonical("/excluded")))
+	r.Consume(newPage(t, u("/loop-a"), 200, nil, canonical("/loop-b")))
+	r.Consume(newPage(t, u("/loop-b"), 200, nil, canonical("/loop-a")))
+	r.Consume(newPage(t, u("/broken"), 200, nil, canonical("/missing")))
+	r.Consume(newPage(t, u("/redirected"), 200, nil, canonical("/moved")))
+	r.Consume(newPage(t, u("/not-found"), 200, nil, canonical("/gone")))
+	r.Consume(newPage(t, u("/gone"), 410, nil, ""))
+	r.Consume(newPage(t, u("/out-of-scope"), 200, nil, canonical("https://other-web-site.com/page")))
+	r.Consume(newPage(t, u("/invalid"), 200, nil, canonical("http://[::1")))
+
+	findings, err := r.Finalize()
+	assert.Nil(t, err)
+	assert.Equal(t, []Finding{
+		{URL: u("/broken"), Validator: "canonical-chain", Message: fmt.Sprintf("broken canonical target %s: status 404", u("/missing")), Severity: SeverityError},
+		{URL: u("/chain"), Validator: "canonical-chain", Message: fmt.Sprintf("canonical chain %s -> %s -> %s", u("/chain"), u("/direct"), u("/final")), Severity: SeverityWarning},
+		{URL: u("/fetched-chain"), Validator: "canonical-chain", Message: fmt.Sprintf("canonical chain %s -> %s -> %s", u("/fetched-chain"), u("/excluded"), u("/final")), Severity: SeverityWarning},
+		{URL: u("/invalid"), Validator: "canonical-chain", Message: `invalid canonical URL "http://[::1"`, Severity: SeverityError},
+		{URL: u("/loop-a"), Validator: "canonical-chain", Message: fmt.Sprintf("canonical loop %s -> %s -> %s", u("/loop-a"), u("/loop-b"), u("/loop-a")), Severity: SeverityError},
+		{URL: u("/loop-b"), Validator: "canonical-chain", Message: fmt.Sprintf("canonical loop %s -> %s -> %s", u("/loop-b"), u("/loop-a"), u("/loop-b")), Severity: SeverityError},
+		{URL: u("/not-found"), Validator: "canonical-chain", Message: fmt.Sprintf("broken canonical target %s: status 410", u("/gone")), Severity: SeverityError},
+		{URL: u("/out-of-scope"), Validator: "canonical-chain", Message: "canonical target https://other-web-site.com/page is out of scope", Severity: SeverityWarning},
+		{URL: u("/redirected"), Validator: "canonical-chain", Message: fmt.Sprintf("broken canonical target %s: status 301", u("/moved")), Severity: SeverityError},
+	}, findings)
+}
+
+func TestCanonicalChain_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the run is interrupted while the first target is fetched
+		requests++
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	r := CanonicalChain(ctx, srv.Client())
+	r.Consume(newPage(t, srv.URL+"/a", 200, nil, `<link rel="canonical" href="/target-a">`))
+	r.Consume(newPage(t, srv.URL+"/b", 200, nil, `<link rel="canonical" href="/target-b">`))
+	findings, err := r.Finalize()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, findings)
+	// the following targets are not fetched
+	assert.Equal(t, 1, requests)
+}
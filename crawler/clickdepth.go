@@ -0,0 +1,116 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// DefaultMaxClickDepth is the click depth past which the pages are flagged by the
+// report of the click-depth validator, the pages should be at most 3 clicks away from
+// the homepage
+const DefaultMaxClickDepth = 3
+
+// clickDepth is the Report built by ClickDepth
+type clickDepth struct {
+	maxDepth int
+
+	mut sync.Mutex
+	// roots are the crawled base URLs
+	roots []string
+	// links are the distinct links of each crawled page, by normalized URL,
+	// only the HTML pages answered with a 2xx status code have links
+	links map[string][]string
+	// pages are the normalized URL of the pages answered with a 2xx status code
+	pages map[string]bool
+}
+
+// ClickDepth builds a Report auditing the internal link graph of the crawl: it
+// computes the click depth of every page answered with a 2xx status code, i.e. the
+// minimal number of links followed from the base URL to reach it, and flags the
+// pages deeper than maxDepth as well as the pages linked from a single other page,
+// which are lost once that link is removed. The links to pages that were not crawled
+// are ignored
+func ClickDepth(maxDepth int) Report {
+	return &clickDepth{maxDepth: maxDepth, links: make(map[string][]string), pages: make(map[string]bool)}
+}
+
+func (r *clickDepth) Consume(p *Page) {
+	u := Normalize(p.URL).String()
+	ok := p.StatusCode >= http.StatusOK && p.StatusCode < http.StatusMultipleChoices
+	var links []string
+	if ok && isHTMLPage(p) {
+		seen := map[string]bool{u: true}
+		for _, link := range p.AbsoluteLinks() {
+			l := Normalize(link)
+			StripFragment(l)
+			if s := l.String(); !seen[s] {
+				seen[s] = true
+				links = append(links, s)
+			}
+		}
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if p.Depth == 0 {
+		r.roots = append(r.roots, u)
+	}
+	r.links[u] = links
+	if ok {
+		r.pages[u] = true
+	}
+}
+
+func (r *clickDepth) Finalize() ([]Finding, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	// breadth first walk of the links from the roots
+	depths := make(map[string]int)
+	queue := make([]string, 0, len(r.links))
+	for _, root := range r.roots {
+		if _, ok := depths[root]; !ok {
+			depths[root] = 0
+			queue = append(queue, root)
+		}
+	}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for _, link := range r.links[u] {
+			if _, ok := r.links[link]; !ok {
+				continue
+			}
+			if _, ok := depths[link]; !ok {
+				depths[link] = depths[u] + 1
+				queue = append(queue, link)
+			}
+		}
+	}
+	// the pages linking to each page, reachable or not
+	inLinks := make(map[string][]string)
+	for u, links := range r.links {
+		for _, link := range links {
+			inLinks[link] = append(inLinks[link], u)
+		}
+	}
+
+	var findings []Finding
+	for u := range r.pages {
+		depth, ok := depths[u]
+		if ok && depth > r.maxDepth {
+			findings = append(findings, Finding{URL: u, Validator: "click-depth", Message: fmt.Sprintf("click depth %d exceeds %d", depth, r.maxDepth), Severity: SeverityWarning})
+		}
+		if ok && depth > 0 && len(inLinks[u]) == 1 {
+			findings = append(findings, Finding{URL: u, Validator: "click-depth", Message: fmt.Sprintf("only linked from %s", inLinks[u][0]), Severity: SeverityWarning})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].URL != findings[j].URL {
+			return findings[i].URL < findings[j].URL
+		}
+		return findings[i].Message < findings[j].Message
+	})
+	return findings, nil
+}
@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClickDepth(t *testing.T) {
+	const site = "https://my-web-site.com"
+	page := func(path string, depth, status int, hrefs ...string) *Page {
+		p := newPage(t, site+path, status, nil, linksPage(path, hrefs...))
+		p.Depth = depth
+		return p
+	}
+
+	r := ClickDepth(2)
+	r.Consume(page("/", 0, 200, "a.html", "b.html", "missing.html", "https://other-web-site.com/"))
+	r.Consume(page("/a.html", 1, 200, "b.html", "c.html#top", "c.html", "/"))
+	r.Consume(page("/b.html", 1, 200, "a.html"))
+	r.Consume(page("/c.html", 2, 200, "d.html"))
+	// found through a longer path first, its click depth is the shortest path
+	r.Consume(page("/d.html", 4, 200, "e.html"))
+	r.Consume(page("/e.html", 4, 200))
+	// the links of the error pages are no clicks
+	r.Consume(page("/missing.html", 1, 404, "e.html"))
+
+	findings, err := r.Finalize()
+	assert.Nil(t, err)
+	assert.Equal(t, []Finding{
+		{URL: site + "/c.html", Validator: "click-depth", Message: "only linked from https://my-web-site.com/a.html", Severity: SeverityWarning},
+		{URL: site + "/d.html", Validator: "click-depth", Message: "click depth 3 exceeds 2", Severity: SeverityWarning},
+		{URL: site + "/d.html", Validator: "click-depth", Message: "only linked from https://my-web-site.com/c.html", Severity: SeverityWarning},
+		{URL: site + "/e.html", Validator: "click-depth", Message: "click depth 4 exceeds 2", Severity: SeverityWarning},
+		{URL: site + "/e.html", Validator: "click-depth", Message: "only linked from https://my-web-site.com/d.html", Severity: SeverityWarning},
+	}, findings)
+}
+
+func Test_crawler_CrawlPages_ClickDepth(t *testing.T) {
+	srv := newTestSite(t, map[string]string{
+		"/index.html": linksPage("index", "page1.html", "page2.html"),
+		"/page1.html": linksPage("page1", "page2.html", "page3.html"),
+		"/page2.html": linksPage("page2", "page1.html"),
+		"/page3.html": linksPage("page3"),
+	})
+
+	r := ClickDepth(1)
+	err := NewCrawler(WithReports(r)).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(*Page) {})
+	assert.Nil(t, err)
+	findings, err := r.Finalize()
+	assert.Nil(t, err)
+	assert.Equal(t, []Finding{
+		{URL: srv.URL + "/page3.html", Validator: "click-depth", Message: "click depth 2 exceeds 1", Severity: SeverityWarning},
+		{URL: srv.URL + "/page3.html", Validator: "click-depth", Message: "only linked from " + srv.URL + "/page1.html", Severity: SeverityWarning},
+	}, findings)
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"sync"
+)
+
+// Collector gathers the results of a visit function concurrently called by a
+// Crawler, both in the order they were added and by URL. It is safe for
+// concurrent use, the zero value is empty
+type Collector[T any] struct {
+	mu    sync.Mutex
+	items []T
+	byURL map[string]T
+}
+
+// Append adds v to the results
+func (c *Collector[T]) Append(v T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = append(c.items, v)
+}
+
+// Put adds v to the results as the result of the page at u, replacing
+// a previous result of the page in the results by URL
+func (c *Collector[T]) Put(u string, v T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = append(c.items, v)
+	if c.byURL == nil {
+		c.byURL = make(map[string]T)
+	}
+	c.byURL[u] = v
+}
+
+// Visit builds a visit function, to be passed to CrawlPages, putting the
+// result of f on every visited page under the URL of the page
+func (c *Collector[T]) Visit(f func(p *Page) T) func(p *Page) {
+	return func(p *Page) {
+		c.Put(p.URL.String(), f(p))
+	}
+}
+
+// Items returns a copy of the results in the order they were added
+func (c *Collector[T]) Items() []T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]T(nil), c.items...)
+}
+
+// Get returns the result put for the page at u
+func (c *Collector[T]) Get(u string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.byURL[u]
+	return v, ok
+}
+
+// ByURL returns a copy of the results put by URL
+func (c *Collector[T]) ByURL() map[string]T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	byURL := make(map[string]T, len(c.byURL))
+	for u, v := range c.byURL {
+		byURL[u] = v
+	}
+	return byURL
+}
+
+// Len returns the number of results
+func (c *Collector[T]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
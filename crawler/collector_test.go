@@ -0,0 +1,49 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestCollector(t *testing.T) {
+	var c Collector[int]
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Append(i)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 100, c.Len())
+	assert.Len(t, c.Items(), 100)
+	assert.Empty(t, c.ByURL())
+
+	c.Put("https://my-web-site.com/a", 1)
+	c.Put("https://my-web-site.com/a", 2)
+	v, ok := c.Get("https://my-web-site.com/a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+	_, ok = c.Get("https://my-web-site.com/b")
+	assert.False(t, ok)
+	assert.Equal(t, map[string]int{"https://my-web-site.com/a": 2}, c.ByURL())
+}
+
+func TestCollector_Visit(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html": linksPage("index", "page1.html"),
+		"/page1.html": linksPage("page1"),
+	})
+
+	var titles Collector[string]
+	err := NewCrawler().CrawlPages(context.Background(), getURL(site.URL+"/index.html"), titles.Visit((*Page).Title))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		site.URL + "/index.html": "index",
+		site.URL + "/page1.html": "page1",
+	}, titles.ByURL())
+	assert.ElementsMatch(t, []string{"index", "page1"}, titles.Items())
+}
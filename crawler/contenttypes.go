@@ -0,0 +1,102 @@
+package crawler
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// unknownContentType is the content type recorded for an invalid media type
+const unknownContentType = "unknown"
+
+// sniffLen is the number of bytes of a body sniffed for its content type
+const sniffLen = 512
+
+// DefaultContentTypeAllowlist are the media types of the responses parsed by default
+var DefaultContentTypeAllowlist = []string{"text/html", "application/xhtml+xml"}
+
+// responseMediaType returns the lower-cased media type of r, without its parameters.
+// When r has no valid Content-Type header it is sniffed from the first bytes of the
+// body returned by head (see http.DetectContentType)
+func responseMediaType(r *http.Response, head func() []byte) string {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(head()))
+	}
+	return strings.ToLower(mediaType)
+}
+
+// ContentTypeStat are the counters of the responses of a content type
+type ContentTypeStat struct {
+	// ContentType is the media type of the responses, without its parameters (e.g. `image/png`)
+	ContentType string
+	// Pages is the number of responses of the content type
+	Pages int
+	// Bytes is the number of bytes of their bodies
+	Bytes int64
+	// URLs are the URLs of the responses when they are not HTML (see IsHTML)
+	URLs []string
+}
+
+// IsHTML reports whether the content type is HTML
+func (s ContentTypeStat) IsHTML() bool {
+	return s.ContentType == "text/html" || s.ContentType == "application/xhtml+xml"
+}
+
+// ContentTypes counts the responses of each content type of the crawls using it
+// (see WithContentTypes) and keeps the inventory of the URLs of the non-HTML ones, so
+// that the composition of a site can be understood. It is safe for concurrent use,
+// the zero value is empty
+type ContentTypes struct {
+	mu    sync.Mutex
+	types map[string]*ContentTypeStat
+}
+
+// record counts the response with the Content-Type header contentType and a
+// body of bytes bytes of the page at u
+func (s *ContentTypes) record(u *url.URL, contentType string, bytes int64) {
+	if s == nil {
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = unknownContentType
+	}
+	mediaType = strings.ToLower(mediaType)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.types == nil {
+		s.types = make(map[string]*ContentTypeStat)
+	}
+	stat, ok := s.types[mediaType]
+	if !ok {
+		stat = &ContentTypeStat{ContentType: mediaType}
+		s.types[mediaType] = stat
+	}
+	stat.Pages++
+	stat.Bytes += bytes
+	if !stat.IsHTML() {
+		stat.URLs = append(stat.URLs, u.String())
+	}
+}
+
+// Results returns the stats of each content type, sorted by content type,
+// with their URLs sorted
+func (s *ContentTypes) Results() []ContentTypeStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]ContentTypeStat, 0, len(s.types))
+	for _, stat := range s.types {
+		r := *stat
+		r.URLs = append([]string(nil), stat.URLs...)
+		sort.Strings(r.URLs)
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ContentType < results[j].ContentType
+	})
+	return results
+}
@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestContentTypes_record(t *testing.T) {
+	var types ContentTypes
+	types.record(getURL("https://my-web-site.com/index.html"), "text/html; charset=utf-8", 10)
+	types.record(getURL("https://my-web-site.com/b.png"), "Image/PNG", 100)
+	types.record(getURL("https://my-web-site.com/a.png"), "image/png", 200)
+	types.record(getURL("https://my-web-site.com/data"), "", 5)
+
+	assert.Equal(t, []ContentTypeStat{
+		{ContentType: "image/png", Pages: 2, Bytes: 300, URLs: []string{"https://my-web-site.com/a.png", "https://my-web-site.com/b.png"}},
+		{ContentType: "text/html", Pages: 1, Bytes: 10},
+		{ContentType: unknownContentType, Pages: 1, Bytes: 5, URLs: []string{"https://my-web-site.com/data"}},
+	}, types.Results())
+
+	var nilTypes *ContentTypes
+	nilTypes.record(getURL("https://my-web-site.com/"), "text/html", 1)
+}
+
+func Test_crawler_CrawlPages_WithContentTypes(t *testing.T) {
+	index := linksPage("index", "doc.pdf", "data.json")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, index)
+		case "/doc.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, "%PDF-1.4")
+		case "/data.json":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, "{}")
+		}
+	}))
+	defer srv.Close()
+
+	types := &ContentTypes{}
+	err := NewCrawler(WithContentTypes(types)).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(*Page) {})
+	assert.Nil(t, err)
+	assert.Equal(t, []ContentTypeStat{
+		{ContentType: "application/json", Pages: 1, Bytes: 2, URLs: []string{srv.URL + "/data.json"}},
+		{ContentType: "application/pdf", Pages: 1, Bytes: 8, URLs: []string{srv.URL + "/doc.pdf"}},
+		{ContentType: "text/html", Pages: 1, Bytes: int64(len(index))},
+	}, types.Results())
+}
+
+func Test_crawler_CrawlPages_WithContentTypeAllowlist(t *testing.T) {
+	// the links of the resources that are not parsed are not followed
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, linksPage("index", "doc.pdf", "data"))
+		case "/doc.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, linksPage("pdf", "from-pdf.html"))
+		case "/data":
+			// sniffed as application/octet-stream
+			fmt.Fprint(w, "\x00\x01"+linksPage("data", "from-data.html"))
+		default:
+			fmt.Fprint(w, linksPage(r.URL.Path))
+		}
+	}))
+	defer srv.Close()
+
+	tests := map[string]struct {
+		opts []Option
+		want []string
+	}{
+		"default": {
+			want: []string{"/index.html", "/doc.pdf", "/data"},
+		},
+		"default_parse_queue": {
+			opts: []Option{WithParseConcurrency(1, 1)},
+			want: []string{"/index.html", "/doc.pdf", "/data"},
+		},
+		"all_parsed": {
+			opts: []Option{WithContentTypeAllowlist()},
+			want: []string{"/index.html", "/doc.pdf", "/data", "/from-pdf.html", "/from-data.html"},
+		},
+		"custom": {
+			opts: []Option{WithContentTypeAllowlist("text/html", "Application/PDF")},
+			want: []string{"/index.html", "/doc.pdf", "/data", "/from-pdf.html"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var got []string
+			err := NewCrawler(tt.opts...).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, p.URL.Path)
+			})
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func Test_crawler_CrawlPages_WithHeadFirst(t *testing.T) {
+	var mu sync.Mutex
+	gets := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			mu.Lock()
+			gets[r.URL.Path]++
+			mu.Unlock()
+		}
+		switch r.URL.Path {
+		case "/index.html":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, linksPage("index", "archive.zip", "large.html", "no-head.html"))
+		case "/archive.zip":
+			w.Header().Set("Content-Type", "application/zip")
+			fmt.Fprint(w, "PK")
+		case "/large.html":
+			large := linksPage("large", strings.Repeat("a", 1000))
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Length", strconv.Itoa(len(large)))
+			fmt.Fprint(w, large)
+		case "/no-head.html":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			fmt.Fprint(w, linksPage("no-head"))
+		}
+	}))
+	defer srv.Close()
+
+	var pages []string
+	err := NewCrawler(WithHeadFirst(), WithMaxBodyBytes(500)).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+		mu.Lock()
+		defer mu.Unlock()
+		pages = append(pages, p.URL.Path)
+	})
+	assert.Nil(t, err)
+	// the filtered out pages are visited but not got
+	assert.ElementsMatch(t, []string{"/index.html", "/archive.zip", "/large.html", "/no-head.html"}, pages)
+	assert.Equal(t, map[string]int{"/index.html": 1, "/no-head.html": 1}, gets)
+}
@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+)
+
+// seededCookies are cookies to store in the cookie jar of a crawler
+// before crawling, as if they were set by the response of url
+type seededCookies struct {
+	url     *url.URL
+	cookies []*http.Cookie
+}
+
+// seedCookies stores the seeded cookies of the crawler in its cookie jar or,
+// without one, in the jar of the client set with WithHTTPClient. A crawler
+// without any jar is given an in-memory one
+func (c *crawler) seedCookies() {
+	if len(c.cookies) == 0 {
+		return
+	}
+	jar := c.jar
+	if jar == nil && c.baseClient != nil {
+		jar = c.baseClient.Jar
+	}
+	if jar == nil {
+		jar = newCookieJar()
+		c.jar = jar
+	}
+	for _, seeded := range c.cookies {
+		jar.SetCookies(seeded.url, seeded.cookies)
+	}
+}
+
+// newCookieJar returns an in-memory cookie jar
+func newCookieJar() http.CookieJar {
+	// cookiejar.New only fails on invalid options
+	jar, _ := cookiejar.New(nil)
+	return jar
+}
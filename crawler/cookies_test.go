@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func Test_crawler_CrawlPages_Cookies(t *testing.T) {
+	// the index sets the session cookie, the other pages are denied without it
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.html" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3ss10n"})
+			fmt.Fprint(w, linksPage("index", "page1.html"))
+			return
+		}
+		if c, err := r.Cookie("session"); err != nil || c.Value != "s3ss10n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, linksPage("page1"))
+	}))
+	defer srv.Close()
+	page1 := getURL(srv.URL + "/page1.html")
+	clientJar, _ := cookiejar.New(nil)
+
+	tests := map[string]struct {
+		opts []Option
+		base string
+		want int
+	}{
+		"no_jar": {
+			base: "/index.html",
+			want: http.StatusUnauthorized,
+		},
+		"jar": {
+			opts: []Option{WithCookieJar(nil)},
+			base: "/index.html",
+			want: http.StatusOK,
+		},
+		"seeded": {
+			opts: []Option{WithCookies(page1, &http.Cookie{Name: "session", Value: "s3ss10n"})},
+			base: "/page1.html",
+			want: http.StatusOK,
+		},
+		"seeded_in_client_jar": {
+			opts: []Option{WithHTTPClient(&http.Client{Jar: clientJar}), WithCookies(page1, &http.Cookie{Name: "session", Value: "s3ss10n"})},
+			base: "/page1.html",
+			want: http.StatusOK,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var status int
+			err := NewCrawler(tt.opts...).CrawlPages(context.Background(), getURL(srv.URL+tt.base), func(p *Page) {
+				mu.Lock()
+				defer mu.Unlock()
+				if p.URL.Path == "/page1.html" {
+					status = p.StatusCode
+				}
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, status)
+		})
+	}
+	assert.Len(t, clientJar.Cookies(page1), 1)
+}
@@ -0,0 +1,90 @@
+package crawler
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CoverageTarget declares the minimum number of pages expected
+// to be crawled under a section (path prefix) of a site
+type CoverageTarget struct {
+	// Prefix is the path prefix of the section (e.g. `/docs/`)
+	Prefix string
+	// Min is the minimum number of pages expected in the section
+	Min int
+}
+
+// CoverageResult is the number of pages crawled under a section
+// compared with its target
+type CoverageResult struct {
+	CoverageTarget
+	// Pages is the number of pages crawled under the section
+	Pages int
+}
+
+// Met reports whether the section reached its target
+func (r CoverageResult) Met() bool {
+	return r.Pages >= r.Min
+}
+
+// Coverage counts the crawled pages of each section declared by the targets. Its
+// Visit method can be used as the visit function (or part of it) of a Crawler.
+// It is safe for concurrent use
+type Coverage struct {
+	targets []CoverageTarget
+	mut     sync.Mutex
+	pages   []int
+}
+
+// NewCoverage creates a Coverage for targets
+func NewCoverage(targets []CoverageTarget) *Coverage {
+	return &Coverage{targets: targets, pages: make([]int, len(targets))}
+}
+
+// Visit counts the page in all the sections it belongs to
+func (c *Coverage) Visit(u *url.URL, _ *html.Node) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	for i, t := range c.targets {
+		if strings.HasPrefix(u.Path, t.Prefix) {
+			c.pages[i]++
+		}
+	}
+}
+
+// Results returns the coverage of each section, in the order of the targets
+func (c *Coverage) Results() []CoverageResult {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	results := make([]CoverageResult, len(c.targets))
+	for i, t := range c.targets {
+		results[i] = CoverageResult{CoverageTarget: t, Pages: c.pages[i]}
+	}
+	return results
+}
+
+// ParseCoverageTargets parses a comma separated list of `prefix:min`
+// coverage targets (e.g. `/docs/:100,/blog/:10`)
+func ParseCoverageTargets(spec string) ([]CoverageTarget, error) {
+	var targets []CoverageTarget
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid coverage target %q, expected prefix:min", item)
+		}
+		min, err := strconv.Atoi(item[i+1:])
+		if err != nil || min < 0 {
+			return nil, fmt.Errorf("invalid coverage target %q, min must be a positive integer", item)
+		}
+		targets = append(targets, CoverageTarget{Prefix: item[:i], Min: min})
+	}
+	return targets, nil
+}
@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_ParseCoverageTargets(t *testing.T) {
+	tests := map[string]struct {
+		spec    string
+		wantErr bool
+		want    []CoverageTarget
+	}{
+		"empty": {
+			spec: "",
+		},
+		"several_targets": {
+			spec: "/docs/:100, /blog/:10",
+			want: []CoverageTarget{{Prefix: "/docs/", Min: 100}, {Prefix: "/blog/", Min: 10}},
+		},
+		"missing_min": {
+			spec:    "/docs/",
+			wantErr: true,
+		},
+		"invalid_min": {
+			spec:    "/docs/:many",
+			wantErr: true,
+		},
+		"negative_min": {
+			spec:    "/docs/:-1",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseCoverageTargets(tt.spec)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Test_Coverage_Integration depends on the startup of the attached `test_data/Dockerfile` container
+// the site has one page under /child/ and none under /orphan/ as orphan pages are not reachable
+func Test_Coverage_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	cov := NewCoverage([]CoverageTarget{
+		{Prefix: "/", Min: 5},
+		{Prefix: "/child/", Min: 1},
+		{Prefix: "/orphan/", Min: 1},
+	})
+	err := NewCrawler().Crawl(context.Background(), getURL(getBaseURLIndex()), cov.Visit)
+	assert.Nil(t, err)
+
+	results := cov.Results()
+	assert.Equal(t, []CoverageResult{
+		{CoverageTarget: CoverageTarget{Prefix: "/", Min: 5}, Pages: 5},
+		{CoverageTarget: CoverageTarget{Prefix: "/child/", Min: 1}, Pages: 1},
+		{CoverageTarget: CoverageTarget{Prefix: "/orphan/", Min: 1}, Pages: 0},
+	}, results)
+	assert.True(t, results[0].Met())
+	assert.True(t, results[1].Met())
+	assert.False(t, results[2].Met())
+}
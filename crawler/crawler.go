@@ -1,16 +1,22 @@
 package crawler
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/html"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
-	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Crawler is used to Crawl a web-site
@@ -19,102 +25,691 @@ type Crawler interface {
 	// share the same domain and will not follow links to external sites
 	// the visit parameter is a function that performs some logic based on a page and it's url
 	Crawl(ctx context.Context, base *url.URL, visit func(u *url.URL, page *html.Node)) error
+	// CrawlPages behaves like Crawl but the visit function receives the whole crawled Page,
+	// including the response status code and headers
+	CrawlPages(ctx context.Context, base *url.URL, visit func(p *Page)) error
+	// Fetch will get each of the input URLs and apply the visit function on it.
+	// Differently from Crawl, the links found in the pages are not followed
+	Fetch(ctx context.Context, urls []*url.URL, visit func(u *url.URL, page *html.Node)) error
+	// FetchStream behaves like Fetch but the URLs are received from a channel as they are
+	// produced. It returns once the channel is closed and all the received pages were visited
+	FetchStream(ctx context.Context, urls <-chan *url.URL, visit func(u *url.URL, page *html.Node)) error
+	// CrawlSites crawls concurrently several independent sites. Each site is scoped to the
+	// domain of its base URL and has its own set of visited pages, the returned map holds
+	// the stats of each site keyed by its base URL. The concurrency limit of the crawler
+	// (see WithMaxConcurrency) is shared by all the sites. The stats are also returned
+	// along with ErrMaxDuration
+	CrawlSites(ctx context.Context, bases []*url.URL, visit func(u *url.URL, page *html.Node)) (map[string]Stats, error)
+	// Pause stops fetching new pages in all the running crawls of the crawler, e.g. to
+	// relieve a struggling server. The in-flight requests complete and the state of
+	// the crawls is kept until Resume is called. A paused crawl can still be cancelled
+	Pause()
+	// Resume resumes the crawls paused by Pause
+	Resume()
+	// SetConcurrency changes the limit of pages concurrently scraped by the running and
+	// future crawls of the crawler (see WithMaxConcurrency). When the limit decreases the
+	// in-flight requests complete and new pages are only fetched once fewer than n are in
+	// flight. A value lower than 1 means no limit
+	SetConcurrency(n int)
 }
 
+// ErrMaxDuration is returned when a crawl is stopped because it reached
+// the maximum duration of the crawler (see WithMaxDuration). Differently,
+// a crawl stopped by the cancellation of its context returns no error
+var ErrMaxDuration = errors.New("crawl maximum duration exceeded")
+
+// ErrDeadlineSkipped is the error a page is recorded with (see Store.Record)
+// when it is not requested because it cannot be fetched before the deadline
+// of the crawl
+var ErrDeadlineSkipped = errors.New("page cannot be fetched before the deadline")
+
+// Version is the version of the crawler, advertised in DefaultUserAgent
+const Version = "0.1.0"
+
+// DefaultUserAgent is the User-Agent of the requests of the crawlers created without
+// WithUserAgent, it identifies the crawler to the owners of the crawled sites
+const DefaultUserAgent = "web-crawler/" + Version + " (+https://github.com/rbroggi/web-crawler)"
+
 type crawler struct {
+	// canonical is the canonicalization pipeline applied
+	// to the URLs before they are visited
+	canonical []Transform
+	// trackingParams are the patterns of the query parameters
+	// removed from the URLs before they are visited
+	trackingParams []string
+	// store is the Store shared by all the crawls,
+	// when nil each crawl uses its own memory store
+	store Store
+	// sem limits the number of pages concurrently scraped,
+	// its limit can be changed with SetConcurrency
+	sem *semaphore
+	// parseSem, when set, separates the parsing of the pages from their
+	// fetching: it limits the number of pages concurrently parsed and
+	// visited while sem only limits the fetches. parseQueue bounds the
+	// fetched pages waiting for a parse slot
+	parseSem   *semaphore
+	parseQueue *semaphore
+	// validators are run on every scraped page and
+	// their findings are reported to onFinding
+	validators []Validator
+	onFinding  func(f Finding)
+	// reports consume every scraped page
+	reports []Report
+	// honorAIOptOut skips the visit of the pages
+	// declaring AI opt-out directives
+	honorAIOptOut bool
+	// transport are the customizations applied, in order, to a
+	// clone of http.DefaultTransport (e.g. dialer, TLS configuration)
+	transport []func(t *http.Transport)
+	// maxDepth, when limitDepth is set, is the maximum number of
+	// hops from the base URL of the crawled pages
+	maxDepth   int
+	limitDepth bool
+	// maxDuration, when positive, is the duration budget of each crawl
+	maxDuration time.Duration
+	// retry is the policy applied to the requests failing with a network error
+	retry RetryPolicy
+	// redirect, when set, is the policy applied to the redirects
+	redirect *RedirectPolicy
+	// hostStats, when set, counts the page requests of each host
+	hostStats *HostStats
+	// contentTypes, when set, counts the responses of each content type
+	contentTypes *ContentTypes
+	// weights, when set, tracks the largest pages and heaviest sections
+	weights *Weights
+	// allowedTypes are the media types of the responses parsed,
+	// all the responses are parsed when empty
+	allowedTypes []string
+	// latency estimates the duration of the page requests
+	latency latencyEstimate
+	// prefetch is the number of links of a page, in document order,
+	// dispatched before its visit function is applied
+	prefetch int
+	// headFirst makes the crawler issue a HEAD request before getting a page
+	headFirst bool
+	// maxBodyBytes, when positive, is the maximum number of bytes
+	// read out of each response body
+	maxBodyBytes int64
+	// rawBody keeps the raw response body of the parsed pages
+	rawBody bool
+	// linksOnly parses the anchors and meta elements of the pages only
+	linksOnly bool
+	// requestTimeout, when positive, bounds each attempt of a page request
+	requestTimeout time.Duration
+	// gate holds back the fetches while the crawler is paused
+	gate pauseGate
+	// pauses are the hosts that asked, through a Retry-After
+	// header, to delay the requests
+	pauses hostPauses
+	// proxies, when set, are the proxies the requests are issued through
+	proxies *ProxyPool
+	// http3, when set, is the HTTP/3 transport of the origins advertising it
+	http3 http.RoundTripper
+	// signer, when set, signs every request
+	signer RequestSigner
+	// middlewares wrap the transport of the crawler, before the signer
+	middlewares []Middleware
+	// limiter, when set, limits the rate of the requests
+	// issued by all the go-routines of the crawler
+	limiter *rateLimiter
+	// throttle, when set, adapts the rate of limiter
+	// to the health of the crawled servers
+	throttle *throttle
+	// exclusions, when set, are the patterns of the pages skipped
+	exclusions *Exclusions
+	// robots makes the crawler skip the pages disallowed by the robots.txt
+	// of their host for the group matching robotsUserAgent
+	robots          bool
+	robotsUserAgent string
+	// robotsRetryDelay is the delay between two attempts of a robots.txt retrieval
+	robotsRetryDelay time.Duration
+	// maxCrawlDelay, when positive, caps the Crawl-delay of the robots.txt files
+	maxCrawlDelay time.Duration
+	// userAgent is the User-Agent header of the requests, the
+	// Go default one is sent when empty
+	userAgent string
+	// userAgents, when set, are the User-Agents rotated
+	// across the requests, instead of userAgent
+	userAgents *userAgents
+	// jar, when set, stores the cookies of the responses and sends
+	// them with the following requests. cookies are stored in the
+	// jar before crawling
+	jar     http.CookieJar
+	cookies []seededCookies
+	// session, when set, renews the session of the
+	// crawler once its requests are denied
+	session *sessionRenewal
+	// baseClient is the client set with WithHTTPClient the
+	// HTTP client of the crawler is built upon
+	baseClient *http.Client
+	// client is the HTTP client used to fetch the pages
+	client *http.Client
 }
 
 func (c *crawler) Crawl(ctx context.Context, base *url.URL, visit func(u *url.URL, page *html.Node)) error {
+	return c.CrawlPages(ctx, base, nodeVisit(visit))
+}
+
+func (c *crawler) CrawlPages(ctx context.Context, base *url.URL, visit func(p *Page)) error {
 	if base == nil {
 		return errors.New("nil base URL cannot be crawled")
 	}
 
+	store := c.store
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	ctx, done := c.budget(ctx)
+	c.crawl(ctx, base, store, visit)
+
+	return done()
+}
+
+func (c *crawler) CrawlSites(ctx context.Context, bases []*url.URL, visit func(u *url.URL, page *html.Node)) (map[string]Stats, error) {
+	for _, base := range bases {
+		if base == nil {
+			return nil, errors.New("nil base URL cannot be crawled")
+		}
+	}
+
+	ctx, done := c.budget(ctx)
+	// each site has its own store so that it has its own visited set and stats
+	stores := make(map[string]Store, len(bases))
+	var wg sync.WaitGroup
+	for _, base := range bases {
+		if _, ok := stores[base.String()]; ok {
+			continue
+		}
+		store := NewMemoryStore()
+		stores[base.String()] = store
+		wg.Add(1)
+		go func(base *url.URL) {
+			defer wg.Done()
+			c.crawl(ctx, base, store, nodeVisit(visit))
+		}(base)
+	}
+	wg.Wait()
+
+	stats := make(map[string]Stats, len(stores))
+	for base, store := range stores {
+		stats[base] = store.Stats()
+	}
+	return stats, done()
+}
+
+func (c *crawler) Pause() {
+	c.gate.pause()
+}
+
+func (c *crawler) Resume() {
+	c.gate.resume()
+}
+
+func (c *crawler) SetConcurrency(n int) {
+	c.sem.setLimit(n)
+}
+
+// crawl recursively crawls base tracking the visited pages in store
+// and returns once all the spawned go-routines are done
+func (c *crawler) crawl(ctx context.Context, base *url.URL, store Store, visit func(p *Page)) {
 	// used to track end of all spawned go-routines
 	var wg sync.WaitGroup
-	var rw sync.RWMutex
-	visited := make(map[string]struct{})
 
-	recursiveVisit(ctx, &rw, &wg, visited, base, visit)
+	// a base already visited by a previous crawl sharing the store is not visited again
+	base = c.canonicalize(base)
+	skipped := store.Stats().Skipped
+	if store.Add(base.String()) {
+		c.recursiveVisit(ctx, store, c.newRobotsCache(ctx), &wg, base, 0, visit)
+	}
 
 	// waits all go-routines to finish
 	wg.Wait()
+	if n := store.Stats().Skipped - skipped; n > 0 {
+		log.Infof("%d pages of %s not fetched, they could not be fetched before the deadline", n, base)
+	}
+}
 
-	return nil
+func (c *crawler) Fetch(ctx context.Context, urls []*url.URL, visit func(u *url.URL, page *html.Node)) error {
+	ctx, done := c.budget(ctx)
+	stream := make(chan *url.URL)
+	go func() {
+		defer close(stream)
+		for _, u := range urls {
+			select {
+			case <-ctx.Done():
+				return
+			case stream <- u:
+			}
+		}
+	}()
+	c.fetchStream(ctx, stream, visit)
+	return done()
+}
+
+func (c *crawler) FetchStream(ctx context.Context, urls <-chan *url.URL, visit func(u *url.URL, page *html.Node)) error {
+	ctx, done := c.budget(ctx)
+	c.fetchStream(ctx, urls, visit)
+	return done()
+}
+
+// fetchStream fetches the URLs received from urls until the channel
+// is closed or ctx is cancelled, and returns once all were visited
+func (c *crawler) fetchStream(ctx context.Context, urls <-chan *url.URL, visit func(u *url.URL, page *html.Node)) {
+	// used to track end of all spawned go-routines
+	var wg sync.WaitGroup
+	var skipped int64
+	// waits all go-routines to finish
+	defer func() {
+		wg.Wait()
+		if n := atomic.LoadInt64(&skipped); n > 0 {
+			log.Infof("%d pages not fetched, they could not be fetched before the deadline", n)
+		}
+	}()
+	robots := c.newRobotsCache(ctx)
+
+	for {
+		select {
+		// if context cancelled no more pages are fetched
+		case <-ctx.Done():
+			return
+		case u, ok := <-urls:
+			// stream closed, all the URLs were dispatched
+			if !ok {
+				return
+			}
+			if u == nil {
+				log.Errorf("nil URL cannot be fetched")
+				continue
+			}
+			wg.Add(1)
+			go func(u *url.URL) {
+				defer wg.Done()
+				if !c.admit(ctx, robots, u) {
+					return
+				}
+				c.process(ctx, u, 0, func(page *Page, err error) {
+					// the pages skipped by the deadline are summed up once all were fetched
+					if errors.Is(err, ErrDeadlineSkipped) {
+						atomic.AddInt64(&skipped, 1)
+						return
+					}
+					// if error while getting page simply return
+					if err != nil {
+						log.Errorf("failed to get page %s", u)
+						return
+					}
+					// apply the visit function
+					if c.visitable(page) {
+						visit(page.URL, page.Node)
+					}
+				})
+			}(u)
+		}
+	}
 }
 
-func recursiveVisit(ctx context.Context, rw *sync.RWMutex, wg *sync.WaitGroup, visited map[string]struct{}, u *url.URL, visit func(u *url.URL, page *html.Node)) {
+// recursiveVisit spawns a go-routine visiting u, which must have already been
+// added to the store, and recursively the eligible links found in the page.
+// depth is the number of hops from the base URL to u
+func (c *crawler) recursiveVisit(ctx context.Context, store Store, robots *robotsCache, wg *sync.WaitGroup, u *url.URL, depth int, visit func(p *Page)) {
 	// collect token for spawning new go-routine
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		// add u to visited pages
-		rw.Lock()
-		visited[u.String()] = struct{}{}
-		rw.Unlock()
-
-		page, err := getPage(ctx, u.String())
-		// if error while getting page simply return
-		if err != nil {
-			log.Errorf("failed to get page %s", u)
+		// excluded or disallowed pages are neither fetched nor recorded in the
+		// stats, the Crawl-delay is waited before taking a concurrency slot
+		if !c.admit(ctx, robots, u) {
 			return
 		}
-
-		// apply the visit function
-		visit(u, page)
-
-		// retrieve all links in the page
-		links := GetPageLinks(page)
-		for link := range links {
-			absLink, err := GetLinkAbsoluteUrl(u, link)
+		// the page is fetched and parsed holding the concurrency slots of the
+		// crawler, the last one is released once its links are dispatched
+		c.process(ctx, u, depth, func(page *Page, err error) {
+			store.Record(u.String(), err)
+			// the pages skipped by the deadline are summed up at the end of the crawl
+			if errors.Is(err, ErrDeadlineSkipped) {
+				return
+			}
+			// if error while getting page simply return
 			if err != nil {
-				log.Errorf("failed to get absolute link on page %s with relative link %s", u, link)
-				continue
+				log.Errorf("failed to get page %s", u)
+				return
 			}
-			rw.RLock()
-			_, ok := visited[absLink.String()]
-			rw.RUnlock()
-			// if not visited and same url domain, visit it
-			if !ok && isSameDomain(u, absLink) {
-				// if context cancelled algo recursion stops
-				select {
-				case <-ctx.Done():
+
+			// a page redirected to an already visited page is not visited again
+			if page.URL != u {
+				page.URL = c.canonicalize(page.URL)
+				if page.URL.String() != u.String() && !store.Add(page.URL.String()) {
 					return
-				default:
-					recursiveVisit(ctx, rw, wg, visited, absLink, visit)
 				}
 			}
-		}
+
+			// the links of a page at the maximum depth, or redirected
+			// to another domain, are not followed
+			var links []*url.URL
+			if (!c.limitDepth || depth < c.maxDepth) && isSameDomain(u, page.URL) {
+				links = c.pageLinks(page)
+			}
+			// the prefetched links are dispatched before the visit so
+			// that they are fetched while the visit function runs
+			prefetched := c.prefetch
+			if prefetched > len(links) {
+				prefetched = len(links)
+			}
+			c.dispatch(ctx, store, robots, wg, links[:prefetched], depth+1, visit)
+
+			// apply the visit function
+			if c.visitable(page) {
+				visit(page)
+			}
+
+			c.dispatch(ctx, store, robots, wg, links[prefetched:], depth+1, visit)
+		})
 	}()
 }
 
-// NewCrawler creates a structure that implements the Crawler interface
-// the maxConcurrency param determines how many go-routines can concurrently
-// crawl the site
-func NewCrawler() Crawler {
-	return &crawler{}
+// pageLinks returns the absolute links of page, in document order, that
+// belong to its domain
+func (c *crawler) pageLinks(page *Page) []*url.URL {
+	var links []*url.URL
+	resolver := NewLinkResolver(page.URL)
+	for _, link := range getOrderedPageLinks(page.Node) {
+		absLink, err := resolver.Resolve(link)
+		if err != nil {
+			log.Errorf("failed to get absolute link on page %s with relative link %s", page.URL, link)
+			continue
+		}
+		absLink = c.canonicalize(absLink)
+		if isSameDomain(page.URL, absLink) {
+			links = append(links, absLink)
+		}
+	}
+	return links
 }
 
-// GetLinkAbsoluteUrl parses a link transforming it in an absolute URI
-// if the link is itself an absolute path it parses it regardless of the
-// input parent URL, otherwise it parses it relative to the parent URL
-func GetLinkAbsoluteUrl(parent *url.URL, link string) (*url.URL, error) {
-	r, err := url.Parse(link)
+// dispatch recursively visits the links not yet visited, found depth hops from
+// the base URL. A link is added to the visited pages before spawning its
+// go-routine so that concurrent go-routines do not visit it twice
+func (c *crawler) dispatch(ctx context.Context, store Store, robots *robotsCache, wg *sync.WaitGroup, links []*url.URL, depth int, visit func(p *Page)) {
+	for _, link := range links {
+		if store.Contains(link.String()) {
+			continue
+		}
+		// if context cancelled algo recursion stops
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		if store.Add(link.String()) {
+			c.recursiveVisit(ctx, store, robots, wg, link, depth, visit)
+		}
+	}
+}
+
+// visitable reports whether the visit function should be applied to page
+func (c *crawler) visitable(page *Page) bool {
+	return !c.honorAIOptOut || len(AIDirectives(page)) == 0
+}
+
+// nodeVisit adapts a visit function receiving the url and the
+// html content of a page to a visit function receiving a Page
+func nodeVisit(visit func(u *url.URL, page *html.Node)) func(p *Page) {
+	return func(p *Page) {
+		visit(p.URL, p.Node)
+	}
+}
+
+// admit reports whether u can be fetched: it is not excluded (see
+// WithExclusions) and robots admits it
+func (c *crawler) admit(ctx context.Context, robots *robotsCache, u *url.URL) bool {
+	if c.exclusions.Excluded(u) {
+		log.Infof("skipping excluded page %s", u)
+		return false
+	}
+	return robots.admit(ctx, u)
+}
+
+// budget wraps ctx with the maximum duration of the crawler. The returned
+// done function, called once the crawl ended, returns ErrMaxDuration if the
+// crawl was stopped by the budget rather than by the cancellation of ctx
+func (c *crawler) budget(ctx context.Context) (context.Context, func() error) {
+	if c.maxDuration <= 0 {
+		return ctx, func() error { return nil }
+	}
+	budgetCtx, cancel := context.WithTimeout(ctx, c.maxDuration)
+	budgetCtx, cut := withDeadlineCut(budgetCtx)
+	return budgetCtx, func() error {
+		defer cancel()
+		if ctx.Err() != nil {
+			return nil
+		}
+		// the requests cut short before the deadline of the budget (see
+		// attemptTimeout) also stop the crawl, unless ctx ends first
+		deadline, _ := budgetCtx.Deadline()
+		parent, ok := ctx.Deadline()
+		cutByBudget := cut() && (!ok || parent.After(deadline))
+		if cutByBudget || errors.Is(budgetCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w (%s)", ErrMaxDuration, c.maxDuration)
+		}
+		return nil
+	}
+}
+
+// process scrapes the page at u and passes it, or the error getting it, to
+// handle. The page is fetched holding a slot of sem and, when the parsing is
+// separated (see WithParseConcurrency), handed off through parseQueue to be
+// parsed and handled holding a slot of parseSem. A page answered with a
+// Retry-After header is requeued, without holding any slot, and the requests
+// to its host are paused for the indicated delay, the page is dropped when
+// the delay exceeds maxRetryAfter. It returns without calling handle if ctx
+// is cancelled while waiting for a slot
+func (c *crawler) process(ctx context.Context, u *url.URL, depth int, handle func(page *Page, err error)) {
+	for requeues := 0; ; requeues++ {
+		if !c.pauses.wait(ctx, u.Host) {
+			return
+		}
+		retryAfter := c.processOnce(ctx, u, depth, requeues < maxRequeues, handle)
+		if retryAfter == nil {
+			return
+		}
+		if retryAfter.delay > maxRetryAfter {
+			log.Warnf("page %s answered with %v, dropped as the delay exceeds %s", u, retryAfter, maxRetryAfter)
+			handle(nil, retryAfter)
+			return
+		}
+		log.Warnf("page %s answered with %v, requeued", u, retryAfter)
+		c.pauses.pause(u.Host, retryAfter.delay)
+	}
+}
+
+// processOnce performs a single attempt of process. When requeue is set and
+// the page is answered with a Retry-After header, handle is not called and the
+// retryAfterError is returned
+func (c *crawler) processOnce(ctx context.Context, u *url.URL, depth int, requeue bool, handle func(page *Page, err error)) *retryAfterError {
+	if !c.sem.acquire(ctx) {
+		return nil
+	}
+	// a paused crawler holds the fetch slots, as nothing can be fetched
+	if !c.gate.wait(ctx) {
+		c.sem.release()
+		return nil
+	}
+	if !c.canFinish(ctx) {
+		log.Debugf("page %s not fetched, it cannot be fetched before the deadline", u)
+		c.sem.release()
+		// the crawl is truncated by the deadline, like when a request is cut short
+		markDeadlineCut(ctx)
+		handle(nil, ErrDeadlineSkipped)
+		return nil
+	}
+	if c.parseSem == nil {
+		defer c.sem.release()
+		page, err := c.scrape(ctx, u, depth, requeue)
+		if retryAfter, ok := err.(*retryAfterError); ok {
+			return retryAfter
+		}
+		handle(page, err)
+		return nil
+	}
+
+	page, body, err := c.fetchPage(ctx, u, requeue)
 	if err != nil {
-		return nil, err
+		c.sem.release()
+		if retryAfter, ok := err.(*retryAfterError); ok {
+			return retryAfter
+		}
+		handle(nil, err)
+		return nil
+	}
+	// the fetch slot is only released once the page entered the parse
+	// queue, so that the fetches block when the parsing lags behind
+	queued := c.parseQueue.acquire(ctx)
+	c.sem.release()
+	if !queued {
+		return nil
+	}
+	parsing := c.parseSem.acquire(ctx)
+	c.parseQueue.release()
+	if !parsing {
+		return nil
 	}
+	defer c.parseSem.release()
+	// the page of a content type that is not parsed already has its Node
+	if page.Node == nil {
+		if page.Node, err = c.parse(bytes.NewReader(body)); err != nil {
+			handle(nil, fmt.Errorf("error while html parsing response - %v", err))
+			return nil
+		}
+	}
+	page.Depth = depth
+	c.validate(page)
+	handle(page, nil)
+	return nil
+}
 
-	// if link is already an absolute link
-	if r.IsAbs() {
-		return r, nil
+// NewCrawler creates a structure that implements the Crawler interface
+// the opts params configure the crawler behaviour (e.g. WithCanonicalization)
+func NewCrawler(opts ...Option) Crawler {
+	return newCrawler(opts...)
+}
+
+func newCrawler(opts ...Option) *crawler {
+	c := &crawler{sem: newSemaphore(0), allowedTypes: DefaultContentTypeAllowlist, trackingParams: DefaultTrackingParams, userAgent: DefaultUserAgent, robotsRetryDelay: robotsRetryDelay}
+	for _, opt := range opts {
+		opt(c)
 	}
+	c.seedCookies()
+	c.client = c.newClient()
+	return c
+}
 
-	// if link is relative to server root
-	if strings.HasPrefix(link, "/") {
-		return parent.Parse(link)
+// NewHTTPClient creates the HTTP client a crawler created with the same opts issues
+// its requests with (transport, proxies, credentials, cookies, rate limit...), the
+// requests without User-Agent being sent with the one of the crawler. It is meant
+// for the requests issued on behalf of a crawl, e.g. by a Report or a login
+func NewHTTPClient(opts ...Option) *http.Client {
+	return newCrawler(opts...).userAgentClient()
+}
+
+// userAgentClient returns a copy of the HTTP client of the crawler setting the
+// User-Agent of the crawler on the requests without one
+func (c *crawler) userAgentClient() *http.Client {
+	client := *c.httpClient()
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
 	}
+	client.Transport = OnRequest(func(req *http.Request) error {
+		if req.Header.Get("User-Agent") == "" {
+			if ua := c.userAgentFor(req.URL.Host); ua != "" {
+				req.Header.Set("User-Agent", ua)
+			}
+		}
+		return nil
+	})(transport)
+	return &client
+}
 
-	// if link is relative to parent page uri
-	return parent.Parse(path.Join(path.Dir(parent.Path), link))
+// httpClient returns the HTTP client of the crawler, http.DefaultClient
+// for a crawler not created by NewCrawler
+func (c *crawler) httpClient() *http.Client {
+	if c.client == nil {
+		return http.DefaultClient
+	}
+	return c.client
+}
+
+// newClient builds the HTTP client of the crawler out of its options, on top
+// of the client set with WithHTTPClient. When no option affects the client,
+// that client, or http.DefaultClient, is used as is
+func (c *crawler) newClient() *http.Client {
+	base := c.baseClient
+	if base == nil {
+		base = http.DefaultClient
+	}
+	if len(c.transport) == 0 && c.proxies == nil && c.http3 == nil && c.signer == nil && len(c.middlewares) == 0 && c.limiter == nil && c.redirect == nil && c.jar == nil {
+		return base
+	}
+	// the client of the caller is not modified
+	client := *base
+	if c.redirect != nil {
+		client.CheckRedirect = c.redirect.checkRedirect
+	}
+	if c.jar != nil {
+		client.Jar = c.jar
+	}
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if len(c.transport) > 0 {
+		if t, ok := transport.(*http.Transport); ok {
+			t = t.Clone()
+			for _, customize := range c.transport {
+				customize(t)
+			}
+			transport = t
+		} else {
+			log.Errorf("the transport customizations are ignored as the transport of the client is not a *http.Transport")
+		}
+	}
+	if c.proxies != nil {
+		if t, ok := transport.(*http.Transport); ok {
+			transport = &proxyTransport{base: t, pool: c.proxies}
+		} else {
+			log.Errorf("the proxy pool is ignored as the transport of the client is not a *http.Transport")
+		}
+	}
+	if c.http3 != nil {
+		if c.proxies == nil {
+			transport = newHTTP3Transport(transport, c.http3)
+		} else {
+			log.Errorf("HTTP/3 is disabled as the requests are issued through a proxy pool")
+		}
+	}
+	if c.signer != nil {
+		transport = &signingTransport{next: transport, signer: c.signer}
+	}
+	// the first middleware is the outermost one, the requests go
+	// through the middlewares in the order they were set
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		transport = c.middlewares[i](transport)
+	}
+	if c.throttle != nil {
+		transport = &throttledTransport{next: transport, throttle: c.throttle}
+	}
+	if c.limiter != nil {
+		transport = &rateLimitedTransport{next: transport, limiter: c.limiter}
+	}
+	client.Transport = transport
+	return &client
+}
+
+// GetLinkAbsoluteUrl parses a link transforming it in an absolute URI
+// if the link is itself an absolute path it parses it regardless of the
+// input parent URL, otherwise it parses it relative to the parent URL
+func GetLinkAbsoluteUrl(parent *url.URL, link string) (*url.URL, error) {
+	return NewLinkResolver(parent).Resolve(link)
 }
 
 // isSameDomain checks whether p, u urls belong to the same domain
@@ -140,6 +735,33 @@ func GetPageLinks(node *html.Node) map[string]struct{} {
 	return getPageLinksRecursive(node, m)
 }
 
+// getOrderedPageLinks retrieves all links found in a page in
+// document order, a link linked several times is only retrieved once
+func getOrderedPageLinks(node *html.Node) []string {
+	var links []string
+	// nothing to retrieve for nil node
+	if node == nil {
+		return links
+	}
+	seen := make(map[string]struct{})
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "a" {
+			for _, a := range node.Attr {
+				if _, ok := seen[a.Val]; a.Key == "href" && !ok {
+					seen[a.Val] = struct{}{}
+					links = append(links, a.Val)
+				}
+			}
+		}
+		for n := node.FirstChild; n != nil; n = n.NextSibling {
+			walk(n)
+		}
+	}
+	walk(node)
+	return links
+}
+
 // getPageLinksRecursive retrieve all links found in a page
 // a set (map[string]struct{}) is used to add semantic meening
 // to the method - no duplicated links are going to be retrieved
@@ -163,21 +785,323 @@ func getPageLinksRecursive(node *html.Node, links map[string]struct{}) map[strin
 	return links
 }
 
+// parse parses the html document read out of r, only its anchors and meta
+// elements with WithLinksOnly
+func (c *crawler) parse(r io.Reader) (*html.Node, error) {
+	if c.linksOnly {
+		return parseLinks(r)
+	}
+	return html.Parse(r)
+}
+
+// parseLinks tokenizes the html document read out of r without building its
+// tree: the returned document only holds its <a> and <meta> elements, in
+// document order, along with their attributes
+func parseLinks(r io.Reader) (*html.Node, error) {
+	doc := &html.Node{Type: html.DocumentNode}
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return nil, err
+			}
+			return doc, nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if t := z.Token(); t.Data == "a" || t.Data == "meta" {
+				doc.AppendChild(&html.Node{Type: html.ElementNode, DataAtom: t.DataAtom, Data: t.Data, Attr: t.Attr})
+			}
+		}
+	}
+}
+
 // getPage performs an HTTP GET request using the input url and tries
-// to parse the result into an html.Node data structure
-func getPage(ctx context.Context, url string) (*html.Node, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// to parse the result into an html.Node data structure. A response whose
+// content type is not parsed (see WithContentTypeAllowlist) is not read,
+// the Node of its page is an empty document
+func (c *crawler) getPage(ctx context.Context, u *url.URL, requeue bool) (*Page, error) {
+	if page := c.headPage(ctx, u); page != nil {
+		return page, nil
+	}
+	start := time.Now()
+	r, err := c.get(ctx, u, requeue)
+	if err != nil {
+		c.hostStats.record(u, start, 0, err)
+		return nil, err
+	}
+	defer r.Body.Close()
+	body := &countingReader{r: c.limitBody(r.Body)}
+	buffered := bufio.NewReader(body)
+	page := &Page{URL: c.pageURL(u, r), StatusCode: r.StatusCode, Header: r.Header, TLS: r.TLS}
+	mediaType := responseMediaType(r, func() []byte {
+		head, _ := buffered.Peek(sniffLen)
+		return head
+	})
+	if c.parsable(mediaType) && c.rawBody {
+		if page.Body, err = ioutil.ReadAll(buffered); err == nil {
+			page.Node, err = c.parse(bytes.NewReader(page.Body))
+		}
+	} else if c.parsable(mediaType) {
+		page.Node, err = c.parse(buffered)
+	} else {
+		page.Node = &html.Node{Type: html.DocumentNode}
+	}
+	c.hostStats.record(u, start, body.n, err)
+	if err != nil {
+		return nil, fmt.Errorf("error while html parsing response - %v", err)
+	}
+	c.latency.observe(time.Since(start))
+	c.warnTruncated(u, body.n)
+	c.recordResponse(page.URL, mediaType, bodySize(r, body.n))
+	return page, nil
+}
+
+// fetchPage performs an HTTP GET request using the input url and returns
+// the page, without its html content, along with the unparsed response body.
+// A response whose content type is not parsed (see WithContentTypeAllowlist)
+// is not read, the Node of its page is already set to an empty document
+func (c *crawler) fetchPage(ctx context.Context, u *url.URL, requeue bool) (*Page, []byte, error) {
+	if page := c.headPage(ctx, u); page != nil {
+		return page, nil, nil
+	}
+	start := time.Now()
+	r, err := c.get(ctx, u, requeue)
+	if err != nil {
+		c.hostStats.record(u, start, 0, err)
+		return nil, nil, err
+	}
+	defer r.Body.Close()
+	body := &countingReader{r: c.limitBody(r.Body)}
+	buffered := bufio.NewReader(body)
+	page := &Page{URL: c.pageURL(u, r), StatusCode: r.StatusCode, Header: r.Header, TLS: r.TLS}
+	mediaType := responseMediaType(r, func() []byte {
+		head, _ := buffered.Peek(sniffLen)
+		return head
+	})
+	var content []byte
+	if c.parsable(mediaType) {
+		content, err = ioutil.ReadAll(buffered)
+		if c.rawBody {
+			page.Body = content
+		}
+	} else {
+		page.Node = &html.Node{Type: html.DocumentNode}
+	}
+	c.hostStats.record(u, start, body.n, err)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while reading response - %v", err)
+	}
+	c.latency.observe(time.Since(start))
+	c.warnTruncated(u, body.n)
+	c.recordResponse(page.URL, mediaType, bodySize(r, body.n))
+	return page, content, nil
+}
+
+// headPage issues a HEAD request to u when the crawler is in HEAD first mode
+// (see WithHeadFirst). It returns the page of the response, with an empty
+// document, when its content type or length is filtered out. It returns nil
+// when the page is to be fetched, the HEAD request failing included
+func (c *crawler) headPage(ctx context.Context, u *url.URL) *Page {
+	if !c.headFirst {
+		return nil
+	}
+	start := time.Now()
+	req, err := c.newRequest(ctx, http.MethodHead, u)
+	if err != nil {
+		return nil
+	}
+	r, err := c.do(ctx, req)
+	if err != nil {
+		log.Debugf("failed HEAD request of page %s, getting it - %v", u, err)
+		return nil
+	}
+	r.Body.Close()
+	// servers not supporting HEAD, or without a Content-Type
+	// to filter on, are answered by the GET request
+	if r.StatusCode < http.StatusOK || r.StatusCode >= http.StatusMultipleChoices {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil
+	}
+	mediaType = strings.ToLower(mediaType)
+	if c.parsable(mediaType) && (c.maxBodyBytes <= 0 || r.ContentLength <= c.maxBodyBytes) {
+		return nil
+	}
+	page := &Page{URL: c.pageURL(u, r), Node: &html.Node{Type: html.DocumentNode}, StatusCode: r.StatusCode, Header: r.Header, TLS: r.TLS}
+	c.hostStats.record(u, start, 0, nil)
+	c.recordResponse(page.URL, mediaType, bodySize(r, 0))
+	return page
+}
+
+// parsable reports whether the responses of mediaType are parsed
+func (c *crawler) parsable(mediaType string) bool {
+	if len(c.allowedTypes) == 0 {
+		return true
+	}
+	for _, t := range c.allowedTypes {
+		if t == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// limitBody limits the reading of a response body to the maximum body
+// size of the crawler, if any
+func (c *crawler) limitBody(body io.Reader) io.Reader {
+	if c.maxBodyBytes <= 0 {
+		return body
+	}
+	return io.LimitReader(body, c.maxBodyBytes)
+}
+
+// warnTruncated logs a warning when the n bytes read out of the body of
+// the page at u reached the maximum body size of the crawler
+func (c *crawler) warnTruncated(u *url.URL, n int64) {
+	if c.maxBodyBytes > 0 && n >= c.maxBodyBytes {
+		log.Warnf("page %s truncated to its first %d bytes", u, c.maxBodyBytes)
+	}
+}
+
+// bodySize returns the size of the body of r out of which n bytes were read,
+// its Content-Length when the body was not read to its end
+func bodySize(r *http.Response, n int64) int64 {
+	if r.ContentLength > n {
+		return r.ContentLength
+	}
+	return n
+}
+
+// recordResponse counts the response of mediaType of the page at u with a body of n bytes
+func (c *crawler) recordResponse(u *url.URL, mediaType string, n int64) {
+	c.contentTypes.record(u, mediaType, n)
+	c.weights.record(u, n)
+}
+
+// pageURL returns the URL of the page requested at u answered with r: the
+// URL it was redirected to when the redirect policy says so, u otherwise
+func (c *crawler) pageURL(u *url.URL, r *http.Response) *url.URL {
+	if c.redirect == nil || !c.redirect.FinalURL || r.Request == nil {
+		return u
+	}
+	return r.Request.URL
+}
+
+// get performs an HTTP GET request using the input url, the request
+// is retried on network errors, timeouts included, according to the retry
+// policy. When requeue is set a response asking to retry later is discarded
+// and its *retryAfterError is returned
+func (c *crawler) get(ctx context.Context, u *url.URL, requeue bool) (*http.Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, u)
 	if err != nil {
 		return nil, fmt.Errorf("error while preparing request - %v", err)
 	}
-	client := http.DefaultClient
-	r, err := client.Do(req)
+	for attempt := 1; ; attempt++ {
+		r, err := c.do(ctx, req)
+		if err == nil {
+			if retryAfter := getRetryAfter(r); requeue && retryAfter != nil {
+				r.Body.Close()
+				return nil, retryAfter
+			}
+			return r, nil
+		}
+		if attempt >= c.retry.Attempts || ctx.Err() != nil {
+			return nil, fmt.Errorf("error while getting page - %v", err)
+		}
+		d := c.retry.delay(attempt)
+		log.Warnf("failed attempt %d to get page %s, retrying in %s - %v", attempt, u, d, err)
+		if !sleep(ctx, d) {
+			return nil, fmt.Errorf("error while getting page - %v", err)
+		}
+	}
+}
+
+// newRequest prepares a request of the crawler to u, with its User-Agent
+func (c *crawler) newRequest(ctx context.Context, method string, u *url.URL) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if ua := c.userAgentFor(u.Host); ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	return req, nil
+}
+
+// userAgentFor returns the User-Agent of the requests to host, an
+// empty string for the Go default one
+func (c *crawler) userAgentFor(host string) string {
+	if c.userAgents != nil {
+		return c.userAgents.pick(host)
+	}
+	return c.userAgent
+}
+
+// do issues req, once more after renewing the session of the crawler when
+// it expired (see WithSessionRenewal)
+func (c *crawler) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if c.session == nil {
+		return c.send(ctx, req)
+	}
+	generation := c.session.current()
+	r, err := c.send(ctx, req)
+	if err != nil || !c.session.expired(r) || !c.session.renew(ctx, generation) {
+		return r, err
+	}
+	r.Body.Close()
+	log.Debugf("request of page %s denied, retried with the renewed session", req.URL)
+	return c.send(ctx, req)
+}
+
+// send issues req within the request timeout of the crawler, shortened to end
+// before the deadline of ctx (see attemptTimeout), if any. The timeout also
+// bounds the reading of the response body
+func (c *crawler) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	timeout, shortened := c.attemptTimeout(ctx)
+	if timeout <= 0 {
+		return c.httpClient().Do(req)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	// the crawl records the attempts cut short by its deadline
+	timedOut := func() {
+		if shortened && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+			markDeadlineCut(ctx)
+		}
+	}
+	r, err := c.httpClient().Do(req.WithContext(attemptCtx))
 	if err != nil {
-		return nil, fmt.Errorf("error while getting page - %v", err)
+		timedOut()
+		cancel()
+		return nil, err
 	}
-	b, err := html.Parse(r.Body)
+	r.Body = &cancelOnClose{ReadCloser: r.Body, cancel: cancel, timedOut: timedOut}
+	return r, nil
+}
+
+// cancelOnClose releases the context of a request when its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+	// timedOut is called on close, before the context is released
+	timedOut func()
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	b.timedOut()
+	return b.ReadCloser.Close()
+}
+
+// scrape gets the page at u, found depth hops from the crawl base URL,
+// and runs the validators of the crawler on it
+func (c *crawler) scrape(ctx context.Context, u *url.URL, depth int, requeue bool) (*Page, error) {
+	page, err := c.getPage(ctx, u, requeue)
 	if err != nil {
-		return nil, fmt.Errorf("error while html parsing response - %v", err)
+		return nil, err
 	}
-	return b, err
+	page.Depth = depth
+	c.validate(page)
+	return page, nil
 }
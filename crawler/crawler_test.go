@@ -2,15 +2,23 @@ package crawler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/html"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // getBaseURLStr retrieves from the env variable `CRAWLER_BASE_URL`
@@ -59,6 +67,33 @@ func pageTitle(n *html.Node) string {
 	return title
 }
 
+// newTestSite starts a local web-server serving pages, a map of
+// path to html content. The server is closed at the end of the test
+func newTestSite(t *testing.T, pages map[string]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, content)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// linksPage builds an html page with title and links to hrefs
+func linksPage(title string, hrefs ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html><head><title>%s</title></head><body>", title)
+	for _, href := range hrefs {
+		fmt.Fprintf(&b, `<p><a href="%s">%s</a></p>`, href, href)
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
 func Test_getPageLinks(t *testing.T) {
 	tests := map[string]struct {
 		htmlStr string
@@ -335,3 +370,559 @@ func Test_crawler_Crawl_Cancelation_Integration(t *testing.T) {
 	// check equality on collected titles and expected titles
 	assert.True(t, reflect.DeepEqual(m, want))
 }
+
+// Test_crawler_Fetch_Integration depends on the startup of the attached `test_data/Dockerfile` container
+// this test fetches an explicit list of pages, including an orphan page which is not reachable by crawling,
+// and checks that only the listed pages are visited as the links in the pages are not followed
+func Test_crawler_Fetch_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	// build a crawler
+	c := &crawler{}
+	m := make(map[string]struct{})
+	mut := sync.Mutex{}
+
+	// visit is a function that given a page collects
+	// it's title and adds it to a set of titles
+	visit := func(u *url.URL, page *html.Node) {
+		title := pageTitle(page)
+		// add the title to the map
+		mut.Lock()
+		m[title] = struct{}{}
+		mut.Unlock()
+	}
+	urls := []*url.URL{
+		getURL(getBaseURLStr() + "page1.html"),
+		getURL(getBaseURLStr() + "orphan/orphan11.html"),
+	}
+	err := c.Fetch(context.Background(), urls, visit)
+	assert.Nil(t, err)
+
+	// want contains only the titles of the fetched pages
+	want := map[string]struct{}{
+		"page1":   {},
+		"orphan1": {},
+	}
+
+	// check equality on collected titles and expected titles
+	assert.True(t, reflect.DeepEqual(m, want))
+}
+
+// Test_crawler_FetchStream_Integration depends on the startup of the attached `test_data/Dockerfile` container
+// this test streams the URLs to be fetched through a channel and checks that all of them are visited
+// once the channel gets closed
+func Test_crawler_FetchStream_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	// build a crawler
+	c := &crawler{}
+	m := make(map[string]struct{})
+	mut := sync.Mutex{}
+
+	visit := func(u *url.URL, page *html.Node) {
+		title := pageTitle(page)
+		// add the title to the map
+		mut.Lock()
+		m[title] = struct{}{}
+		mut.Unlock()
+	}
+
+	urls := make(chan *url.URL)
+	go func() {
+		defer close(urls)
+		urls <- getURL(getBaseURLStr() + "page2.html")
+		urls <- getURL(getBaseURLStr() + "child/page11.html")
+	}()
+	err := c.FetchStream(context.Background(), urls, visit)
+	assert.Nil(t, err)
+
+	want := map[string]struct{}{
+		"page2":  {},
+		"page11": {},
+	}
+
+	// check equality on collected titles and expected titles
+	assert.True(t, reflect.DeepEqual(m, want))
+}
+
+func Test_crawler_CrawlSites(t *testing.T) {
+	siteA := newTestSite(t, map[string]string{
+		"/index.html": linksPage("a-index", "a1.html", "a2.html"),
+		"/a1.html":    linksPage("a1", "index.html"),
+		"/a2.html":    linksPage("a2", "a1.html"),
+	})
+	siteB := newTestSite(t, map[string]string{
+		"/index.html": linksPage("b-index", "b1.html", siteA.URL+"/a1.html"),
+		"/b1.html":    linksPage("b1"),
+	})
+
+	var mut sync.Mutex
+	titles := make(map[string]struct{})
+	visit := func(u *url.URL, page *html.Node) {
+		mut.Lock()
+		titles[pageTitle(page)] = struct{}{}
+		mut.Unlock()
+	}
+
+	baseA, baseB := getURL(siteA.URL+"/index.html"), getURL(siteB.URL+"/index.html")
+	stats, err := NewCrawler().CrawlSites(context.Background(), []*url.URL{baseA, baseB}, visit)
+	assert.Nil(t, err)
+
+	// each site is scoped to its own domain and has its own stats
+	assert.Equal(t, map[string]Stats{
+		baseA.String(): {Pages: 3},
+		baseB.String(): {Pages: 2},
+	}, stats)
+	assert.Equal(t, map[string]struct{}{
+		"a-index": {}, "a1": {}, "a2": {}, "b-index": {}, "b1": {},
+	}, titles)
+}
+
+func Test_crawler_CrawlSites_NilBase(t *testing.T) {
+	_, err := NewCrawler().CrawlSites(context.Background(), []*url.URL{getURL("http://localhost/"), nil}, func(*url.URL, *html.Node) {})
+	assert.NotNil(t, err)
+}
+
+func Test_crawler_Crawl_MaxConcurrency(t *testing.T) {
+	var mut sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	pages := map[string]string{
+		"/index.html": linksPage("index", "p1.html", "p2.html", "p3.html", "p4.html", "p5.html", "p6.html"),
+	}
+	for i := 1; i <= 6; i++ {
+		pages[fmt.Sprintf("/p%d.html", i)] = linksPage(fmt.Sprintf("p%d", i))
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mut.Unlock()
+		// keep the request in flight long enough to overlap with the others
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, pages[r.URL.Path])
+		mut.Lock()
+		inFlight--
+		mut.Unlock()
+	}))
+	defer srv.Close()
+
+	store := NewMemoryStore()
+	c := NewCrawler(WithMaxConcurrency(2), WithStore(store))
+	err := c.Crawl(context.Background(), getURL(srv.URL+"/index.html"), func(*url.URL, *html.Node) {})
+	assert.Nil(t, err)
+
+	assert.Equal(t, Stats{Pages: 7}, store.Stats())
+	assert.LessOrEqual(t, maxInFlight, 2)
+}
+
+func Test_crawler_Crawl_WithParseConcurrency(t *testing.T) {
+	var mut sync.Mutex
+	served, parsing, maxParsing := 0, 0, 0
+	servedAtFirstVisits := 0
+	pages := map[string]string{
+		"/index.html": linksPage("index", "p1.html", "p2.html", "p3.html", "p4.html", "p5.html", "p6.html"),
+	}
+	for i := 1; i <= 6; i++ {
+		pages[fmt.Sprintf("/p%d.html", i)] = linksPage(fmt.Sprintf("p%d", i))
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, pages[r.URL.Path])
+		mut.Lock()
+		served++
+		mut.Unlock()
+	}))
+	defer srv.Close()
+
+	visits := 0
+	store := NewMemoryStore()
+	c := NewCrawler(WithMaxConcurrency(2), WithParseConcurrency(1, 6), WithStore(store))
+	err := c.CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+		mut.Lock()
+		parsing++
+		if parsing > maxParsing {
+			maxParsing = parsing
+		}
+		visits++
+		if visits == 3 {
+			servedAtFirstVisits = served
+		}
+		mut.Unlock()
+		// slow visits must not hold back the fetches
+		time.Sleep(20 * time.Millisecond)
+		mut.Lock()
+		parsing--
+		mut.Unlock()
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, Stats{Pages: 7}, store.Stats())
+	assert.Equal(t, 1, maxParsing)
+	assert.Equal(t, 7, servedAtFirstVisits)
+}
+
+func Test_crawler_Crawl_WithMaxDepth(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html": linksPage("index", "p1.html"),
+		"/p1.html":    linksPage("p1", "p2.html", "index.html"),
+		"/p2.html":    linksPage("p2", "p3.html"),
+		"/p3.html":    linksPage("p3"),
+	})
+	tests := map[string]struct {
+		maxDepth int
+		want     map[string]int
+	}{
+		"base_only": {
+			maxDepth: 0,
+			want:     map[string]int{"index": 0},
+		},
+		"one_hop": {
+			maxDepth: 1,
+			want:     map[string]int{"index": 0, "p1": 1},
+		},
+		"no_limit": {
+			maxDepth: -1,
+			want:     map[string]int{"index": 0, "p1": 1, "p2": 2, "p3": 3},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mut sync.Mutex
+			depths := make(map[string]int)
+			err := NewCrawler(WithMaxDepth(tt.maxDepth)).CrawlPages(context.Background(), getURL(site.URL+"/index.html"), func(p *Page) {
+				mut.Lock()
+				defer mut.Unlock()
+				depths[p.Title()] = p.Depth
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, depths)
+		})
+	}
+}
+
+func Test_crawler_Crawl_WithMaxDuration(t *testing.T) {
+	// every page but the index hangs until its request is cancelled
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.html" {
+			fmt.Fprint(w, linksPage("index", "p1.html", "p2.html"))
+			return
+		}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	tests := map[string]struct {
+		timeout time.Duration
+		wantErr error
+	}{
+		"max_duration_exceeded": {
+			timeout: time.Minute,
+			wantErr: ErrMaxDuration,
+		},
+		"context_cancelled_first": {
+			// the hanging requests are dispatched, before the deadline margin
+			timeout: 50*time.Millisecond + deadlineMargin,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			store := NewMemoryStore()
+			start := time.Now()
+			err := NewCrawler(WithMaxDuration(100*time.Millisecond), WithStore(store)).Crawl(ctx, getURL(srv.URL+"/index.html"), func(*url.URL, *html.Node) {})
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+			} else {
+				assert.Nil(t, err)
+			}
+			// the hanging requests are cancelled and recorded once the crawl returns
+			assert.Less(t, int64(time.Since(start)), int64(time.Second))
+			assert.Equal(t, Stats{Pages: 1, Errors: 2}, store.Stats())
+		})
+	}
+}
+
+// countingTransport counts the requests handed to the default transport
+type countingTransport struct {
+	requests int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.requests, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func Test_crawler_Crawl_WithHTTPClient(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html": linksPage("index", "page1.html"),
+		"/page1.html": linksPage("page1"),
+	})
+	transport := &countingTransport{}
+	client := &http.Client{Transport: transport, Timeout: time.Minute}
+
+	var signed int32
+	signer := RequestSignerFunc(func(req *http.Request) error {
+		atomic.AddInt32(&signed, 1)
+		return nil
+	})
+	c := NewCrawler(WithHTTPClient(client), WithRequestSigner(signer))
+	err := c.Crawl(context.Background(), getURL(site.URL+"/index.html"), func(*url.URL, *html.Node) {})
+	assert.Nil(t, err)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&transport.requests))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&signed))
+	// the client of the caller is not modified, its settings are kept
+	assert.Equal(t, transport, client.Transport)
+	assert.Equal(t, time.Minute, c.(*crawler).client.Timeout)
+}
+
+func Test_crawler_Crawl_WithUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix domain sockets not supported")
+	}
+	socket := filepath.Join(t.TempDir(), "crawler.sock")
+	l, err := net.Listen("unix", socket)
+	assert.Nil(t, err)
+	pages := map[string]string{
+		"/index.html": linksPage("index", "page1.html"),
+		"/page1.html": linksPage("page1", "index.html"),
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, pages[r.URL.Path])
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	var mut sync.Mutex
+	titles := make(map[string]struct{})
+	visit := func(u *url.URL, page *html.Node) {
+		mut.Lock()
+		titles[pageTitle(page)] = struct{}{}
+		mut.Unlock()
+	}
+
+	// the host of the URL is not resolved, connections go through the socket
+	c := NewCrawler(WithUnixSocket(socket))
+	err = c.Crawl(context.Background(), getURL("http://unix-socket-site/index.html"), visit)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]struct{}{"index": {}, "page1": {}}, titles)
+}
+
+func Test_crawler_CrawlPages_WithMaxBodyBytes(t *testing.T) {
+	// the links past the maximum body size are not read
+	index := linksPage("index", "page1.html")
+	site := newTestSite(t, map[string]string{
+		"/index.html": index + strings.Repeat(" ", 100) + linksPage("tail", "page2.html"),
+		"/page1.html": linksPage("page1"),
+		"/page2.html": linksPage("page2"),
+	})
+
+	for name, opts := range map[string][]Option{
+		"fetch_and_parse": {WithMaxBodyBytes(int64(len(index)))},
+		"parse_queue":     {WithMaxBodyBytes(int64(len(index))), WithParseConcurrency(1, 1)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var titles []string
+			err := NewCrawler(opts...).CrawlPages(context.Background(), getURL(site.URL+"/index.html"), func(p *Page) {
+				mu.Lock()
+				defer mu.Unlock()
+				titles = append(titles, p.Title())
+			})
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, []string{"index", "page1"}, titles)
+		})
+	}
+}
+
+func Test_crawler_CrawlPages_WithRawBody(t *testing.T) {
+	// the raw body keeps the source as served, unlike the rendering of the page
+	index := "<html><head><title>index</title></head><body>\n<P>Call us</P>\n</body></html>"
+	site := newTestSite(t, map[string]string{
+		"/index.html": index,
+	})
+
+	for name, opts := range map[string][]Option{
+		"fetch_and_parse": {WithRawBody()},
+		"parse_queue":     {WithRawBody(), WithParseConcurrency(1, 1)},
+		"not_kept":        nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var page *Page
+			err := NewCrawler(opts...).CrawlPages(context.Background(), getURL(site.URL+"/index.html"), func(p *Page) {
+				page = p
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, "index", page.Title())
+			if opts == nil {
+				assert.Nil(t, page.Body)
+				return
+			}
+			assert.Equal(t, index, string(page.Body))
+		})
+	}
+}
+
+func Test_crawler_CrawlPages_WithLinksOnly(t *testing.T) {
+	// the anchor inside the script is not an element of the page
+	index := `<html><head><title>index</title><meta name="robots" content="noindex"></head><body>
+<script>document.write('<a href="/script.html">script</a>')</script>
+<div><p><a href="/page1.html">page 1</a></p><a href="/page2.html"/></div></body></html>`
+	site := newTestSite(t, map[string]string{
+		"/index.html": index,
+		"/page1.html": linksPage("page 1", "/index.html"),
+		"/page2.html": linksPage("page 2"),
+	})
+
+	for name, opts := range map[string][]Option{
+		"fetch_and_parse": {WithLinksOnly()},
+		"parse_queue":     {WithLinksOnly(), WithParseConcurrency(1, 1)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			pages := make(map[string]*Page)
+			err := NewCrawler(opts...).CrawlPages(context.Background(), getURL(site.URL+"/index.html"), func(p *Page) {
+				mu.Lock()
+				defer mu.Unlock()
+				pages[p.URL.Path] = p
+			})
+			assert.Nil(t, err)
+			assert.Len(t, pages, 3)
+			index := pages["/index.html"]
+			assert.Equal(t, []string{"/page1.html", "/page2.html"}, getOrderedPageLinks(index.Node))
+			assert.Equal(t, "noindex", getMeta(index.Node, "robots"))
+			// the other elements are not parsed
+			assert.Empty(t, index.Title())
+		})
+	}
+}
+
+func Test_crawler_CrawlPages_WithPrefetch(t *testing.T) {
+	tests := map[string]struct {
+		prefetch int
+		// want are the pages requested while the index is visited
+		want []string
+	}{
+		"no_prefetch": {},
+		"prefetched": {
+			prefetch: 2,
+			want:     []string{"/page1.html", "/page2.html"},
+		},
+		"more_than_links": {
+			prefetch: 10,
+			want:     []string{"/page1.html", "/page2.html", "/page3.html"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			requested := make(chan string, 10)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/index.html" {
+					requested <- r.URL.Path
+					fmt.Fprint(w, linksPage(r.URL.Path))
+					return
+				}
+				fmt.Fprint(w, linksPage(r.URL.Path, "page1.html", "page2.html", "page3.html"))
+			}))
+			defer srv.Close()
+
+			var during []string
+			err := NewCrawler(WithPrefetch(tt.prefetch)).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+				if p.Title() != "/index.html" {
+					return
+				}
+				// the visit of the index lasts until the prefetched pages are requested
+				timeout := time.After(100 * time.Millisecond)
+				for len(during) < len(tt.want) {
+					select {
+					case path := <-requested:
+						during = append(during, path)
+					case <-timeout:
+						return
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				for len(requested) > 0 {
+					during = append(during, <-requested)
+				}
+			})
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, tt.want, during)
+		})
+	}
+}
+
+func Test_crawler_CrawlPages_WithUserAgent(t *testing.T) {
+	tests := map[string]struct {
+		opts []Option
+		want string
+	}{
+		"default": {
+			want: DefaultUserAgent,
+		},
+		"custom": {
+			opts: []Option{WithUserAgent("my-bot/1.0")},
+			want: "my-bot/1.0",
+		},
+		"go_default": {
+			opts: []Option{WithUserAgent("")},
+			want: "Go-http-client/1.1",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			agents := make(map[string]string)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				agents[r.URL.Path] = r.UserAgent()
+				mu.Unlock()
+				if r.URL.Path == "/robots.txt" {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprint(w, linksPage("index"))
+			}))
+			defer srv.Close()
+
+			opts := append([]Option{WithRobotsTxt("my-bot"), WithHeadFirst()}, tt.opts...)
+			err := NewCrawler(opts...).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(*Page) {})
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]string{"/robots.txt": tt.want, "/index.html": tt.want}, agents)
+		})
+	}
+}
+
+func Test_NewHTTPClient(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+	defer srv.Close()
+
+	client := NewHTTPClient(WithUserAgent("my-bot/1.0"), WithHeaders(http.Header{"X-Api-Key": {"t0k3n"}}))
+	resp, err := client.Get(srv.URL + "/image.png")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "my-bot/1.0", got.UserAgent())
+	assert.Equal(t, "t0k3n", got.Header.Get("X-Api-Key"))
+
+	// the User-Agent of a request is kept
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/image.png", nil)
+	assert.Nil(t, err)
+	req.Header.Set("User-Agent", "other-bot/2.0")
+	resp, err = client.Do(req)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "other-bot/2.0", got.UserAgent())
+}
@@ -0,0 +1,98 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyWeight is the weight of the last observed duration in the
+// average latency of the page requests
+const latencyWeight = 0.125
+
+// deadlineMargin is the time left, before the deadline of a crawl, to record the
+// pages whose request was cut short by the deadline
+const deadlineMargin = 50 * time.Millisecond
+
+// latencyEstimate is the moving average of the duration of the page requests,
+// the zero value has no estimate
+type latencyEstimate struct {
+	mu  sync.Mutex
+	avg time.Duration
+}
+
+// observe updates the estimate with the duration d of a page request
+func (e *latencyEstimate) observe(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.avg == 0 {
+		e.avg = d
+		return
+	}
+	e.avg += time.Duration(latencyWeight * float64(d-e.avg))
+}
+
+// estimate returns the expected duration of a page request, 0 until the
+// first page request completed
+func (e *latencyEstimate) estimate() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.avg
+}
+
+// canFinish reports whether a page request started now is expected to complete
+// deadlineMargin before the deadline of ctx, if any. The pages that cannot plausibly
+// be fetched in time are not requested, so that a crawl with a deadline (e.g.
+// WithMaxDuration) ends at its deadline with the pages it could fetch rather than
+// with requests cancelled mid-flight
+func (c *crawler) canFinish(ctx context.Context) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return true
+	}
+	// without estimate, the first requests are issued until the deadline
+	estimate := c.latency.estimate()
+	if estimate == 0 {
+		return time.Until(deadline) >= 0
+	}
+	return time.Until(deadline)-deadlineMargin >= estimate
+}
+
+// attemptTimeout returns the timeout of an attempt of a page request started now:
+// the request timeout of the crawler, shortened so that the attempt ends
+// deadlineMargin before the deadline of ctx, if any, in which case shortened is
+// true. 0 means no timeout
+func (c *crawler) attemptTimeout(ctx context.Context) (timeout time.Duration, shortened bool) {
+	timeout = c.requestTimeout
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return timeout, false
+	}
+	// too close to the deadline, the attempt is bounded by ctx itself
+	if left := time.Until(deadline) - deadlineMargin; left > 0 && (timeout <= 0 || left < timeout) {
+		return left, true
+	}
+	return timeout, false
+}
+
+// deadlineCutKey is the context key of the flag set once a request of a crawl
+// was cut short because of the deadline of the crawl (see attemptTimeout)
+type deadlineCutKey struct{}
+
+// withDeadlineCut returns a copy of ctx carrying the flag set by markDeadlineCut,
+// and the function reporting whether it was set
+func withDeadlineCut(ctx context.Context) (context.Context, func() bool) {
+	cut := new(int32)
+	return context.WithValue(ctx, deadlineCutKey{}, cut), func() bool {
+		return atomic.LoadInt32(cut) == 1
+	}
+}
+
+// markDeadlineCut sets the flag of ctx, if any, recording that a request
+// was cut short because of the deadline
+func markDeadlineCut(ctx context.Context) {
+	if cut, ok := ctx.Value(deadlineCutKey{}).(*int32); ok {
+		atomic.StoreInt32(cut, 1)
+	}
+}
@@ -0,0 +1,141 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func Test_latencyEstimate(t *testing.T) {
+	var e latencyEstimate
+	assert.Equal(t, time.Duration(0), e.estimate())
+	e.observe(100 * time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, e.estimate())
+	e.observe(900 * time.Millisecond)
+	assert.Equal(t, 200*time.Millisecond, e.estimate())
+}
+
+func Test_crawler_canFinish(t *testing.T) {
+	c := &crawler{}
+	assert.True(t, c.canFinish(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// no estimate yet
+	assert.True(t, c.canFinish(ctx))
+	c.latency.observe(time.Second)
+	assert.False(t, c.canFinish(ctx))
+	assert.True(t, c.canFinish(context.Background()))
+}
+
+func Test_crawler_Crawl_Deadline(t *testing.T) {
+	// every page takes 100ms to be served
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, linksPage(r.URL.Path, "p1.html", "p2.html", "p3.html"))
+	}))
+	defer srv.Close()
+
+	// the index and p1 are fetched in time, the other pages cannot be
+	// fetched before the deadline and are not requested
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond+deadlineMargin)
+	defer cancel()
+	store := NewMemoryStore()
+	err := NewCrawler(WithMaxConcurrency(1), WithStore(store)).Crawl(ctx, getURL(srv.URL+"/index.html"), func(*url.URL, *html.Node) {})
+	assert.Nil(t, err)
+	// the pages not requested are recorded as skipped
+	assert.Equal(t, Stats{Pages: 2, Skipped: 2}, store.Stats())
+}
+
+func Test_crawler_CrawlSites_Deadline_MaxDuration(t *testing.T) {
+	// every page takes 100ms to be served
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, linksPage(r.URL.Path, "p1.html", "p2.html", "p3.html"))
+	}))
+	defer srv.Close()
+
+	// the crawl ends before its budget, the pages that could not be fetched
+	// in time being skipped, and is reported as truncated by the budget
+	start := time.Now()
+	c := NewCrawler(WithMaxConcurrency(1), WithMaxDuration(250*time.Millisecond+deadlineMargin))
+	stats, err := c.CrawlSites(context.Background(), []*url.URL{getURL(srv.URL + "/index.html")}, func(*url.URL, *html.Node) {})
+	assert.Less(t, int64(time.Since(start)), int64(250*time.Millisecond+deadlineMargin))
+	assert.True(t, errors.Is(err, ErrMaxDuration), err)
+	assert.Equal(t, map[string]Stats{srv.URL + "/index.html": {Pages: 2, Skipped: 2}}, stats)
+}
+
+func Test_crawler_Fetch_Deadline(t *testing.T) {
+	// every page takes 100ms to be served
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, linksPage(r.URL.Path))
+	}))
+	defer srv.Close()
+
+	var visited []string
+	c := NewCrawler(WithMaxConcurrency(1), WithMaxDuration(250*time.Millisecond+deadlineMargin))
+	urls := []*url.URL{getURL(srv.URL + "/p1.html"), getURL(srv.URL + "/p2.html"), getURL(srv.URL + "/p3.html"), getURL(srv.URL + "/p4.html")}
+	err := c.Fetch(context.Background(), urls, func(u *url.URL, _ *html.Node) {
+		visited = append(visited, u.Path)
+	})
+	// the pages skipped because of the deadline truncate the fetch
+	assert.True(t, errors.Is(err, ErrMaxDuration), err)
+	assert.Len(t, visited, 2)
+}
+
+func Test_crawler_attemptTimeout(t *testing.T) {
+	c := &crawler{}
+	timeout, shortened := c.attemptTimeout(context.Background())
+	assert.Equal(t, time.Duration(0), timeout)
+	assert.False(t, shortened)
+	c.requestTimeout = time.Second
+	timeout, shortened = c.attemptTimeout(context.Background())
+	assert.Equal(t, time.Second, timeout)
+	assert.False(t, shortened)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	// the request timeout ends before the deadline
+	timeout, shortened = c.attemptTimeout(ctx)
+	assert.Equal(t, time.Second, timeout)
+	assert.False(t, shortened)
+	c.requestTimeout = 0
+	timeout, shortened = c.attemptTimeout(ctx)
+	assert.InDelta(t, int64(time.Hour-deadlineMargin), int64(timeout), float64(time.Second))
+	assert.True(t, shortened)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	c.requestTimeout = time.Second
+	timeout, shortened = c.attemptTimeout(ctx)
+	assert.LessOrEqual(t, int64(timeout), int64(500*time.Millisecond-deadlineMargin))
+	assert.True(t, shortened)
+}
+
+func Test_crawler_Crawl_Deadline_CutsRequests(t *testing.T) {
+	var cut time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the page hangs until its request is cancelled
+		<-r.Context().Done()
+		cut = time.Now()
+	}))
+	defer srv.Close()
+
+	deadline := time.Now().Add(300 * time.Millisecond)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	store := NewMemoryStore()
+	err := NewCrawler(WithRequestTimeout(time.Minute), WithStore(store)).Crawl(ctx, getURL(srv.URL+"/index.html"), func(*url.URL, *html.Node) {})
+	assert.Nil(t, err)
+	// the request is cut before the deadline and the page recorded as an error
+	assert.Equal(t, Stats{Errors: 1}, store.Stats())
+	srv.Close()
+	assert.True(t, cut.Before(deadline))
+}
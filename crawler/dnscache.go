@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsCache caches the addresses of the resolved hosts for a fixed time to
+// live, so that the connections to a host do not resolve it every time
+type dnsCache struct {
+	ttl time.Duration
+	// lookup resolves a host, net.DefaultResolver.LookupHost by default
+	lookup func(ctx context.Context, host string) ([]string, error)
+	// now is the clock of the cache, overridden by tests
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+}
+
+// dnsEntry holds the addresses of a host, ready is closed once they are
+// resolved so that concurrent connections wait for a single lookup
+type dnsEntry struct {
+	ready   chan struct{}
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// newDNSCache returns a dnsCache keeping the addresses for ttl
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{ttl: ttl, lookup: net.DefaultResolver.LookupHost, now: time.Now, entries: make(map[string]*dnsEntry)}
+}
+
+// resolve returns the addresses of host, from the cache while they did not
+// expire. A failed lookup is not cached
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	if ok {
+		select {
+		case <-entry.ready:
+			ok = entry.err == nil && c.now().Before(entry.expires)
+		default:
+			// the lookup is in progress
+		}
+	}
+	if !ok {
+		entry = &dnsEntry{ready: make(chan struct{})}
+		c.entries[host] = entry
+		c.mu.Unlock()
+		// the lookup is not cancelled with the connection waiting for it first
+		entry.addrs, entry.err = c.lookup(context.Background(), host)
+		entry.expires = c.now().Add(c.ttl)
+		close(entry.ready)
+		return entry.addrs, entry.err
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.addrs, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dialer wraps dial so that the host names are resolved through the cache,
+// the addresses of a host are tried in turn until a connection succeeds
+func (c *dnsCache) dialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+		addrs, err := c.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var conn net.Conn
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		for _, a := range addrs {
+			if conn, err = dial(ctx, network, net.JoinHostPort(a, port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_dnsCache_resolve(t *testing.T) {
+	now := time.Date(2021, 4, 18, 20, 0, 0, 0, time.UTC)
+	var lookups int32
+	c := newDNSCache(time.Minute)
+	c.now = func() time.Time { return now }
+	c.lookup = func(ctx context.Context, host string) ([]string, error) {
+		atomic.AddInt32(&lookups, 1)
+		if host == "unknown.test" {
+			return nil, errors.New("no such host")
+		}
+		return []string{"10.0.0.12"}, nil
+	}
+
+	// the concurrent connections wait for a single lookup
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addrs, err := c.resolve(context.Background(), "my-web-site.test")
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"10.0.0.12"}, addrs)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lookups))
+
+	// the addresses are resolved again once expired
+	now = now.Add(time.Minute)
+	_, err := c.resolve(context.Background(), "my-web-site.test")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&lookups))
+
+	// a failed lookup is not cached
+	_, err = c.resolve(context.Background(), "unknown.test")
+	assert.NotNil(t, err)
+	_, err = c.resolve(context.Background(), "unknown.test")
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&lookups))
+}
+
+func Test_dnsCache_dialer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	defer srv.Close()
+	port := getURL(srv.URL).Port()
+
+	c := newDNSCache(time.Minute)
+	c.lookup = func(ctx context.Context, host string) ([]string, error) {
+		// the first address does not accept connections
+		return []string{"127.0.0.2", "127.0.0.1"}, nil
+	}
+	client := &http.Client{Transport: &http.Transport{DialContext: c.dialer(nil)}}
+	r, err := client.Get("http://my-web-site.test:" + port + "/")
+	assert.Nil(t, err)
+	r.Body.Close()
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+}
@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"net"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// DomainGroup holds the settings shared by the hosts matching one of its
+// patterns, e.g. all the sub-domains of a large estate (see WithDomainGroups)
+type DomainGroup struct {
+	// Patterns are the host names of the group in the syntax of path.Match
+	// (e.g. `*.my-company.com`), the port of the hosts is ignored
+	Patterns []string
+	// Header are the headers set on the requests to the group (e.g. Authorization)
+	Header http.Header
+	// RateLimit, when positive, is the maximum number of requests per second to
+	// the hosts of the group, all together, with bursts of up to RateBurst requests
+	RateLimit float64
+	RateBurst int
+}
+
+// Matches reports whether host (e.g. `www.my-company.com:8080`) belongs to the group
+func (g DomainGroup) Matches(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	for _, pattern := range g.Patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// domainGroup is a DomainGroup along with the rate limiter of its hosts
+type domainGroup struct {
+	DomainGroup
+	limiter *rateLimiter
+}
+
+// domainGroupsMiddleware returns a Middleware applying to every request the
+// settings of the first group its host belongs to, if any
+func domainGroupsMiddleware(groups []DomainGroup) Middleware {
+	compiled := make([]domainGroup, 0, len(groups))
+	for _, g := range groups {
+		dg := domainGroup{DomainGroup: g}
+		if g.RateLimit > 0 {
+			dg.limiter = newRateLimiter(g.RateLimit, g.RateBurst)
+		}
+		compiled = append(compiled, dg)
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			for _, g := range compiled {
+				if !g.Matches(req.URL.Host) {
+					continue
+				}
+				if g.limiter != nil {
+					if err := g.limiter.wait(req.Context()); err != nil {
+						return nil, err
+					}
+				}
+				if len(g.Header) > 0 {
+					// a RoundTripper must not modify the request
+					req = req.Clone(req.Context())
+					setHeaders(req, g.Header)
+				}
+				break
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
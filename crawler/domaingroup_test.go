@@ -0,0 +1,64 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDomainGroup_Matches(t *testing.T) {
+	g := DomainGroup{Patterns: []string{"*.my-company.com", "Intranet.local", "[broken"}}
+	tests := map[string]struct {
+		host string
+		want bool
+	}{
+		"sub_domain":        {host: "www.my-company.com", want: true},
+		"nested_sub_domain": {host: "docs.eu.my-company.com", want: true},
+		"port_ignored":      {host: "WWW.my-company.com:8080", want: true},
+		"case_insensitive":  {host: "intranet.local", want: true},
+		"apex_not_matched":  {host: "my-company.com"},
+		"other_domain":      {host: "my-company.com.evil.com"},
+		"ipv6":              {host: "[::1]:8080"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, g.Matches(tt.host))
+		})
+	}
+}
+
+func Test_crawler_CrawlPages_WithDomainGroups(t *testing.T) {
+	var mu sync.Mutex
+	var requests []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, time.Now())
+		mu.Unlock()
+		fmt.Fprint(w, linksPage(r.Header.Get("X-Api-Key"), "page1.html", "page2.html"))
+	}))
+	defer srv.Close()
+
+	groups := []DomainGroup{
+		{Patterns: []string{"*.my-company.com"}, Header: http.Header{"X-Api-Key": {"other"}}},
+		{Patterns: []string{"127.0.0.*"}, Header: http.Header{"X-Api-Key": {"key"}}, RateLimit: 20, RateBurst: 1},
+		{Patterns: []string{"127.0.0.1"}, Header: http.Header{"X-Api-Key": {"shadowed"}}},
+	}
+	var titles []string
+	start := time.Now()
+	err := NewCrawler(WithDomainGroups(groups...)).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+		mu.Lock()
+		defer mu.Unlock()
+		titles = append(titles, p.Title())
+	})
+	assert.Nil(t, err)
+	// the first matching group applies
+	assert.Equal(t, []string{"key", "key", "key"}, titles)
+	// 3 requests at 20 requests per second without burst last at least 100ms
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(90*time.Millisecond))
+	assert.Len(t, requests, 3)
+}
@@ -0,0 +1,44 @@
+package crawler
+
+import (
+	"net/url"
+	"path"
+	"sync"
+)
+
+// Exclusions is a set of URL path patterns excluded from the crawls using it
+// (see WithExclusions). Patterns can be added while crawls are running, e.g. by
+// an operator spotting a runaway section: the pages not yet fetched matching
+// them are skipped without cancelling the crawls. The zero value is empty
+type Exclusions struct {
+	mu       sync.RWMutex
+	patterns []string
+}
+
+// Add excludes the pages whose path matches pattern, in the syntax of
+// path.Match (e.g. `/calendar/*/*`). It returns an error for malformed patterns
+func (e *Exclusions) Add(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.patterns = append(e.patterns, pattern)
+	return nil
+}
+
+// Excluded reports whether the path of u matches one of the patterns
+func (e *Exclusions) Excluded(u *url.URL) bool {
+	if e == nil {
+		return false
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, pattern := range e.patterns {
+		// patterns are validated by Add
+		if ok, _ := path.Match(pattern, u.Path); ok {
+			return true
+		}
+	}
+	return false
+}
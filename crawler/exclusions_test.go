@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestExclusions_Excluded(t *testing.T) {
+	var e Exclusions
+	assert.Nil(t, e.Add("/calendar/*/*"))
+	assert.Nil(t, e.Add("/tmp-*"))
+	assert.NotNil(t, e.Add("/[broken"))
+
+	tests := map[string]struct {
+		url  string
+		want bool
+	}{
+		"matching":         {url: "https://my-web-site.com/calendar/2021/04", want: true},
+		"matching_prefix":  {url: "https://my-web-site.com/tmp-page.html", want: true},
+		"deeper_path":      {url: "https://my-web-site.com/calendar/2021/04/18", want: false},
+		"not_matching":     {url: "https://my-web-site.com/calendar", want: false},
+		"query_is_ignored": {url: "https://my-web-site.com/tmp-page.html?id=1", want: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, e.Excluded(getURL(tt.url)))
+		})
+	}
+
+	var nilExclusions *Exclusions
+	assert.False(t, nilExclusions.Excluded(getURL("https://my-web-site.com/")))
+}
+
+func Test_crawler_CrawlPages_WithExclusions(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html":       linksPage("index", "page1.html", "calendar/2021/01", "calendar/2021/02"),
+		"/page1.html":       linksPage("page1"),
+		"/calendar/2021/01": linksPage("january"),
+		"/calendar/2021/02": linksPage("february"),
+	})
+
+	exclusions := &Exclusions{}
+	var mu sync.Mutex
+	var titles []string
+	err := NewCrawler(WithExclusions(exclusions), WithMaxConcurrency(1)).CrawlPages(context.Background(), getURL(site.URL+"/index.html"), func(p *Page) {
+		mu.Lock()
+		defer mu.Unlock()
+		titles = append(titles, p.Title())
+		// the runaway section is excluded while the crawl is running
+		if p.Title() == "index" {
+			assert.Nil(t, exclusions.Add("/calendar/*/*"))
+		}
+	})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"index", "page1"}, titles)
+}
@@ -0,0 +1,44 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/url"
+	"sync"
+)
+
+// Extract crawls base and applies extract on every crawled page collecting
+// the returned records. Pages for which extract fails are skipped (best effort)
+// and the number of failures is reported in the returned error along with the
+// first failure, the records of the other pages are still returned.
+// Records are returned in the order in which the pages are visited
+func Extract[T any](ctx context.Context, base *url.URL, extract func(*Page) (T, error)) ([]T, error) {
+	var mut sync.Mutex
+	var records []T
+	var failures int
+	var firstErr error
+
+	visit := func(p *Page) {
+		record, err := extract(p)
+		mut.Lock()
+		defer mut.Unlock()
+		if err != nil {
+			log.Errorf("failed to extract record from page %s - %v", p.URL, err)
+			failures++
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		records = append(records, record)
+	}
+
+	if err := NewCrawler().CrawlPages(ctx, base, visit); err != nil {
+		return nil, err
+	}
+	if firstErr != nil {
+		return records, fmt.Errorf("extraction failed on %d pages, first error - %w", failures, firstErr)
+	}
+	return records, nil
+}
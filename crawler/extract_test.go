@@ -0,0 +1,63 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"sort"
+	"testing"
+)
+
+// Test_Extract_Integration depends on the startup of the attached `test_data/Dockerfile` container
+// this test extracts a typed record (url + title) from each crawled page
+func Test_Extract_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	type record struct {
+		URL   string
+		Title string
+	}
+	records, err := Extract(context.Background(), getURL(getBaseURLIndex()), func(p *Page) (record, error) {
+		return record{URL: p.URL.String(), Title: pageTitle(p.Node)}, nil
+	})
+	assert.Nil(t, err)
+
+	var titles []string
+	for _, r := range records {
+		titles = append(titles, r.Title)
+	}
+	sort.Strings(titles)
+	assert.Equal(t, []string{"index", "page1", "page11", "page2", "page3"}, titles)
+}
+
+// Test_Extract_Errors_Integration depends on the startup of the attached `test_data/Dockerfile` container
+// failing pages are skipped, the records of the other pages are returned along with an error
+func Test_Extract_Errors_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	errNoIndex := errors.New("index page is not extracted")
+	titles, err := Extract(context.Background(), getURL(getBaseURLIndex()), func(p *Page) (string, error) {
+		title := pageTitle(p.Node)
+		if title == "index" {
+			return "", errNoIndex
+		}
+		return title, nil
+	})
+	assert.True(t, errors.Is(err, errNoIndex))
+
+	sort.Strings(titles)
+	assert.Equal(t, []string{"page1", "page11", "page2", "page3"}, titles)
+}
+
+func Test_Extract_NilBase(t *testing.T) {
+	var base *url.URL
+	_, err := Extract(context.Background(), base, func(p *Page) (string, error) {
+		return "", nil
+	})
+	assert.NotNil(t, err)
+}
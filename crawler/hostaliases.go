@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HostAliases maps the host:port of crawled servers to the address the
+// connections to them are made to instead, like a hosts file scoped to
+// the crawler (see WithHostAliases). The requests keep the host of their
+// URL, e.g. to crawl a site before its DNS records exist or a given
+// deployment of a blue/green setup. It implements flag.Value so that it
+// can be set with a repeated flag
+type HostAliases map[string]string
+
+func (a HostAliases) String() string {
+	aliases := make([]string, 0, len(a))
+	for hostPort, address := range a {
+		host, port, _ := net.SplitHostPort(hostPort)
+		aliases = append(aliases, host+":"+port+":"+address)
+	}
+	sort.Strings(aliases)
+	return strings.Join(aliases, ",")
+}
+
+// Set adds an alias in the host:port:address syntax of the curl --resolve
+// option (e.g. `my-web-site.com:443:10.0.0.12`), an IPv6 address can be
+// enclosed in brackets. Like for curl, the `*` host aliases all the hosts
+// on the port that have no alias of their own
+func (a HostAliases) Set(value string) error {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 || parts[0] == "" {
+		return fmt.Errorf("invalid alias %q, expected host:port:address", value)
+	}
+	if port, err := strconv.Atoi(parts[1]); err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port in alias %q", value)
+	}
+	address := strings.TrimSuffix(strings.TrimPrefix(parts[2], "["), "]")
+	if address == "" {
+		return fmt.Errorf("invalid alias %q, expected host:port:address", value)
+	}
+	a[net.JoinHostPort(strings.ToLower(parts[0]), parts[1])] = address
+	return nil
+}
+
+// dialer wraps dial so that the connections to an aliased host:port are made
+// to its address, on the same port
+func (a HostAliases) dialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			if address, ok := a[net.JoinHostPort(strings.ToLower(host), port)]; ok {
+				addr = net.JoinHostPort(address, port)
+			} else if address, ok := a[net.JoinHostPort("*", port)]; ok {
+				addr = net.JoinHostPort(address, port)
+			}
+		}
+		return dial(ctx, network, addr)
+	}
+}
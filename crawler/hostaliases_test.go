@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestHostAliases_Set(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		want    HostAliases
+		wantErr bool
+	}{
+		"ipv4": {
+			value: "my-web-site.com:443:10.0.0.12",
+			want:  HostAliases{"my-web-site.com:443": "10.0.0.12"},
+		},
+		"ipv6": {
+			value: "My-Web-Site.com:80:[::1]",
+			want:  HostAliases{"my-web-site.com:80": "::1"},
+		},
+		"host_name": {
+			value: "my-web-site.com:443:green.my-web-site.com",
+			want:  HostAliases{"my-web-site.com:443": "green.my-web-site.com"},
+		},
+		"wildcard": {
+			value: "*:443:10.0.0.12",
+			want:  HostAliases{"*:443": "10.0.0.12"},
+		},
+		"missing_address": {
+			value:   "my-web-site.com:443",
+			wantErr: true,
+		},
+		"invalid_port": {
+			value:   "my-web-site.com:https:10.0.0.12",
+			wantErr: true,
+		},
+		"empty_address": {
+			value:   "my-web-site.com:443:",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			aliases := HostAliases{}
+			err := aliases.Set(tt.value)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, aliases)
+		})
+	}
+}
+
+func TestHostAliases_String(t *testing.T) {
+	aliases := HostAliases{"my-web-site.com:443": "10.0.0.12", "[::1]:8080": "10.0.0.13"}
+	assert.Equal(t, "::1:8080:10.0.0.13,my-web-site.com:443:10.0.0.12", aliases.String())
+}
+
+func Test_crawler_CrawlPages_WithHostAliases(t *testing.T) {
+	var hosts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		fmt.Fprint(w, linksPage("index"))
+	}))
+	defer srv.Close()
+	port := getURL(srv.URL).Port()
+
+	aliases := HostAliases{}
+	assert.Nil(t, aliases.Set("my-web-site.test:"+port+":127.0.0.1"))
+	var visited []string
+	err := NewCrawler(WithHostAliases(aliases)).Crawl(context.Background(), getURL("http://my-web-site.test:"+port+"/index.html"), func(u *url.URL, _ *html.Node) {
+		visited = append(visited, u.String())
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"http://my-web-site.test:" + port + "/index.html"}, visited)
+	assert.Equal(t, []string{"my-web-site.test:" + port}, hosts)
+}
+
+func TestHostAliases_dialer(t *testing.T) {
+	aliases := HostAliases{"my-web-site.com:443": "10.0.0.12", "*:443": "10.0.0.13"}
+	var dialed []string
+	dial := aliases.dialer(func(_ context.Context, _, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return nil, errors.New("not dialed")
+	})
+	for _, addr := range []string{"my-web-site.com:443", "other-web-site.com:443", "other-web-site.com:80"} {
+		_, err := dial(context.Background(), "tcp", addr)
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, []string{"10.0.0.12:443", "10.0.0.13:443", "other-web-site.com:80"}, dialed)
+}
@@ -0,0 +1,90 @@
+package crawler
+
+import (
+	"io"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HostStat are the counters of the requests to a host
+type HostStat struct {
+	// Host is the host of the requested pages
+	Host string
+	// Pages is the number of pages successfully fetched
+	Pages int
+	// Errors is the number of pages that could not be fetched
+	Errors int
+	// Bytes is the number of bytes of the fetched page bodies
+	Bytes int64
+	// Latency is the total duration of the page requests, reading of the bodies included
+	Latency time.Duration
+}
+
+// AvgLatency returns the average duration of the page requests to the host
+func (s HostStat) AvgLatency() time.Duration {
+	if n := s.Pages + s.Errors; n > 0 {
+		return s.Latency / time.Duration(n)
+	}
+	return 0
+}
+
+// HostStats counts the page requests of each host of the crawls using it (see
+// WithHostStats), so that slow or broken hosts of a multi-host crawl can be
+// identified. It is safe for concurrent use, the zero value is empty
+type HostStats struct {
+	mu    sync.Mutex
+	hosts map[string]*HostStat
+}
+
+// record counts a page request to the host of u started at start, a page
+// to be requeued is only counted once requeued
+func (s *HostStats) record(u *url.URL, start time.Time, bytes int64, err error) {
+	if _, requeued := err.(*retryAfterError); s == nil || requeued {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hosts == nil {
+		s.hosts = make(map[string]*HostStat)
+	}
+	stat, ok := s.hosts[u.Host]
+	if !ok {
+		stat = &HostStat{Host: u.Host}
+		s.hosts[u.Host] = stat
+	}
+	if err != nil {
+		stat.Errors++
+	} else {
+		stat.Pages++
+	}
+	stat.Bytes += bytes
+	stat.Latency += time.Since(start)
+}
+
+// Results returns the stats of each host, sorted by host
+func (s *HostStats) Results() []HostStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]HostStat, 0, len(s.hosts))
+	for _, stat := range s.hosts {
+		results = append(results, *stat)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Host < results[j].Host
+	})
+	return results
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
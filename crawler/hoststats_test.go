@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestHostStat_AvgLatency(t *testing.T) {
+	assert.Equal(t, time.Duration(0), HostStat{}.AvgLatency())
+	assert.Equal(t, 2*time.Second, HostStat{Pages: 2, Errors: 1, Latency: 6 * time.Second}.AvgLatency())
+}
+
+func Test_crawler_CrawlSites_WithHostStats(t *testing.T) {
+	index := linksPage("index", "page1.html")
+	page1 := linksPage("page1")
+	site := newTestSite(t, map[string]string{
+		"/index.html": index,
+		"/page1.html": page1,
+	})
+	// the broken site closes every connection
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		assert.Nil(t, err)
+		conn.Close()
+	}))
+	defer broken.Close()
+
+	stats := &HostStats{}
+	bases := []*url.URL{getURL(site.URL + "/index.html"), getURL(broken.URL + "/index.html")}
+	_, err := NewCrawler(WithHostStats(stats)).CrawlSites(context.Background(), bases, func(*url.URL, *html.Node) {})
+	assert.Nil(t, err)
+
+	results := stats.Results()
+	assert.Len(t, results, 2)
+	for _, r := range results {
+		switch r.Host {
+		case getURL(site.URL).Host:
+			assert.Equal(t, 2, r.Pages)
+			assert.Equal(t, 0, r.Errors)
+			assert.Equal(t, int64(len(index)+len(page1)), r.Bytes)
+		case getURL(broken.URL).Host:
+			assert.Equal(t, 0, r.Pages)
+			assert.Equal(t, 1, r.Errors)
+			assert.Equal(t, int64(0), r.Bytes)
+		default:
+			t.Errorf("unexpected host %s", r.Host)
+		}
+		assert.Greater(t, int64(r.Latency), int64(0))
+	}
+}
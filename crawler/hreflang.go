@@ -0,0 +1,203 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxAuditPageSize is the maximum size of the pages fetched by the reports (e.g. the
+// hreflang alternates that were not crawled)
+const maxAuditPageSize = 10 << 20
+
+// hreflangAlternate is an alternate link of a page: the URL of its version in lang
+type hreflangAlternate struct {
+	lang string
+	url  string
+}
+
+// hreflangPage is the status and the alternates of a crawled or fetched page
+type hreflangPage struct {
+	status     int
+	alternates []hreflangAlternate
+	// err is the error fetching the page
+	err error
+}
+
+// hreflang is the Report built by Hreflang
+type hreflang struct {
+	ctx    context.Context
+	client *http.Client
+
+	mut      sync.Mutex
+	findings []Finding
+	// pages are the crawled pages, by normalized URL
+	pages map[string]*hreflangPage
+}
+
+// Hreflang builds a Report auditing the hreflang alternate links of the crawled HTML
+// pages: it flags the pages without alternate for themselves and, once the crawl is
+// done, the alternates that are not answered with a 200 status code or that do not
+// link back to the page. The alternates that were not crawled (e.g. hosted on other
+// domains) are fetched with client, e.g. the one built by NewHTTPClient, without
+// following the redirects, and with ctx, Finalize returning its error once it is
+// done. A nil client means http.DefaultClient
+func Hreflang(ctx context.Context, client *http.Client) Report {
+	// a redirected alternate is reported rather than followed
+	return &hreflang{ctx: ctx, client: auditClient(client, true), pages: make(map[string]*hreflangPage)}
+}
+
+func (r *hreflang) Consume(p *Page) {
+	page := &hreflangPage{status: p.StatusCode}
+	var findings []Finding
+	if isHTMLPage(p) {
+		var invalid []string
+		page.alternates, invalid = getHreflangAlternates(p)
+		for _, href := range invalid {
+			findings = append(findings, Finding{URL: p.URL.String(), Validator: "hreflang", Message: fmt.Sprintf("invalid hreflang alternate URL %q", href), Severity: SeverityError})
+		}
+		if len(page.alternates) > 0 && !page.links(Normalize(p.URL).String()) {
+			findings = append(findings, Finding{URL: p.URL.String(), Validator: "hreflang", Message: "missing self-referencing hreflang alternate", Severity: SeverityWarning})
+		}
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.findings = append(r.findings, findings...)
+	r.pages[Normalize(p.URL).String()] = page
+}
+
+func (r *hreflang) Finalize() ([]Finding, error) {
+	r.mut.Lock()
+	findings := append([]Finding(nil), r.findings...)
+	// the crawled pages are not modified once consumed
+	pages := make(map[string]*hreflangPage, len(r.pages))
+	for u, page := range r.pages {
+		pages[u] = page
+	}
+	r.mut.Unlock()
+
+	crawled := make([]string, 0, len(pages))
+	for u, page := range pages {
+		if page.status >= http.StatusOK && page.status < http.StatusMultipleChoices {
+			crawled = append(crawled, u)
+		}
+	}
+	sort.Strings(crawled)
+	// the alternates are fetched once all the crawled pages are known,
+	// without holding the lock
+	for _, u := range crawled {
+		for _, alternate := range pages[u].alternates {
+			if _, ok := pages[alternate.url]; !ok {
+				pages[alternate.url] = r.fetch(alternate.url)
+				if r.ctx.Err() != nil {
+					return nil, r.ctx.Err()
+				}
+			}
+		}
+	}
+	for _, u := range crawled {
+		for _, alternate := range pages[u].alternates {
+			if alternate.url == u {
+				continue
+			}
+			if message := checkAlternate(u, alternate, pages[alternate.url]); message != "" {
+				findings = append(findings, Finding{URL: u, Validator: "hreflang", Message: message, Severity: SeverityError})
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].URL != findings[j].URL {
+			return findings[i].URL < findings[j].URL
+		}
+		return findings[i].Message < findings[j].Message
+	})
+	return findings, nil
+}
+
+// checkAlternate describes why alternate of the page at u, answered with page, is
+// broken, an empty string when it is answered with a 200 status code and links back to u
+func checkAlternate(u string, alternate hreflangAlternate, page *hreflangPage) string {
+	switch {
+	case page.err != nil:
+		return fmt.Sprintf("broken hreflang alternate %s (%s): %v", alternate.url, alternate.lang, page.err)
+	case page.status != http.StatusOK:
+		return fmt.Sprintf("broken hreflang alternate %s (%s): status %d", alternate.url, alternate.lang, page.status)
+	case !page.links(u):
+		return fmt.Sprintf("hreflang alternate %s (%s) does not link back", alternate.url, alternate.lang)
+	}
+	return ""
+}
+
+// fetch gets the page at u and its alternates
+func (r *hreflang) fetch(u string) *hreflangPage {
+	resp, err := doRequest(r.ctx, r.client, http.MethodGet, u)
+	if err != nil {
+		return &hreflangPage{err: err}
+	}
+	defer resp.Body.Close()
+	page := &hreflangPage{status: resp.StatusCode}
+	if resp.StatusCode != http.StatusOK {
+		return page
+	}
+	node, err := html.Parse(io.LimitReader(resp.Body, maxAuditPageSize))
+	if err != nil {
+		return &hreflangPage{err: err}
+	}
+	page.alternates, _ = getHreflangAlternates(&Page{URL: resp.Request.URL, Node: node})
+	return page
+}
+
+// links reports whether the page has an alternate pointing to the normalized URL u
+func (p *hreflangPage) links(u string) bool {
+	for _, alternate := range p.alternates {
+		if alternate.url == u {
+			return true
+		}
+	}
+	return false
+}
+
+// getHreflangAlternates returns the hreflang alternate links of a page, with
+// their normalized absolute URL, and the hrefs that cannot be resolved
+func getHreflangAlternates(p *Page) ([]hreflangAlternate, []string) {
+	var alternates []hreflangAlternate
+	var invalid []string
+	resolver := NewLinkResolver(p.URL)
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "link" && hasRel(attr(node, "rel"), "alternate") {
+			if lang := strings.TrimSpace(attr(node, "hreflang")); lang != "" {
+				href := strings.TrimSpace(attr(node, "href"))
+				u, err := resolver.Resolve(href)
+				if err != nil || href == "" {
+					invalid = append(invalid, href)
+				} else {
+					alternates = append(alternates, hreflangAlternate{lang: lang, url: Normalize(u).String()})
+				}
+			}
+		}
+		for n := node.FirstChild; n != nil; n = n.NextSibling {
+			walk(n)
+		}
+	}
+	if p.Node != nil {
+		walk(p.Node)
+	}
+	return alternates, invalid
+}
+
+// hasRel reports whether the rel attribute of a link holds the given link type
+func hasRel(rel, linkType string) bool {
+	for _, token := range strings.Fields(rel) {
+		if strings.EqualFold(token, linkType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHreflang(t *testing.T) {
+	alternates := func(hrefs ...string) string {
+		page := "<html><head>"
+		for i := 0; i < len(hrefs); i += 2 {
+			page += fmt.Sprintf(`<link rel="alternate" hreflang="%s" href="%s">`, hrefs[i], hrefs[i+1])
+		}
+		return page + "</head></html>"
+	}
+	// the alternates hosted on another domain are fetched
+	var other *httptest.Server
+	other = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/de/":
+			fmt.Fprint(w, alternates("en", "https://my-web-site.com/en/", "de", other.URL+"/de/"))
+		case "/it/":
+			fmt.Fprint(w, alternates("it", other.URL+"/it/"))
+		case "/es/":
+			http.Redirect(w, r, "/es/index.html", http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer other.Close()
+
+	const en, fr, pt = "https://my-web-site.com/en/", "https://my-web-site.com/fr/", "https://my-web-site.com/pt/"
+	r := Hreflang(context.Background(), other.Client())
+	r.Consume(newPage(t, en, 200, nil, alternates("en", "/en/", "fr", "/fr/", "pt", "/pt/", "x-default", "/en/",
+		"de", other.URL+"/de/", "it", other.URL+"/it/", "es", other.URL+"/es/", "nl", other.URL+"/nl/")))
+	r.Consume(newPage(t, fr, 200, nil, alternates("en", "HTTPS://My-Web-Site.com:443/en/", "fr", "/fr/")))
+	r.Consume(newPage(t, pt, 404, nil, ""))
+	// a page without alternate for itself
+	r.Consume(newPage(t, "https://my-web-site.com/no-self", 200, nil, alternates("en", "/en/", "xx", "http://[::1")))
+
+	findings, err := r.Finalize()
+	assert.Nil(t, err)
+	assert.Equal(t, []Finding{
+		{URL: en, Validator: "hreflang", Message: fmt.Sprintf("broken hreflang alternate %s/es/ (es): status 301", other.URL), Severity: SeverityError},
+		{URL: en, Validator: "hreflang", Message: fmt.Sprintf("broken hreflang alternate %s/nl/ (nl): status 404", other.URL), Severity: SeverityError},
+		{URL: en, Validator: "hreflang", Message: "broken hreflang alternate https://my-web-site.com/pt/ (pt): status 404", Severity: SeverityError},
+		{URL: en, Validator: "hreflang", Message: fmt.Sprintf("hreflang alternate %s/it/ (it) does not link back", other.URL), Severity: SeverityError},
+		{URL: "https://my-web-site.com/no-self", Validator: "hreflang", Message: "hreflang alternate https://my-web-site.com/en/ (en) does not link back", Severity: SeverityError},
+		{URL: "https://my-web-site.com/no-self", Validator: "hreflang", Message: `invalid hreflang alternate URL "http://[::1"`, Severity: SeverityError},
+		{URL: "https://my-web-site.com/no-self", Validator: "hreflang", Message: "missing self-referencing hreflang alternate", Severity: SeverityWarning},
+	}, findings)
+}
+
+func TestHreflang_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the run is interrupted while the first alternate is fetched
+		requests++
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	r := Hreflang(ctx, srv.Client())
+	r.Consume(newPage(t, srv.URL+"/en/", 200, nil, fmt.Sprintf(`<link rel="alternate" hreflang="en" href="/en/">
+		<link rel="alternate" hreflang="de" href="%[1]s/de/"><link rel="alternate" hreflang="fr" href="%[1]s/fr/">`, srv.URL)))
+	findings, err := r.Finalize()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, findings)
+	// the following alternates are not fetched
+	assert.Equal(t, 1, requests)
+}
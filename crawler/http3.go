@@ -0,0 +1,172 @@
+package crawler
+
+import (
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultAltSvcMaxAge is the freshness of an Alt-Svc alternative without
+	// ma parameter (RFC 7838, section 3.1)
+	defaultAltSvcMaxAge = 24 * time.Hour
+	// http3FallbackDelay is the time an origin whose HTTP/3 request failed is
+	// reached over TCP only, whatever it advertises
+	http3FallbackDelay = 5 * time.Minute
+)
+
+// altSvc is the HTTP/3 alternative advertised by an origin
+type altSvc struct {
+	// port is the UDP port of the alternative, on the host of the origin
+	port    string
+	expires time.Time
+	// brokenUntil is the time until which the alternative is not used,
+	// after a failed request
+	brokenUntil time.Time
+}
+
+// http3Transport is a http.RoundTripper issuing the requests over HTTP/3, through
+// h3, to the origins advertising it with an Alt-Svc header, and over tcp, the
+// transport of HTTP/2 and HTTP/1.1, otherwise. A request failing over HTTP/3 is
+// retried over tcp, the origin being reached over tcp for http3FallbackDelay
+type http3Transport struct {
+	tcp http.RoundTripper
+	h3  http.RoundTripper
+
+	mu sync.Mutex
+	// alternatives are the HTTP/3 alternatives, by origin (scheme://host:port)
+	alternatives map[string]*altSvc
+}
+
+func newHTTP3Transport(tcp, h3 http.RoundTripper) *http3Transport {
+	return &http3Transport{tcp: tcp, h3: h3, alternatives: make(map[string]*altSvc)}
+}
+
+func (t *http3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	origin := altSvcOrigin(req)
+	// only the requests without body can be retried over tcp
+	if port, ok := t.alternative(origin); ok && (req.Body == nil || req.Body == http.NoBody) {
+		h3req := req.Clone(req.Context())
+		if !strings.HasSuffix(origin, ":"+port) {
+			// the alternative listens on another port, the Host
+			// header still names the origin
+			if h3req.Host == "" {
+				h3req.Host = req.URL.Host
+			}
+			h3req.URL.Host = net.JoinHostPort(req.URL.Hostname(), port)
+		}
+		resp, err := t.h3.RoundTrip(h3req)
+		if err == nil {
+			resp.Request = req
+			t.update(origin, resp.Header)
+			return resp, nil
+		}
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		log.Warnf("HTTP/3 request of %s failed, falling back to TCP - %v", req.URL, err)
+		t.markBroken(origin)
+	}
+	resp, err := t.tcp.RoundTrip(req)
+	if err == nil {
+		t.update(origin, resp.Header)
+	}
+	return resp, err
+}
+
+// alternative returns the port of the fresh HTTP/3 alternative of origin
+func (t *http3Transport) alternative(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	alt, ok := t.alternatives[origin]
+	now := time.Now()
+	if !ok || alt.port == "" || now.After(alt.expires) || now.Before(alt.brokenUntil) {
+		return "", false
+	}
+	return alt.port, true
+}
+
+// update records the HTTP/3 alternative that origin advertises in header
+func (t *http3Transport) update(origin string, header http.Header) {
+	values := header.Values("Alt-Svc")
+	if origin == "" || len(values) == 0 {
+		return
+	}
+	port, maxAge, ok := parseAltSvc(strings.Join(values, ","))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	alt, known := t.alternatives[origin]
+	if !known {
+		alt = &altSvc{}
+		t.alternatives[origin] = alt
+	}
+	if !ok {
+		// the origin cleared its alternatives, or advertises no HTTP/3 one
+		alt.port = ""
+		return
+	}
+	alt.port, alt.expires = port, time.Now().Add(maxAge)
+}
+
+// markBroken makes origin reached over tcp for http3FallbackDelay
+func (t *http3Transport) markBroken(origin string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if alt, ok := t.alternatives[origin]; ok {
+		alt.brokenUntil = time.Now().Add(http3FallbackDelay)
+	}
+}
+
+// altSvcOrigin returns the origin of an https request, HTTP/3 requiring TLS,
+// an empty string for the other requests
+func altSvcOrigin(req *http.Request) string {
+	if req.URL.Scheme != "https" {
+		return ""
+	}
+	port := req.URL.Port()
+	if port == "" {
+		port = defaultPorts["https"]
+	}
+	return "https://" + net.JoinHostPort(strings.ToLower(req.URL.Hostname()), port)
+}
+
+// parseAltSvc returns the port and the max age of the first HTTP/3 alternative
+// of an Alt-Svc header value (RFC 7838) on the host of the origin, the
+// alternatives on other hosts are not used. It reports false when the value
+// holds no such alternative or clears the alternatives
+func parseAltSvc(value string) (string, time.Duration, bool) {
+	for _, entry := range strings.Split(value, ",") {
+		params := strings.Split(entry, ";")
+		proto, authority, found := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if strings.TrimSpace(params[0]) == "clear" {
+			return "", 0, false
+		}
+		if !found || proto != "h3" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(strings.Trim(authority, `"`))
+		if err != nil || host != "" {
+			continue
+		}
+		if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+			continue
+		}
+		maxAge := defaultAltSvcMaxAge
+		for _, param := range params[1:] {
+			if name, v, _ := strings.Cut(strings.TrimSpace(param), "="); name == "ma" {
+				if seconds, err := strconv.Atoi(strings.Trim(v, `"`)); err == nil && seconds >= 0 {
+					maxAge = time.Duration(seconds) * time.Second
+				}
+			}
+		}
+		return port, maxAge, true
+	}
+	return "", 0, false
+}
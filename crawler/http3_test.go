@@ -0,0 +1,150 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_parseAltSvc(t *testing.T) {
+	tests := map[string]struct {
+		value  string
+		port   string
+		maxAge time.Duration
+		ok     bool
+	}{
+		"h3":               {value: `h3=":443"`, port: "443", maxAge: defaultAltSvcMaxAge, ok: true},
+		"max_age":          {value: `h3=":8443"; ma=3600`, port: "8443", maxAge: time.Hour, ok: true},
+		"first_h3":         {value: `h2=":443", h3-29=":443", h3=":4433"; ma=60; persist=1, h3=":443"`, port: "4433", maxAge: time.Minute, ok: true},
+		"other_host":       {value: `h3="alt.my-web-site.com:443"`},
+		"other_host_first": {value: `h3="alt.my-web-site.com:443", h3=":443"`, port: "443", maxAge: defaultAltSvcMaxAge, ok: true},
+		"invalid_port":     {value: `h3=":http"`},
+		"clear":            {value: `clear`},
+		"no_h3":            {value: `h2=":443"`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			port, maxAge, ok := parseAltSvc(tt.value)
+			assert.Equal(t, tt.port, port)
+			assert.Equal(t, tt.maxAge, maxAge)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func Test_http3Transport(t *testing.T) {
+	var mu sync.Mutex
+	var protos []string
+	h3Err := errors.New("no route to host")
+	var failH3 bool
+	var h3Hosts []string
+	respond := func(req *http.Request, proto string) *http.Response {
+		mu.Lock()
+		defer mu.Unlock()
+		protos = append(protos, proto)
+		header := http.Header{"Alt-Svc": {`h3=":4433"`}}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}
+	}
+	tcp := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return respond(req, "tcp"), nil
+	})
+	h3 := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		h3Hosts = append(h3Hosts, req.URL.Host+" "+req.Host)
+		fail := failH3
+		mu.Unlock()
+		if fail {
+			return nil, h3Err
+		}
+		return respond(req, "h3"), nil
+	})
+	transport := newHTTP3Transport(tcp, h3)
+	get := func(u string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, u, nil)
+		resp, err := transport.RoundTrip(req)
+		assert.Nil(t, err)
+		// the response is the one of the request of the caller
+		assert.Equal(t, req, resp.Request)
+		return resp
+	}
+
+	get("https://my-web-site.com/")
+	get("https://my-web-site.com/page")
+	// http requests are never upgraded
+	get("http://my-web-site.com/")
+	get("http://my-web-site.com/")
+	// a failed HTTP/3 request is retried over tcp, and the origin no longer upgraded
+	failH3 = true
+	get("https://my-web-site.com/other")
+	get("https://my-web-site.com/other")
+	assert.Equal(t, []string{"tcp", "h3", "tcp", "tcp", "tcp", "tcp"}, protos)
+	// the alternative is reached on its port, the Host header naming the origin
+	assert.Equal(t, []string{"my-web-site.com:4433 my-web-site.com", "my-web-site.com:4433 my-web-site.com"}, h3Hosts)
+}
+
+func Test_http3Transport_Clear(t *testing.T) {
+	var protos []string
+	altSvc := `h3=":443"`
+	tcp := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		protos = append(protos, "tcp")
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Alt-Svc": {altSvc}}, Body: http.NoBody}, nil
+	})
+	h3 := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		protos = append(protos, "h3 "+req.URL.Host)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Alt-Svc": {altSvc}}, Body: http.NoBody}, nil
+	})
+	transport := newHTTP3Transport(tcp, h3)
+	for i := 0; i < 4; i++ {
+		if i == 2 {
+			altSvc = "clear"
+		}
+		req, _ := http.NewRequest(http.MethodGet, "https://my-web-site.com/", nil)
+		_, err := transport.RoundTrip(req)
+		assert.Nil(t, err)
+	}
+	// the alternative on the port of the origin keeps its URL
+	assert.Equal(t, []string{"tcp", "h3 my-web-site.com", "h3 my-web-site.com", "tcp"}, protos)
+}
+
+func Test_crawler_CrawlPages_WithHTTP3(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", `h3=":443"; ma=60`)
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, linksPage("index", "/a.html", "/b.html"))
+		default:
+			fmt.Fprint(w, linksPage(r.URL.Path))
+		}
+	})
+	srv := httptest.NewTLSServer(handler)
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var h3Paths []string
+	h3 := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		h3Paths = append(h3Paths, req.URL.Path)
+		mu.Unlock()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		resp := rec.Result()
+		resp.Proto, resp.ProtoMajor, resp.ProtoMinor = "HTTP/3.0", 3, 0
+		return resp, nil
+	})
+	var pages []*Page
+	err := NewCrawler(WithHTTPClient(srv.Client()), WithHTTP3(h3), WithMaxConcurrency(1)).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+		mu.Lock()
+		defer mu.Unlock()
+		pages = append(pages, p)
+	})
+	assert.Nil(t, err)
+	assert.Len(t, pages, 3)
+	// the links of the first page, answered over TCP, are fetched over HTTP/3
+	assert.ElementsMatch(t, []string{"/a.html", "/b.html"}, h3Paths)
+}
@@ -0,0 +1,221 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxManifestSize is the maximum size of the web manifests read by the Icons report
+const maxManifestSize = 1 << 20
+
+// iconRef is an icon or web manifest referenced by a page, kind being one of
+// icon, apple-touch-icon, manifest or favicon (the implicit /favicon.ico of
+// the pages without icon link)
+type iconRef struct {
+	kind string
+	url  string
+}
+
+// icons is the Report built by Icons
+type icons struct {
+	ctx    context.Context
+	client *http.Client
+
+	mut      sync.Mutex
+	findings []Finding
+	// refs are the pages of each referenced icon and manifest
+	refs map[iconRef][]string
+}
+
+// Icons builds a Report auditing the favicons, apple-touch icons and web manifests
+// referenced by the HTML pages answered with a 2xx status code: it flags the pages
+// without apple-touch icon or web manifest and, once the crawl is done, the pages
+// whose icons, manifests or manifest icons cannot be fetched with client. The pages
+// without icon link are flagged if the /favicon.ico of their host is missing. client is
+// e.g. the one built by NewHTTPClient, a nil client means http.DefaultClient. The
+// resources are fetched with ctx, Finalize returning its error once it is done
+func Icons(ctx context.Context, client *http.Client) Report {
+	return &icons{ctx: ctx, client: auditClient(client, false), refs: make(map[iconRef][]string)}
+}
+
+func (r *icons) Consume(p *Page) {
+	if p.StatusCode < http.StatusOK || p.StatusCode >= http.StatusMultipleChoices || !isHTMLPage(p) {
+		return
+	}
+	links := getIconLinks(p.Node)
+	resolver := NewLinkResolver(p.URL)
+	var findings []Finding
+	var refs []iconRef
+	for _, link := range links {
+		u, err := resolver.Resolve(link.url)
+		if err != nil || link.url == "" {
+			findings = append(findings, Finding{URL: p.URL.String(), Validator: "icons", Message: fmt.Sprintf("invalid %s URL %q", link.kind, link.url), Severity: SeverityError})
+			continue
+		}
+		// the inline icons need no request
+		if u.Scheme == "data" {
+			continue
+		}
+		refs = append(refs, iconRef{kind: link.kind, url: u.String()})
+	}
+	if !hasIconKind(links, "icon") {
+		favicon := url.URL{Scheme: p.URL.Scheme, Host: p.URL.Host, Path: "/favicon.ico"}
+		refs = append(refs, iconRef{kind: "favicon", url: favicon.String()})
+	}
+	if !hasIconKind(links, "apple-touch-icon") {
+		findings = append(findings, Finding{URL: p.URL.String(), Validator: "icons", Message: "missing apple-touch-icon", Severity: SeverityInfo})
+	}
+	if !hasIconKind(links, "manifest") {
+		findings = append(findings, Finding{URL: p.URL.String(), Validator: "icons", Message: "missing web manifest", Severity: SeverityInfo})
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.findings = append(r.findings, findings...)
+	for _, ref := range refs {
+		r.refs[ref] = append(r.refs[ref], p.URL.String())
+	}
+}
+
+func (r *icons) Finalize() ([]Finding, error) {
+	r.mut.Lock()
+	findings := append([]Finding(nil), r.findings...)
+	refs := make(map[iconRef][]string, len(r.refs))
+	for ref, pages := range r.refs {
+		refs[ref] = append([]string(nil), pages...)
+	}
+	// the resources are fetched without holding the lock
+	r.mut.Unlock()
+	// a resource shared by several pages is only fetched once
+	for ref, pages := range refs {
+		messages := r.check(ref)
+		if r.ctx.Err() != nil {
+			return nil, r.ctx.Err()
+		}
+		for _, message := range messages {
+			for _, page := range pages {
+				findings = append(findings, Finding{URL: page, Validator: "icons", Message: message, Severity: SeverityError})
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].URL != findings[j].URL {
+			return findings[i].URL < findings[j].URL
+		}
+		return findings[i].Message < findings[j].Message
+	})
+	return findings, nil
+}
+
+// check fetches the resource of ref and describes its problems
+func (r *icons) check(ref iconRef) []string {
+	switch ref.kind {
+	case "manifest":
+		return r.checkManifest(ref.url)
+	case "favicon":
+		if problem := checkResource(r.ctx, r.client, ref.url); problem != "" {
+			return []string{fmt.Sprintf("missing favicon, no icon link and %s is broken (%s)", ref.url, problem)}
+		}
+	default:
+		if problem := checkResource(r.ctx, r.client, ref.url); problem != "" {
+			return []string{fmt.Sprintf("broken %s %s (%s)", ref.kind, ref.url, problem)}
+		}
+	}
+	return nil
+}
+
+// checkManifest fetches the web manifest at u and describes its problems:
+// the manifest must be a JSON object whose icons can be fetched
+func (r *icons) checkManifest(u string) []string {
+	resp, err := doRequest(r.ctx, r.client, http.MethodGet, u)
+	if err != nil {
+		return []string{fmt.Sprintf("broken manifest %s (%s)", u, err)}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return []string{fmt.Sprintf("broken manifest %s (status %d)", u, resp.StatusCode)}
+	}
+	var manifest struct {
+		Icons []struct {
+			Src string `json:"src"`
+		} `json:"icons"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&manifest); err != nil {
+		return []string{fmt.Sprintf("invalid manifest %s (%s)", u, err)}
+	}
+	base, err := url.Parse(u)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid manifest URL %q", u)}
+	}
+	var problems []string
+	for _, icon := range manifest.Icons {
+		// the icons of a manifest are relative to the manifest URL
+		src, err := NewLinkResolver(base).Resolve(icon.Src)
+		if err != nil || strings.TrimSpace(icon.Src) == "" {
+			problems = append(problems, fmt.Sprintf("invalid manifest icon URL %q in %s", icon.Src, u))
+			continue
+		}
+		if src.Scheme == "data" {
+			continue
+		}
+		if problem := checkResource(r.ctx, r.client, src.String()); problem != "" {
+			problems = append(problems, fmt.Sprintf("broken manifest icon %s in %s (%s)", src, u, problem))
+		}
+	}
+	return problems
+}
+
+// getIconLinks returns the icon, apple-touch-icon and manifest links of a page,
+// the href of each link being kept unresolved
+func getIconLinks(node *html.Node) []iconRef {
+	var links []iconRef
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "link" {
+			if kind := iconKind(attr(node, "rel")); kind != "" {
+				links = append(links, iconRef{kind: kind, url: strings.TrimSpace(attr(node, "href"))})
+			}
+		}
+		for n := node.FirstChild; n != nil; n = n.NextSibling {
+			walk(n)
+		}
+	}
+	if node != nil {
+		walk(node)
+	}
+	return links
+}
+
+// iconKind returns the kind of a link given its rel attribute, an empty
+// string for the links that are neither icons nor manifests
+func iconKind(rel string) string {
+	for _, token := range strings.Fields(strings.ToLower(rel)) {
+		switch token {
+		case "icon":
+			return "icon"
+		case "apple-touch-icon", "apple-touch-icon-precomposed":
+			return "apple-touch-icon"
+		case "manifest":
+			return "manifest"
+		}
+	}
+	return ""
+}
+
+// hasIconKind reports whether one of links is of the given kind
+func hasIconKind(links []iconRef, kind string) bool {
+	for _, link := range links {
+		if link.kind == kind {
+			return true
+		}
+	}
+	return false
+}
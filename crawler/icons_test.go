@@ -0,0 +1,88 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIcons(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/favicon.png", "/apple-touch-icon.png", "/icons/192.png":
+			w.WriteHeader(http.StatusOK)
+		case "/site.webmanifest":
+			fmt.Fprint(w, `{"name": "My web site", "icons": [{"src": "icons/192.png"}, {"src": "icons/512.png"}]}`)
+		case "/broken.webmanifest":
+			fmt.Fprint(w, `<html></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	page := func(links string) string {
+		return fmt.Sprintf(`<html><head>%s</head></html>`, links)
+	}
+
+	r := Icons(context.Background(), srv.Client())
+	r.Consume(newPage(t, srv.URL+"/complete", 200, nil, page(`<link rel="icon" href="/favicon.png">
+		<link rel="apple-touch-icon" href="apple-touch-icon.png"><link rel="manifest" href="/site.webmanifest">`)))
+	r.Consume(newPage(t, srv.URL+"/inline", 200, nil, page(`<link rel="shortcut icon" href="data:image/png;base64,iVBORw0KGgo=">
+		<link rel="apple-touch-icon-precomposed" href="/missing.png"><link rel="manifest" href="/broken.webmanifest">`)))
+	r.Consume(newPage(t, srv.URL+"/no-icon", 200, nil, page(`<link rel="apple-touch-icon" href="/apple-touch-icon.png"><link rel="manifest" href="">`)))
+	r.Consume(newPage(t, srv.URL+"/bare", 200, nil, page("")))
+	// the pages that are not 2xx HTML pages are ignored
+	r.Consume(newPage(t, srv.URL+"/not-found", 404, nil, "<html></html>"))
+	r.Consume(newPage(t, srv.URL+"/data.json", 200, http.Header{"Content-Type": {"application/json"}}, ""))
+
+	findings, err := r.Finalize()
+	assert.Nil(t, err)
+	assert.Equal(t, []Finding{
+		{URL: srv.URL + "/bare", Validator: "icons", Message: "missing apple-touch-icon", Severity: SeverityInfo},
+		{URL: srv.URL + "/bare", Validator: "icons", Message: fmt.Sprintf("missing favicon, no icon link and %s/favicon.ico is broken (status 404)", srv.URL), Severity: SeverityError},
+		{URL: srv.URL + "/bare", Validator: "icons", Message: "missing web manifest", Severity: SeverityInfo},
+		{URL: srv.URL + "/complete", Validator: "icons", Message: fmt.Sprintf("broken manifest icon %s/icons/512.png in %s/site.webmanifest (status 404)", srv.URL, srv.URL), Severity: SeverityError},
+		{URL: srv.URL + "/inline", Validator: "icons", Message: fmt.Sprintf("broken apple-touch-icon %s/missing.png (status 404)", srv.URL), Severity: SeverityError},
+		{URL: srv.URL + "/inline", Validator: "icons", Message: fmt.Sprintf("invalid manifest %s/broken.webmanifest (invalid character '<' looking for beginning of value)", srv.URL), Severity: SeverityError},
+		{URL: srv.URL + "/no-icon", Validator: "icons", Message: `invalid manifest URL ""`, Severity: SeverityError},
+		{URL: srv.URL + "/no-icon", Validator: "icons", Message: fmt.Sprintf("missing favicon, no icon link and %s/favicon.ico is broken (status 404)", srv.URL), Severity: SeverityError},
+	}, findings)
+}
+
+func Test_iconKind(t *testing.T) {
+	tests := map[string]struct {
+		rel  string
+		want string
+	}{
+		"icon":         {rel: "icon", want: "icon"},
+		"shortcut":     {rel: "Shortcut Icon", want: "icon"},
+		"apple":        {rel: "apple-touch-icon-precomposed", want: "apple-touch-icon"},
+		"manifest":     {rel: "manifest", want: "manifest"},
+		"stylesheet":   {rel: "stylesheet"},
+		"partial_name": {rel: "mask-icon"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, iconKind(tt.rel))
+		})
+	}
+}
+
+func TestIcons_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the run is interrupted while the resource is fetched
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	r := Icons(ctx, srv.Client())
+	r.Consume(newPage(t, srv.URL+"/", 200, nil, `<link rel="manifest" href="/site.webmanifest">`))
+	findings, err := r.Finalize()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, findings)
+}
@@ -0,0 +1,169 @@
+package crawler
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// Link is an anchor found in a page along with the context
+// in which it appears
+type Link struct {
+	// Href is the raw value of the href attribute
+	Href string
+	// Text is the text of the anchor
+	Text string
+	// Sentence is the sentence surrounding the anchor in its
+	// closest block element (e.g. <p>, <li>, <td>)
+	Sentence string
+	// Path is the CSS path of the anchor in the document
+	// (e.g. `html > body > ul > li:nth-of-type(2) > a`)
+	Path string
+}
+
+// blockElements are the elements used to delimit the context of a link
+var blockElements = map[string]struct{}{
+	"address": {}, "article": {}, "aside": {}, "blockquote": {}, "body": {}, "dd": {}, "div": {},
+	"dt": {}, "figcaption": {}, "footer": {}, "h1": {}, "h2": {}, "h3": {}, "h4": {}, "h5": {},
+	"h6": {}, "header": {}, "li": {}, "main": {}, "nav": {}, "p": {}, "section": {}, "td": {}, "th": {},
+}
+
+// GetPageLinksWithContext retrieves all the anchors with an href attribute found
+// in a page along with their context. Differently from GetPageLinks an entry is
+// returned for every anchor, so the same href appears as many times as it is
+// linked in the page. Links are returned in document order
+func GetPageLinksWithContext(node *html.Node) []Link {
+	var links []Link
+	// nothing to retrieve for nil node
+	if node == nil {
+		return links
+	}
+	return getPageLinksWithContextRecursive(node, links)
+}
+
+func getPageLinksWithContextRecursive(node *html.Node, links []Link) []Link {
+	// check if we are in a <a></a> html element
+	if node.Type == html.ElementNode && node.Data == "a" {
+		for _, a := range node.Attr {
+			if a.Key == "href" {
+				links = append(links, Link{
+					Href:     a.Val,
+					Text:     collapseSpaces(nodeText(node)),
+					Sentence: surroundingSentence(node),
+					Path:     cssPath(node),
+				})
+				break
+			}
+		}
+	}
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		links = getPageLinksWithContextRecursive(n, links)
+	}
+	return links
+}
+
+// nodeText returns the concatenation of all the text nodes under node
+func nodeText(node *html.Node) string {
+	var b strings.Builder
+	writeNodeText(&b, node, nil, nil)
+	return b.String()
+}
+
+// writeNodeText writes into b the text under node. When target is found the
+// start and end offsets of its text in b are stored in bounds
+func writeNodeText(b *strings.Builder, node *html.Node, target *html.Node, bounds *[2]int) {
+	if node == target && bounds != nil {
+		bounds[0] = b.Len()
+		defer func() { bounds[1] = b.Len() }()
+	}
+	if node.Type == html.TextNode {
+		b.WriteString(node.Data)
+		return
+	}
+	if node.Type == html.ElementNode && (node.Data == "script" || node.Data == "style") {
+		return
+	}
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		writeNodeText(b, n, target, bounds)
+	}
+}
+
+// surroundingSentence returns the sentence containing the anchor in the text of
+// the closest block element ancestor of the anchor
+func surroundingSentence(anchor *html.Node) string {
+	block := anchor.Parent
+	for block != nil {
+		if block.Type == html.ElementNode {
+			if _, ok := blockElements[block.Data]; ok {
+				break
+			}
+		}
+		block = block.Parent
+	}
+	if block == nil {
+		return collapseSpaces(nodeText(anchor))
+	}
+
+	var b strings.Builder
+	var bounds [2]int
+	writeNodeText(&b, block, anchor, &bounds)
+	text := b.String()
+
+	// the sentence starts after the last terminator preceding the anchor
+	// and ends with the first terminator following it
+	start := strings.LastIndexAny(text[:bounds[0]], ".!?") + 1
+	end := len(text)
+	if i := strings.IndexAny(text[bounds[1]:], ".!?"); i >= 0 {
+		end = bounds[1] + i + 1
+	}
+	return collapseSpaces(text[start:end])
+}
+
+// cssPath returns the CSS path of an element node: the chain of tag names from the
+// document root to the element, disambiguated with :nth-of-type when the element
+// has siblings of the same type. An ancestor with an id attribute shortens the path
+func cssPath(node *html.Node) string {
+	var parts []string
+	for n := node; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if id := attr(n, "id"); id != "" {
+			parts = append(parts, fmt.Sprintf("%s#%s", n.Data, id))
+			break
+		}
+		part := n.Data
+		index, count := 0, 0
+		if n.Parent != nil {
+			for s := n.Parent.FirstChild; s != nil; s = s.NextSibling {
+				if s.Type == html.ElementNode && s.Data == n.Data {
+					count++
+					if s == n {
+						index = count
+					}
+				}
+			}
+		}
+		if count > 1 {
+			part = fmt.Sprintf("%s:nth-of-type(%d)", part, index)
+		}
+		parts = append(parts, part)
+	}
+	// reverse the parts, from the root to the node
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, " > ")
+}
+
+// attr returns the value of the key attribute of node, or an empty string
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// collapseSpaces trims s and replaces every sequence of white spaces with a single space
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"strings"
+	"testing"
+)
+
+func Test_GetPageLinksWithContext(t *testing.T) {
+	tests := map[string]struct {
+		htmlStr string
+		want    []Link
+	}{
+		"sentence_in_paragraph": {
+			htmlStr: `<html><body>
+						<p>Welcome. Read the <a href="/docs">docs</a> before starting! Enjoy.</p>
+					  </body></html>`,
+			want: []Link{
+				{Href: "/docs", Text: "docs", Sentence: "Read the docs before starting!", Path: "html > body > p > a"},
+			},
+		},
+		"same_href_twice_in_list": {
+			htmlStr: `<html><body>
+						<ul>
+							<li><a href="page1.html">first</a></li>
+							<li>again <a href="page1.html">first</a></li>
+						</ul>
+					  </body></html>`,
+			want: []Link{
+				{Href: "page1.html", Text: "first", Sentence: "first", Path: "html > body > ul > li:nth-of-type(1) > a"},
+				{Href: "page1.html", Text: "first", Sentence: "again first", Path: "html > body > ul > li:nth-of-type(2) > a"},
+			},
+		},
+		"path_shortened_by_id": {
+			htmlStr: `<html><body>
+						<div id="footer"><p><a href="/contact"><b>contact</b> us</a></p></div>
+					  </body></html>`,
+			want: []Link{
+				{Href: "/contact", Text: "contact us", Sentence: "contact us", Path: "div#footer > p > a"},
+			},
+		},
+		"anchor_without_href_ignored": {
+			htmlStr: `<html><body><p><a name="top">top</a></p></body></html>`,
+			want:    nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			node, err := html.Parse(strings.NewReader(tt.htmlStr))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, GetPageLinksWithContext(node))
+		})
+	}
+}
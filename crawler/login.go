@@ -0,0 +1,237 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// LoginForm describes the login form of a site protected by a CSRF token
+// (see FormLogin)
+type LoginForm struct {
+	// URL is the page of the form the CSRF token is read from
+	URL *url.URL
+	// Action is the URL the form is posted to, URL when nil
+	Action *url.URL
+	// CSRFSelector selects the element holding the CSRF token in its value
+	// attribute, or content attribute for a meta, e.g. `input[name=csrf_token]`
+	// or `meta[name=csrf-token]`. It is a compound selector made of an optional
+	// tag name and #id, .class, [attr] or [attr=value] conditions. No selector
+	// means no token is posted
+	CSRFSelector string
+	// CSRFField is the name of the field the token is posted as, the name
+	// attribute of the selected element when empty
+	CSRFField string
+	// Fields are the posted credentials (e.g. username and password)
+	Fields url.Values
+}
+
+// FormLogin logs in through form with client before crawling: it fetches the
+// login page, extracts its CSRF token and posts it along with the credentials.
+// The session cookies are installed in the cookie jar of client, which must
+// have one, so that a crawler using client (see WithHTTPClient), or its jar (see
+// WithCookieJar), is logged in.
+// A response to the post with a 4xx or 5xx status code is an error
+func FormLogin(ctx context.Context, client *http.Client, form LoginForm) error {
+	if client.Jar == nil {
+		return errors.New("the login client has no cookie jar")
+	}
+	if form.URL == nil {
+		return errors.New("nil login URL")
+	}
+	fields := url.Values{}
+	for k, v := range form.Fields {
+		fields[k] = v
+	}
+	if form.CSRFSelector != "" {
+		name, token, err := getCSRFToken(ctx, client, form)
+		if err != nil {
+			return err
+		}
+		fields.Set(name, token)
+	}
+
+	action := form.Action
+	if action == nil {
+		action = form.URL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, action.String(), strings.NewReader(fields.Encode()))
+	if err != nil {
+		return fmt.Errorf("error while preparing login request - %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", form.URL.String())
+	r, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error while posting login form to %s - %v", action, err)
+	}
+	defer r.Body.Close()
+	io.Copy(io.Discard, r.Body)
+	if r.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("login form posted to %s answered with status %d", action, r.StatusCode)
+	}
+	return nil
+}
+
+// getCSRFToken returns the field name and the CSRF token of the login page of form
+func getCSRFToken(ctx context.Context, client *http.Client, form LoginForm) (string, string, error) {
+	sel, err := parseSelector(form.CSRFSelector)
+	if err != nil {
+		return "", "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, form.URL.String(), nil)
+	if err != nil {
+		return "", "", fmt.Errorf("error while preparing request - %v", err)
+	}
+	r, err := client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("error while getting login page %s - %v", form.URL, err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("login page %s answered with status %d", form.URL, r.StatusCode)
+	}
+	node, err := html.Parse(r.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("error while html parsing login page - %v", err)
+	}
+	element := sel.find(node)
+	if element == nil {
+		return "", "", fmt.Errorf("no element matching %q in login page %s", form.CSRFSelector, form.URL)
+	}
+	token := attr(element, "value")
+	if element.Data == "meta" {
+		token = attr(element, "content")
+	}
+	name := form.CSRFField
+	if name == "" {
+		name = attr(element, "name")
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("no field name for the CSRF token of login page %s", form.URL)
+	}
+	return name, token, nil
+}
+
+// selector is a compound CSS selector: an optional tag name and attribute conditions
+type selector struct {
+	tag   string
+	conds []selectorCond
+}
+
+// selectorCond is an attribute condition of a selector, val is ignored when
+// any is set. A class condition matches one of the classes of the attribute
+type selectorCond struct {
+	key, val   string
+	any, class bool
+}
+
+// parseSelector parses a compound selector made of an optional tag name
+// and #id, .class, [attr] or [attr=value] conditions (e.g. `input.token[name=csrf]`)
+func parseSelector(s string) (*selector, error) {
+	invalid := fmt.Errorf("invalid selector %q", s)
+	sel := &selector{}
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, "#.[")
+	if i < 0 {
+		i = len(s)
+	}
+	// combinators (e.g. descendants) are not supported
+	if strings.ContainsAny(s[:i], " \t>+~,") {
+		return nil, invalid
+	}
+	sel.tag = strings.ToLower(s[:i])
+	for s = s[i:]; s != ""; {
+		switch s[0] {
+		case '#', '.':
+			end := strings.IndexAny(s[1:], "#.[") + 1
+			if end == 0 {
+				end = len(s)
+			}
+			if end == 1 {
+				return nil, invalid
+			}
+			if s[0] == '#' {
+				sel.conds = append(sel.conds, selectorCond{key: "id", val: s[1:end]})
+			} else {
+				sel.conds = append(sel.conds, selectorCond{key: "class", val: s[1:end], class: true})
+			}
+			s = s[end:]
+		case '[':
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, invalid
+			}
+			key, val, hasVal := strings.Cut(s[1:end], "=")
+			key = strings.ToLower(strings.TrimSpace(key))
+			if key == "" {
+				return nil, invalid
+			}
+			val = strings.Trim(strings.TrimSpace(val), `"'`)
+			sel.conds = append(sel.conds, selectorCond{key: key, val: val, any: !hasVal})
+			s = s[end+1:]
+		default:
+			return nil, invalid
+		}
+	}
+	if sel.tag == "" && len(sel.conds) == 0 {
+		return nil, invalid
+	}
+	return sel, nil
+}
+
+// matches reports whether node is an element matching the selector
+func (sel *selector) matches(node *html.Node) bool {
+	if node.Type != html.ElementNode || sel.tag != "" && node.Data != sel.tag {
+		return false
+	}
+	for _, cond := range sel.conds {
+		v, ok := "", false
+		for _, a := range node.Attr {
+			if a.Key == cond.key {
+				v, ok = a.Val, true
+				break
+			}
+		}
+		switch {
+		case !ok:
+			return false
+		case cond.any:
+		case cond.class:
+			if !containsField(v, cond.val) {
+				return false
+			}
+		case v != cond.val:
+			return false
+		}
+	}
+	return true
+}
+
+// find returns the first element under node, in document order, matching the selector
+func (sel *selector) find(node *html.Node) *html.Node {
+	if sel.matches(node) {
+		return node
+	}
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		if found := sel.find(n); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// containsField reports whether the white space separated fields of s contain field
+func containsField(s, field string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
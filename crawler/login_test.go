@@ -0,0 +1,124 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func Test_selector_find(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><head><meta name="csrf-token" content="meta-token"></head><body>
+		<form id="login" class="form wide"><input type="hidden" name="csrf_token" value="input-token"><input name="username"></form>
+		</body></html>`))
+	assert.Nil(t, err)
+
+	tests := map[string]struct {
+		selector string
+		// want is the name attribute of the found element
+		want    string
+		wantErr bool
+	}{
+		"tag_and_attribute":     {selector: "input[name=csrf_token]", want: "csrf_token"},
+		"quoted_value":          {selector: `meta[name="csrf-token"]`, want: "csrf-token"},
+		"attribute_presence":    {selector: "[content]", want: "csrf-token"},
+		"first_in_order":        {selector: "input", want: "csrf_token"},
+		"id_and_classes":        {selector: "form#login.wide.form", want: ""},
+		"not_found":             {selector: "input[name=password]"},
+		"descendant_combinator": {selector: "form input", wantErr: true},
+		"empty_id":              {selector: "input#", wantErr: true},
+		"unterminated":          {selector: "input[name=csrf_token", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			sel, err := parseSelector(tt.selector)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			found := sel.find(doc)
+			if name == "not_found" {
+				assert.Nil(t, found)
+				return
+			}
+			assert.NotNil(t, found)
+			assert.Equal(t, tt.want, attr(found, "name"))
+		})
+	}
+}
+
+func TestFormLogin(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			http.SetCookie(w, &http.Cookie{Name: "csrf", Value: "t0k3n"})
+			fmt.Fprint(w, `<html><body><form><input type="hidden" name="csrf_token" value="t0k3n"></form></body></html>`)
+			return
+		}
+		csrf, err := r.Cookie("csrf")
+		if err != nil || csrf.Value != r.FormValue("csrf_token") || r.FormValue("username") != "alice" || r.FormValue("password") != "s3cr3t" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "alice"})
+		http.Redirect(w, r, "/index.html", http.StatusSeeOther)
+	})
+	mux.HandleFunc("/index.html", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, linksPage("index"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := map[string]struct {
+		password   string
+		wantErr    bool
+		wantStatus int
+	}{
+		"logged_in": {
+			password:   "s3cr3t",
+			wantStatus: http.StatusOK,
+		},
+		"invalid_credentials": {
+			password:   "guess",
+			wantErr:    true,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			jar, _ := cookiejar.New(nil)
+			client := &http.Client{Jar: jar}
+			err := FormLogin(context.Background(), client, LoginForm{
+				URL:          getURL(srv.URL + "/login"),
+				CSRFSelector: "input[name=csrf_token]",
+				Fields:       url.Values{"username": {"alice"}, "password": {tt.password}},
+			})
+			assert.Equal(t, tt.wantErr, err != nil)
+
+			var status int
+			err = NewCrawler(WithHTTPClient(client)).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+				status = p.StatusCode
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantStatus, status)
+		})
+	}
+}
+
+func TestFormLogin_NoJar(t *testing.T) {
+	err := FormLogin(context.Background(), &http.Client{}, LoginForm{URL: getURL("https://my-web-site.com/login")})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,49 @@
+package crawler
+
+import (
+	"net/http"
+)
+
+// Middleware wraps the http.RoundTripper issuing the requests of the crawler,
+// it is the extension point to inspect or modify the requests and responses
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to a http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// OnRequest returns a Middleware calling hook on a copy of every request before
+// it is issued, e.g. to add headers. An error returned by hook aborts the request
+func OnRequest(hook func(req *http.Request) error) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// a RoundTripper must not modify the request
+			req = req.Clone(req.Context())
+			if err := hook(req); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// setHeaders sets header on req, replacing the values of the same headers.
+// A Host header sets the host of the request
+func setHeaders(req *http.Request, header http.Header) {
+	for name, values := range header {
+		if http.CanonicalHeaderKey(name) == "Host" {
+			if len(values) > 0 {
+				req.Host = values[0]
+			}
+			continue
+		}
+		req.Header.Del(name)
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func Test_crawler_CrawlPages_WithMiddlewares(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, linksPage(r.Header.Get("X-Order")))
+	}))
+	defer srv.Close()
+
+	order := func(name string) Middleware {
+		return OnRequest(func(req *http.Request) error {
+			req.Header.Set("X-Order", req.Header.Get("X-Order")+name)
+			return nil
+		})
+	}
+	auth := OnRequest(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer token")
+		return nil
+	})
+
+	var mu sync.Mutex
+	var pages []*Page
+	err := NewCrawler(WithMiddlewares(auth, order("a")), WithMiddlewares(order("b"))).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+		mu.Lock()
+		defer mu.Unlock()
+		pages = append(pages, p)
+	})
+	assert.Nil(t, err)
+	assert.Len(t, pages, 1)
+	assert.Equal(t, http.StatusOK, pages[0].StatusCode)
+	// the middlewares are applied in the order they were set
+	assert.Equal(t, "ab", pages[0].Title())
+}
+
+func TestOnRequest_Error(t *testing.T) {
+	errAborted := errors.New("aborted")
+	next := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatal("request not aborted")
+		return nil, nil
+	})
+	req, _ := http.NewRequest(http.MethodGet, "https://my-web-site.com/", nil)
+	_, err := OnRequest(func(req *http.Request) error { return errAborted })(next).RoundTrip(req)
+	assert.ErrorIs(t, err, errAborted)
+}
+
+func Test_crawler_CrawlPages_WithHeaders(t *testing.T) {
+	type request struct {
+		host, language, agent string
+	}
+	var mu sync.Mutex
+	requests := make(map[string]request)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path] = request{host: r.Host, language: r.Header.Get("Accept-Language"), agent: r.UserAgent()}
+		mu.Unlock()
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, linksPage("index"))
+	}))
+	defer srv.Close()
+
+	header := http.Header{"Accept-Language": {"fr-CH"}, "User-Agent": {"my-bot/1.0"}, "host": {"my-web-site.com"}}
+	err := NewCrawler(WithRobotsTxt("my-bot"), WithHeaders(header)).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(*Page) {})
+	assert.Nil(t, err)
+	want := request{host: "my-web-site.com", language: "fr-CH", agent: "my-bot/1.0"}
+	assert.Equal(t, map[string]request{"/robots.txt": want, "/index.html": want}, requests)
+}
@@ -0,0 +1,169 @@
+package crawler
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultTrackingParams are the patterns of the query parameters removed by default
+// from the crawled URLs (see WithTrackingParams)
+var DefaultTrackingParams = []string{"utm_*", "fbclid", "gclid"}
+
+// defaultPorts are the ports removed from the URLs of their scheme by Normalize
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Normalize returns a copy of u in the normal form of RFC 3986 so that the
+// equivalent URLs are visited once: the scheme and host are lower-cased, the
+// default port of the scheme is removed, the dot segments of the path are
+// resolved, an empty path becomes `/` and the percent-encodings of the path and
+// query are upper-cased, the unreserved characters being decoded. Unlike the
+// canonicalization transforms (see WithCanonicalization), it never changes the
+// resource a URL points to, so that the crawler always applies it
+func Normalize(u *url.URL) *url.URL {
+	n := *u
+	if u.User != nil {
+		user := *u.User
+		n.User = &user
+	}
+	if n.Opaque != "" {
+		n.Scheme = strings.ToLower(n.Scheme)
+		return &n
+	}
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = strings.ToLower(n.Host)
+	if port := n.Port(); port == "" || port == defaultPorts[n.Scheme] {
+		n.Host = strings.TrimSuffix(strings.TrimSuffix(n.Host, port), ":")
+	}
+
+	p := removeDotSegments(normalizeEscapes(n.EscapedPath()))
+	if p == "" && n.Host != "" {
+		p = "/"
+	}
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		n.Path, n.RawPath = unescaped, p
+		if n.EscapedPath() != p {
+			// the path cannot be escaped back to p
+			n.Path, n.RawPath = u.Path, u.RawPath
+		}
+	}
+	n.RawQuery = normalizeEscapes(n.RawQuery)
+	return &n
+}
+
+// normalizeEscapes upper-cases the hexadecimal digits of the percent-encodings
+// of s and decodes the unreserved characters (letters, digits, `-`, `.`, `_`
+// and `~`), the malformed percent-encodings are kept as is
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// removeDotSegments resolves the `.` and `..` segments of a rooted path as
+// described in RFC 3986 (section 5.2.4), the other paths are kept as is
+func removeDotSegments(p string) string {
+	if !strings.HasPrefix(p, "/") || !strings.Contains(p, ".") {
+		return p
+	}
+	segments := strings.Split(p, "/")
+	// the first segment is the empty one before the root
+	out := make([]string, 1, len(segments))
+	for i, s := range segments[1:] {
+		last := i == len(segments)-2
+		switch s {
+		case ".":
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, s)
+			continue
+		}
+		// a trailing dot segment designates a directory
+		if last {
+			out = append(out, "")
+		}
+	}
+	return strings.Join(out, "/")
+}
+
+// isUnreserved reports whether c is an unreserved character of RFC 3986
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// stripParams removes in place the query parameters of u whose key matches one of
+// the patterns (path.Match syntax), the order of the other parameters is kept.
+// The malformed patterns match no key
+func stripParams(u *url.URL, patterns []string) {
+	if u.RawQuery == "" || len(patterns) == 0 {
+		return
+	}
+	params := strings.Split(u.RawQuery, "&")
+	kept := params[:0]
+	for _, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if !matchesAny(patterns, key) {
+			kept = append(kept, param)
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+}
+
+// matchesAny reports whether s matches one of the patterns
+func matchesAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalize normalizes u, removes its tracking parameters and applies the
+// canonicalization pipeline of the crawler
+func (c *crawler) canonicalize(u *url.URL) *url.URL {
+	n := Normalize(u)
+	stripParams(n, c.trackingParams)
+	return Canonicalize(n, c.canonical)
+}
@@ -0,0 +1,123 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want string
+	}{
+		"normalized":          {in: "http://my-web-site.com/a/b?c=d#e", want: "http://my-web-site.com/a/b?c=d#e"},
+		"scheme_and_host":     {in: "HTTP://My-Web-Site.COM/Path", want: "http://my-web-site.com/Path"},
+		"http_default_port":   {in: "http://my-web-site.com:80/a", want: "http://my-web-site.com/a"},
+		"https_default_port":  {in: "https://my-web-site.com:443/a", want: "https://my-web-site.com/a"},
+		"other_port":          {in: "http://my-web-site.com:443/a", want: "http://my-web-site.com:443/a"},
+		"empty_port":          {in: "http://my-web-site.com:/a", want: "http://my-web-site.com/a"},
+		"ipv6_default_port":   {in: "http://[::1]:80/a", want: "http://[::1]/a"},
+		"empty_path":          {in: "http://my-web-site.com", want: "http://my-web-site.com/"},
+		"dot_segments":        {in: "HTTP://My-Web-Site.com:80/a/../b", want: "http://my-web-site.com/b"},
+		"dot_segments_mixed":  {in: "http://my-web-site.com/a/./b/../../c/d", want: "http://my-web-site.com/c/d"},
+		"trailing_dot":        {in: "http://my-web-site.com/a/b/..", want: "http://my-web-site.com/a/"},
+		"above_root":          {in: "http://my-web-site.com/../../a", want: "http://my-web-site.com/a"},
+		"dots_in_names":       {in: "http://my-web-site.com/a.b/..c/index.html", want: "http://my-web-site.com/a.b/..c/index.html"},
+		"unreserved_decoded":  {in: "http://my-web-site.com/%7euser/%41%2e%5F", want: "http://my-web-site.com/~user/A._"},
+		"encoded_dot_segment": {in: "http://my-web-site.com/a/%2E%2E/b", want: "http://my-web-site.com/b"},
+		"reserved_upper_case": {in: "http://my-web-site.com/a%2fb%3f", want: "http://my-web-site.com/a%2Fb%3F"},
+		"query":               {in: "http://my-web-site.com/?q=%7e%2f", want: "http://my-web-site.com/?q=~%2F"},
+		"malformed_escape":    {in: "http://my-web-site.com/?q=100%", want: "http://my-web-site.com/?q=100%"},
+		"opaque":              {in: "MAILTO:Me@My-Web-Site.com", want: "mailto:Me@My-Web-Site.com"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			u := getURL(tt.in)
+			assert.Equal(t, tt.want, Normalize(u).String())
+			// the input is not modified
+			assert.Equal(t, getURL(tt.in), u)
+		})
+	}
+}
+
+func Test_crawler_CrawlPages_Normalized(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html":     linksPage("index", "page1.html", "dir/../page1.html", "./%70age1.html", "dir/%7Efile.html", "dir/~file.html"),
+		"/page1.html":     linksPage("page1"),
+		"/dir/~file.html": linksPage("file"),
+	})
+
+	var mu sync.Mutex
+	var titles []string
+	err := NewCrawler().CrawlPages(context.Background(), getURL(site.URL+"/index.html"), func(p *Page) {
+		mu.Lock()
+		defer mu.Unlock()
+		titles = append(titles, p.Title())
+	})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"index", "page1", "file"}, titles)
+}
+
+func Test_stripParams(t *testing.T) {
+	tests := map[string]struct {
+		in       string
+		patterns []string
+		want     string
+	}{
+		"default":           {in: "https://my-web-site.com/a?utm_source=x&b=1&fbclid=y&a=2&gclid=z", patterns: DefaultTrackingParams, want: "https://my-web-site.com/a?b=1&a=2"},
+		"all_stripped":      {in: "https://my-web-site.com/a?utm_source=x&utm_medium=y", patterns: DefaultTrackingParams, want: "https://my-web-site.com/a"},
+		"escaped_key":       {in: "https://my-web-site.com/a?utm%5Fsource=x&b=1", patterns: DefaultTrackingParams, want: "https://my-web-site.com/a?b=1"},
+		"no_patterns":       {in: "https://my-web-site.com/a?utm_source=x", want: "https://my-web-site.com/a?utm_source=x"},
+		"malformed_pattern": {in: "https://my-web-site.com/a?utm_source=x&[=1", patterns: []string{"["}, want: "https://my-web-site.com/a?utm_source=x&[=1"},
+		"no_query":          {in: "https://my-web-site.com/a", patterns: DefaultTrackingParams, want: "https://my-web-site.com/a"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			u := getURL(tt.in)
+			stripParams(u, tt.patterns)
+			assert.Equal(t, tt.want, u.String())
+		})
+	}
+}
+
+func Test_crawler_CrawlPages_WithTrackingParams(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html": linksPage("index", "page1.html?utm_source=newsletter", "page1.html?fbclid=abc", "page1.html?ref=home"),
+		"/page1.html": linksPage("page1"),
+	})
+
+	tests := map[string]struct {
+		opts []Option
+		want []string
+	}{
+		"default": {
+			want: []string{"/index.html", "/page1.html", "/page1.html?ref=home"},
+		},
+		"custom": {
+			opts: []Option{WithTrackingParams("utm_*", "ref")},
+			want: []string{"/index.html", "/page1.html", "/page1.html?fbclid=abc"},
+		},
+		"disabled": {
+			opts: []Option{WithTrackingParams()},
+			want: []string{"/index.html", "/page1.html?fbclid=abc", "/page1.html?ref=home", "/page1.html?utm_source=newsletter"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var visited []string
+			err := NewCrawler(tt.opts...).CrawlPages(context.Background(), getURL(site.URL+"/index.html"), func(p *Page) {
+				mu.Lock()
+				defer mu.Unlock()
+				visited = append(visited, p.URL.RequestURI())
+			})
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, tt.want, visited)
+		})
+	}
+}
@@ -0,0 +1,619 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Option configures a Crawler created by NewCrawler
+type Option func(c *crawler)
+
+// WithCanonicalization sets the ordered list of transforms applied to every
+// URL before it is checked against the set of visited pages and fetched.
+// URLs that differ only by parts removed by the transforms are crawled once
+func WithCanonicalization(transforms ...Transform) Option {
+	return func(c *crawler) {
+		c.canonical = transforms
+	}
+}
+
+// WithTrackingParams sets the patterns (path.Match syntax, e.g. `utm_*`) of the query
+// parameters, typically added by analytics tools, removed from every URL before it is
+// checked against the set of visited pages and fetched, so that the decorated links are
+// crawled once. By default DefaultTrackingParams, no patterns means the parameters are
+// all kept. The malformed patterns match no parameter
+func WithTrackingParams(patterns ...string) Option {
+	return func(c *crawler) {
+		c.trackingParams = patterns
+	}
+}
+
+// WithStore sets the Store used to track the visited pages and the crawl stats.
+// The store is shared by all the Crawl calls, so several crawls (e.g. over
+// multiple seeds) share one set of visited pages and one set of stats
+func WithStore(store Store) Option {
+	return func(c *crawler) {
+		c.store = store
+	}
+}
+
+// WithMaxConcurrency limits to n the number of pages concurrently scraped by
+// the crawler, across all its running crawls. A value lower than 1 means no limit
+func WithMaxConcurrency(n int) Option {
+	return func(c *crawler) {
+		c.sem = newSemaphore(n)
+	}
+}
+
+// WithMaxDepth limits the crawl to the pages found at most n hops (links followed)
+// from the base URL: the links of the pages at depth n are not followed. As pages
+// are visited concurrently, a page reachable through several paths gets the depth
+// of the path it was first found through. A value lower than 0 means no limit
+func WithMaxDepth(n int) Option {
+	return func(c *crawler) {
+		c.maxDepth = n
+		c.limitDepth = n >= 0
+	}
+}
+
+// WithMaxDuration sets the maximum duration of each crawl (or fetch) of the
+// crawler: once it expires no new page is fetched, the in-flight requests are
+// cancelled and the crawl returns, once all its go-routines ended, an error
+// wrapping ErrMaxDuration. The requests are bounded to end just before it
+// expires (see WithRequestTimeout). A value lower or equal to 0 means no limit
+func WithMaxDuration(d time.Duration) Option {
+	return func(c *crawler) {
+		c.maxDuration = d
+	}
+}
+
+// WithRetry sets the policy retrying with exponential backoff the page requests
+// failing with a network error, so that transient DNS or connection failures do not
+// drop pages from the crawl. By default a failed request is not retried
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *crawler) {
+		c.retry = policy
+	}
+}
+
+// WithRedirectPolicy sets how the redirects of the page requests are followed,
+// overriding the CheckRedirect function of the client set with WithHTTPClient. By
+// default the redirects are followed as http.Client does
+func WithRedirectPolicy(policy RedirectPolicy) Option {
+	return func(c *crawler) {
+		c.redirect = &policy
+	}
+}
+
+// WithHostStats counts in stats the pages, errors, bytes and latency of the page
+// requests of each host, e.g. to identify the slow or broken hosts of CrawlSites
+func WithHostStats(stats *HostStats) Option {
+	return func(c *crawler) {
+		c.hostStats = stats
+	}
+}
+
+// WithContentTypes counts in types the responses and bytes of each content type
+// and keeps the inventory of the non-HTML resources (e.g. images, PDFs) crawled
+func WithContentTypes(types *ContentTypes) Option {
+	return func(c *crawler) {
+		c.contentTypes = types
+	}
+}
+
+// WithWeights tracks in weights the size of the crawled pages, to report the
+// largest ones and the heaviest sections of the crawled sites
+func WithWeights(weights *Weights) Option {
+	return func(c *crawler) {
+		c.weights = weights
+	}
+}
+
+// WithContentTypeAllowlist sets the media types (e.g. `text/html`) of the responses
+// parsed, by default DefaultContentTypeAllowlist. The content type of a response is
+// taken from its Content-Type header, or sniffed from its first bytes when it has
+// none. The responses of other content types (e.g. PDFs, images) are not read: their
+// pages are visited with an empty document and their links are not followed. No
+// types means all the responses are parsed
+func WithContentTypeAllowlist(types ...string) Option {
+	return func(c *crawler) {
+		c.allowedTypes = make([]string, len(types))
+		for i, t := range types {
+			c.allowedTypes[i] = strings.ToLower(strings.TrimSpace(t))
+		}
+	}
+}
+
+// WithHeadFirst makes the crawler issue a HEAD request before getting a page, the
+// page is only got when its Content-Type is allowed (see WithContentTypeAllowlist)
+// and its Content-Length does not exceed the maximum body size (see WithMaxBodyBytes),
+// saving the download of large resources. Otherwise its page is visited with an empty
+// document. The page is got as usual when the HEAD request fails or its response has
+// no Content-Type
+func WithHeadFirst() Option {
+	return func(c *crawler) {
+		c.headFirst = true
+	}
+}
+
+// WithMaxBodyBytes limits to n the bytes read out of each response body, so that a
+// huge response (or a tarpit endpoint) cannot exhaust the memory while it is parsed:
+// a longer page is truncated to its first n bytes. A value of n lower than or equal
+// to 0 means no limit (the default)
+func WithMaxBodyBytes(n int64) Option {
+	return func(c *crawler) {
+		c.maxBodyBytes = n
+	}
+}
+
+// WithRawBody keeps on each parsed page the raw response body it was parsed
+// from (see Page.Body), e.g. to search the page source as it was served rather
+// than its rendering. The bodies are kept as long as the pages are
+func WithRawBody() Option {
+	return func(c *crawler) {
+		c.rawBody = true
+	}
+}
+
+// WithLinksOnly makes the crawler tokenize the pages for their links rather than
+// parse them into a document tree, e.g. to only list the crawled URLs: the Node of
+// each page is a document holding its <a> and <meta> elements only, so that the
+// links are still followed and the robots meta tags honored. The validators, the
+// reports and the visit function only see these elements
+func WithLinksOnly() Option {
+	return func(c *crawler) {
+		c.linksOnly = true
+	}
+}
+
+// WithRequestTimeout bounds each attempt of a page request, reading of the response
+// included, to d so that a slow page does not hold a concurrency slot for the whole
+// crawl. A timed out request fails like a network error and is retried according to
+// WithRetry. Near the deadline of a crawl (see WithMaxDuration) the timeout is
+// shortened to end just before it. A value of d lower than or equal to 0 means
+// no timeout (the default)
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *crawler) {
+		c.requestTimeout = d
+	}
+}
+
+// WithParseConcurrency separates the parsing of the pages from their fetching so
+// that slow parsing of huge pages does not idle the network: at most n pages are
+// concurrently parsed and visited while WithMaxConcurrency only limits the pages
+// concurrently fetched. At most queue fetched pages wait for a parse slot (a value
+// lower than 1 means 1), fetches block while the queue is full. A value of n lower
+// than 1 means a page is fetched and parsed holding a single concurrency slot
+func WithParseConcurrency(n, queue int) Option {
+	return func(c *crawler) {
+		if n < 1 {
+			c.parseSem, c.parseQueue = nil, nil
+			return
+		}
+		if queue < 1 {
+			queue = 1
+		}
+		c.parseSem = newSemaphore(n)
+		c.parseQueue = newSemaphore(queue)
+	}
+}
+
+// WithPrefetch makes the crawler start fetching the first n links of a page, in
+// document order, while its visit function is still running, so that slow visits
+// (e.g. extraction-heavy ones) overlap with the network. The prefetched pages are
+// still subject to the concurrency limits, exclusions and robots.txt rules. A value
+// lower than 1 means the links are only dispatched once the visit returned
+func WithPrefetch(n int) Option {
+	return func(c *crawler) {
+		if n < 0 {
+			n = 0
+		}
+		c.prefetch = n
+	}
+}
+
+// WithRateLimit limits to rps the requests per second issued by the crawler, across
+// all its go-routines and running crawls, allowing bursts of up to burst requests
+// (a value lower than 1 means 1). Redirects and robots.txt requests are also limited.
+// A value of rps lower or equal to 0 means no limit
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *crawler) {
+		if rps <= 0 {
+			c.limiter = nil
+			return
+		}
+		c.limiter = newRateLimiter(rps, burst)
+		c.throttle = nil
+	}
+}
+
+// WithAdaptiveRateLimit behaves like WithRateLimit but the rate adapts to the health
+// of the crawled servers, between min and max requests per second: it starts at max,
+// halves when the servers answer with 5xx status codes, fail or slow down, and
+// increases back by steps when they recover. A value of max lower or equal to 0
+// means no limit, min is capped to max
+func WithAdaptiveRateLimit(min, max float64, burst int) Option {
+	return func(c *crawler) {
+		if max <= 0 {
+			c.limiter, c.throttle = nil, nil
+			return
+		}
+		if min > max {
+			min = max
+		}
+		c.limiter = newRateLimiter(max, burst)
+		c.throttle = newThrottle(c.limiter, min, max)
+	}
+}
+
+// WithValidators sets the validators run on every scraped page, the findings
+// are reported to onFinding which might be called concurrently
+func WithValidators(onFinding func(f Finding), validators ...Validator) Option {
+	return func(c *crawler) {
+		c.onFinding = onFinding
+		c.validators = validators
+	}
+}
+
+// WithReports sets the reports consuming every scraped page, their findings are
+// returned by their Finalize method once the crawl is done
+func WithReports(reports ...Report) Option {
+	return func(c *crawler) {
+		c.reports = reports
+	}
+}
+
+// WithAIOptOut makes the crawler honor the AI opt-out directives of the pages
+// (see AIDirectives): pages declaring them are not passed to the visit function,
+// their links are still followed
+func WithAIOptOut() Option {
+	return func(c *crawler) {
+		c.honorAIOptOut = true
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to fetch the pages (and the robots.txt
+// files), e.g. for custom timeouts, proxies or cookie jars. The client is not
+// modified: the options affecting the transport (e.g. WithTLSVersions,
+// WithRequestSigner, WithRateLimit) apply to a copy of it, the ones customizing
+// the connections (WithDialContext, WithUnixSocket, WithTLSVersions) require its
+// transport to be a *http.Transport (or nil)
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *crawler) {
+		c.baseClient = client
+	}
+}
+
+// WithProxyPool issues the requests of the crawler through the proxies of pool,
+// rotated and evicted when dead. The transport of a client set with WithHTTPClient
+// must be a *http.Transport, its Proxy setting is overridden
+func WithProxyPool(pool *ProxyPool) Option {
+	return func(c *crawler) {
+		c.proxies = pool
+	}
+}
+
+// WithMiddlewares adds middlewares wrapping the transport of the crawler, e.g. to
+// inject headers, log or cache the requests. The requests go through the middlewares
+// in order, after the rate limiting (see WithRateLimit) and before the signing (see
+// WithRequestSigner) so that the headers they add are signed
+func WithMiddlewares(middlewares ...Middleware) Option {
+	return func(c *crawler) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// WithHeaders sets header on the requests to hosts, robots.txt and redirected ones
+// included (e.g. Accept-Language, an API key). No hosts means all the requests, but
+// the redirect hops to another host than the one of the first request. A header of
+// the request, like its User-Agent, is replaced and a Host header sets the host of
+// the requests. The headers are applied as a middleware, in order with the ones set
+// with WithMiddlewares
+func WithHeaders(header http.Header, hosts ...string) Option {
+	if len(header) == 0 {
+		return func(c *crawler) {}
+	}
+	return withScopedHeader(header, hosts)
+}
+
+// WithAuthRealms sets on every request the headers of the realm it belongs to, the
+// one with the longest matching path prefix (see AuthRealm). Redirected requests get
+// the headers of their own realm, the realms without host only being applied within
+// the host of the first request. The realms are applied as a middleware, in order
+// with the ones set with WithMiddlewares
+func WithAuthRealms(realms ...AuthRealm) Option {
+	return func(c *crawler) {
+		if len(realms) > 0 {
+			c.middlewares = append(c.middlewares, authRealmsMiddleware(realms))
+		}
+	}
+}
+
+// WithDomainGroups applies to the requests the settings of the first group their host
+// belongs to (see DomainGroup), instead of repeating them for every host. The rate
+// limit of a group applies on top of the one set with WithRateLimit. The groups are
+// applied as a middleware, in order with the ones set with WithMiddlewares
+func WithDomainGroups(groups ...DomainGroup) Option {
+	return func(c *crawler) {
+		if len(groups) > 0 {
+			c.middlewares = append(c.middlewares, domainGroupsMiddleware(groups))
+		}
+	}
+}
+
+// WithBasicAuth sends the HTTP basic authentication credentials of user on the
+// requests to hosts (e.g. `intranet.my-company.com` or `localhost:8080`), robots.txt
+// and redirected requests included. No hosts means all the requests, but the
+// redirect hops to another host than the one of the first request
+func WithBasicAuth(user, password string, hosts ...string) Option {
+	return withScopedHeader(BasicAuthHeader(user, password), hosts)
+}
+
+// WithBearerToken sends the bearer token on the requests to hosts, robots.txt and
+// redirected requests included. No hosts means all the requests, but the redirect
+// hops to another host than the one of the first request
+func WithBearerToken(token string, hosts ...string) Option {
+	return withScopedHeader(BearerAuthHeader(token), hosts)
+}
+
+// withScopedHeader sets header on the requests to hosts, all the requests within the
+// host of the first request of their redirect chain without hosts
+func withScopedHeader(header http.Header, hosts []string) Option {
+	if len(hosts) == 0 {
+		return WithAuthRealms(AuthRealm{Header: header})
+	}
+	realms := make([]AuthRealm, 0, len(hosts))
+	for _, host := range hosts {
+		realms = append(realms, AuthRealm{Host: host, Header: header})
+	}
+	return WithAuthRealms(realms...)
+}
+
+// WithCookieJar makes the crawler store the cookies set by the crawled sites in jar
+// and send them with the following requests, e.g. to keep a session. The jar is
+// shared by all the crawls of the crawler, a nil jar means a new in-memory one. It
+// replaces the jar of a client set with WithHTTPClient
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(c *crawler) {
+		if jar == nil {
+			jar = newCookieJar()
+		}
+		c.jar = jar
+	}
+}
+
+// WithCookies stores cookies in the cookie jar of the crawler before crawling, as if
+// they were set by the response of u, e.g. the session cookie of an authenticated area.
+// Without WithCookieJar the cookies are stored in the jar of the client set with
+// WithHTTPClient or, if it has none, in a new in-memory jar
+func WithCookies(u *url.URL, cookies ...*http.Cookie) Option {
+	return func(c *crawler) {
+		c.cookies = append(c.cookies, seededCookies{url: u, cookies: cookies})
+	}
+}
+
+// WithSessionRenewal makes the crawler survive the expiry of an authenticated session:
+// once a request succeeded, the first request denied with a 401 or 403 status code
+// calls login, with the HTTP client of the crawler, and is retried once. The requests
+// concurrently denied wait for the renewal and are retried with the new session. A
+// failing login is logged and the denied response is kept
+func WithSessionRenewal(login func(ctx context.Context, client *http.Client) error) Option {
+	return func(c *crawler) {
+		if login == nil {
+			c.session = nil
+			return
+		}
+		c.session = &sessionRenewal{login: login, client: c.userAgentClient}
+	}
+}
+
+// WithDialContext sets the function used to open the network connections to the
+// crawled servers, e.g. to reach them through a tunnel. The addr parameter is the
+// host:port of the crawled URL
+func WithDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(c *crawler) {
+		c.transport = append(c.transport, func(t *http.Transport) {
+			t.DialContext = dial
+		})
+	}
+}
+
+// WithDNSCache makes the crawler cache the addresses of the resolved hosts for ttl,
+// instead of resolving a host for every new connection. It applies to the dial
+// function set before it (see WithDialContext). A ttl lower or equal to 0 means no cache
+func WithDNSCache(ttl time.Duration) Option {
+	return func(c *crawler) {
+		if ttl <= 0 {
+			return
+		}
+		cache := newDNSCache(ttl)
+		c.transport = append(c.transport, func(t *http.Transport) {
+			t.DialContext = cache.dialer(t.DialContext)
+		})
+	}
+}
+
+// WithHostAliases makes the crawler connect to the address aliasing the host:port
+// of the crawled URLs, if any, instead of resolving their host. It applies to the
+// dial function set before it (see WithDialContext)
+func WithHostAliases(aliases HostAliases) Option {
+	return func(c *crawler) {
+		c.transport = append(c.transport, func(t *http.Transport) {
+			t.DialContext = aliases.dialer(t.DialContext)
+		})
+	}
+}
+
+// WithUnixSocket makes the crawler connect to the Unix domain socket at path whatever
+// the host of the crawled URLs, which are still used for the requests and the
+// same-domain checks (e.g. `http://localhost/index.html`)
+func WithUnixSocket(path string) Option {
+	return WithDialContext(func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	})
+}
+
+// WithRequestSigner sets the signer applied to every request issued by the
+// crawler, redirects included (e.g. a SigV4Signer)
+func WithRequestSigner(signer RequestSigner) Option {
+	return func(c *crawler) {
+		c.signer = signer
+	}
+}
+
+// WithTLSVersions sets the minimum and maximum TLS versions accepted by the
+// crawler (e.g. tls.VersionTLS12), a zero value keeps the Go default
+func WithTLSVersions(min, max uint16) Option {
+	return func(c *crawler) {
+		c.transport = append(c.transport, func(t *http.Transport) {
+			config := transportTLSConfig(t)
+			config.MinVersion = min
+			config.MaxVersion = max
+		})
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the connections of the crawler, e.g. to
+// crawl internal sites with a private PKI or mutual TLS. A copy of config is used, the
+// TLS options set after it (e.g. WithTLSVersions, WithRootCAs) amend that copy
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *crawler) {
+		c.transport = append(c.transport, func(t *http.Transport) {
+			t.TLSClientConfig = config.Clone()
+		})
+	}
+}
+
+// WithRootCAs makes the crawler trust the servers whose certificate is issued by one
+// of the certificate authorities of pool, instead of the system ones
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *crawler) {
+		c.transport = append(c.transport, func(t *http.Transport) {
+			transportTLSConfig(t).RootCAs = pool
+		})
+	}
+}
+
+// WithClientCertificates sets the certificates the crawler presents to the servers
+// requesting one (mutual TLS), e.g. loaded with tls.LoadX509KeyPair
+func WithClientCertificates(certs ...tls.Certificate) Option {
+	return func(c *crawler) {
+		c.transport = append(c.transport, func(t *http.Transport) {
+			transportTLSConfig(t).Certificates = certs
+		})
+	}
+}
+
+// WithInsecureSkipVerify makes the crawler accept any certificate presented by the
+// servers, whatever its host name or issuer. It is only meant for testing
+func WithInsecureSkipVerify() Option {
+	return func(c *crawler) {
+		c.transport = append(c.transport, func(t *http.Transport) {
+			transportTLSConfig(t).InsecureSkipVerify = true
+		})
+	}
+}
+
+// WithHTTP2 enables or disables HTTP/2. When enabled the crawler negotiates HTTP/2
+// with the TLS servers supporting it, so that the requests to a host are multiplexed
+// over a single connection, and falls back to HTTP/1.1 with the other servers. This
+// is the default of http.DefaultTransport, the option also forces it on the transport
+// of a client set with WithHTTPClient. When disabled only HTTP/1.1 is used over TCP,
+// HTTP/3 being enabled separately with WithHTTP3
+func WithHTTP2(enabled bool) Option {
+	return func(c *crawler) {
+		c.transport = append(c.transport, func(t *http.Transport) {
+			t.ForceAttemptHTTP2 = enabled
+			if enabled {
+				return
+			}
+			// a non-nil empty map disables HTTP/2, h2 must no
+			// longer be negotiated if the transport was used
+			t.TLSNextProto = make(map[string]func(authority string, c *tls.Conn) http.RoundTripper)
+			if t.TLSClientConfig != nil {
+				var protos []string
+				for _, proto := range t.TLSClientConfig.NextProtos {
+					if proto != "h2" {
+						protos = append(protos, proto)
+					}
+				}
+				t.TLSClientConfig.NextProtos = protos
+			}
+		})
+	}
+}
+
+// WithHTTP3 issues the requests over HTTP/3 to the origins advertising it with an
+// Alt-Svc header (RFC 7838), through h3, an HTTP/3 http.RoundTripper e.g. the
+// http3.Transport of github.com/quic-go/quic-go. The first requests to an origin go
+// over TCP, with HTTP/2 or HTTP/1.1 (see WithHTTP2), the following ones over HTTP/3
+// once a response advertises an h3 alternative on the same host. A request failing
+// over HTTP/3 is retried over TCP, the origin being then reached over TCP for 5
+// minutes. HTTP/3 is not used through a proxy pool (see WithProxyPool)
+func WithHTTP3(h3 http.RoundTripper) Option {
+	return func(c *crawler) {
+		c.http3 = h3
+	}
+}
+
+// WithExclusions makes the crawler skip the pages matching the patterns of
+// exclusions, which can be added while the crawls are running
+func WithExclusions(exclusions *Exclusions) Option {
+	return func(c *crawler) {
+		c.exclusions = exclusions
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests of the crawler, robots.txt
+// ones included, DefaultUserAgent by default. An empty userAgent sends the Go default one
+func WithUserAgent(userAgent string) Option {
+	return func(c *crawler) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithUserAgents rotates the User-Agent header of the page requests through agents,
+// per request or per host according to rotation, e.g. to compare how sites serve
+// different clients. The robots.txt requests keep the User-Agent set with
+// WithUserAgent. No agents means no rotation
+func WithUserAgents(rotation UserAgentRotation, agents ...string) Option {
+	return func(c *crawler) {
+		if len(agents) == 0 {
+			c.userAgents = nil
+			return
+		}
+		c.userAgents = &userAgents{agents: agents, rotation: rotation, hosts: make(map[string]string)}
+	}
+}
+
+// WithRobotsTxt makes the crawler honor the robots.txt of the crawled hosts: the
+// pages disallowed for the group matching userAgent (or for the `*` group) are not
+// fetched and the requests to a host are spaced by its Crawl-delay. The robots.txt
+// of each host is fetched once per crawl, bounded by the request timeout (see
+// WithRequestTimeout). A retrieval failing with a network or server error is
+// retried up to 3 times, 5s apart, the pages of the host waiting for it. Once
+// all its attempts failed the host is not crawled for 5s, the pages admitted
+// past that retrieving it again
+func WithRobotsTxt(userAgent string) Option {
+	return func(c *crawler) {
+		c.robots = true
+		c.robotsUserAgent = userAgent
+	}
+}
+
+// WithMaxCrawlDelay caps the Crawl-delay honored with WithRobotsTxt to max, so that
+// a robots.txt asking for a long delay does not stall the crawl: the requests to its
+// host are spaced by max instead. It overrides the will of the site owners and should
+// only be used on the sites one is allowed to crawl that fast. A zero max means no cap
+func WithMaxCrawlDelay(max time.Duration) Option {
+	return func(c *crawler) {
+		c.maxCrawlDelay = max
+	}
+}
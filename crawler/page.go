@@ -0,0 +1,80 @@
+package crawler
+
+import (
+	"crypto/tls"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// Page is a crawled page
+type Page struct {
+	// URL is the address the page was fetched from
+	URL *url.URL
+	// Node is the root of the parsed html document
+	Node *html.Node
+	// StatusCode is the HTTP status code of the response,
+	// it is 0 when the page was not fetched by the crawler
+	StatusCode int
+	// Header holds the HTTP headers of the response
+	Header http.Header
+	// TLS holds the state of the TLS connection the page was
+	// fetched through, it is nil for plain HTTP
+	TLS *tls.ConnectionState
+	// Depth is the number of hops from the crawl base URL through which the
+	// page was first found, it is 0 for the base URL and the fetched URLs
+	Depth int
+	// Body is the raw response body the page was parsed from, it is only
+	// kept with WithRawBody and is nil for the responses that are not parsed
+	Body []byte
+}
+
+// Title returns the text of the first <title> element of the page
+// or an empty string if the page has no title
+func (p *Page) Title() string {
+	return getTitle(p.Node)
+}
+
+// Links returns all the anchors of the page along with their context
+// (see GetPageLinksWithContext)
+func (p *Page) Links() []Link {
+	return GetPageLinksWithContext(p.Node)
+}
+
+// AbsoluteLinks returns the distinct links of the page in their absolute
+// form, sorted alphabetically. Links that cannot be parsed are skipped
+func (p *Page) AbsoluteLinks() []*url.URL {
+	var links []*url.URL
+	resolver := NewLinkResolver(p.URL)
+	for link := range GetPageLinks(p.Node) {
+		absLink, err := resolver.Resolve(link)
+		if err != nil {
+			log.Errorf("failed to get absolute link on page %s with relative link %s", p.URL, link)
+			continue
+		}
+		links = append(links, absLink)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].String() < links[j].String()
+	})
+	return links
+}
+
+// getTitle scans node until it finds the title
+// element and returns its text
+func getTitle(node *html.Node) string {
+	if node == nil {
+		return ""
+	}
+	if node.Type == html.ElementNode && node.Data == "title" {
+		return collapseSpaces(nodeText(node))
+	}
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		if title := getTitle(n); title != "" {
+			return title
+		}
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package crawler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"strings"
+	"testing"
+)
+
+func Test_Page(t *testing.T) {
+	htmlStr := `<!doctype html>
+	            <html>
+				  <head><title> my page </title></head>
+				  <body>
+					<p><a href="page1.html">p1</a></p>
+					<p><a href="/index.html">index</a> and <a href="page1.html">p1 again</a></p>
+					<p><a href="https://another-web-site.com/">other</a></p>
+				  </body>
+			    </html>`
+	node, err := html.Parse(strings.NewReader(htmlStr))
+	assert.Nil(t, err)
+
+	p := &Page{URL: getURL("https://my-web-site.com/root/parent"), Node: node}
+
+	assert.Equal(t, "my page", p.Title())
+	assert.Len(t, p.Links(), 4)
+
+	var abs []string
+	for _, u := range p.AbsoluteLinks() {
+		abs = append(abs, u.String())
+	}
+	assert.Equal(t, []string{
+		"https://another-web-site.com/",
+		"https://my-web-site.com/index.html",
+		"https://my-web-site.com/root/page1.html",
+	}, abs)
+}
+
+func Test_Page_NoTitle(t *testing.T) {
+	node, err := html.Parse(strings.NewReader(`<html><body><p>no title</p></body></html>`))
+	assert.Nil(t, err)
+	assert.Equal(t, "", (&Page{URL: getURL("https://my-web-site.com"), Node: node}).Title())
+}
@@ -0,0 +1,49 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+)
+
+// pauseGate holds back the fetches while paused, the zero value is not paused
+type pauseGate struct {
+	mu sync.Mutex
+	// resumed is closed on resume, it is nil while not paused
+	resumed chan struct{}
+}
+
+// pause holds back the next calls to wait until resume is called
+func (g *pauseGate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed == nil {
+		g.resumed = make(chan struct{})
+	}
+}
+
+// resume releases the calls to wait held back by pause
+func (g *pauseGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		close(g.resumed)
+		g.resumed = nil
+	}
+}
+
+// wait blocks while the gate is paused, it returns false
+// if ctx is cancelled while waiting
+func (g *pauseGate) wait(ctx context.Context) bool {
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+	if resumed == nil {
+		return true
+	}
+	select {
+	case <-resumed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
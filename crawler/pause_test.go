@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_crawler_Crawl_PauseResume(t *testing.T) {
+	pages := map[string]string{
+		"/index.html": linksPage("index", "page1.html", "page2.html"),
+		"/page1.html": linksPage("page1"),
+		"/page2.html": linksPage("page2"),
+	}
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, pages[r.URL.Path])
+	}))
+	defer srv.Close()
+
+	store := NewMemoryStore()
+	c := NewCrawler(WithStore(store))
+	done := make(chan error)
+	go func() {
+		done <- c.Crawl(context.Background(), getURL(srv.URL+"/index.html"), func(u *url.URL, page *html.Node) {
+			// the crawler is paused once the index is fetched
+			if u.Path == "/index.html" {
+				c.Pause()
+			}
+		})
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("paused crawl returned")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	c.Resume()
+	assert.Nil(t, <-done)
+	assert.Equal(t, Stats{Pages: 3}, store.Stats())
+}
+
+func Test_crawler_Crawl_Paused_Cancelation(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html": linksPage("index"),
+	})
+
+	c := NewCrawler()
+	c.Pause()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	visited := false
+	err := c.Crawl(ctx, getURL(site.URL+"/index.html"), func(*url.URL, *html.Node) {
+		visited = true
+	})
+	assert.Nil(t, err)
+	assert.False(t, visited)
+}
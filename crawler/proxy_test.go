@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseProxyRotation(t *testing.T) {
+	r, err := ParseProxyRotation("round-robin")
+	assert.Nil(t, err)
+	assert.Equal(t, RoundRobin, r)
+	r, err = ParseProxyRotation("lru")
+	assert.Nil(t, err)
+	assert.Equal(t, LeastRecentlyUsed, r)
+	_, err = ParseProxyRotation("random")
+	assert.NotNil(t, err)
+}
+
+func TestProxyPool_pick(t *testing.T) {
+	a, b := getURL("http://proxy-a:3128"), getURL("http://proxy-b:3128")
+	base := &http.Transport{}
+
+	for _, rotation := range []ProxyRotation{RoundRobin, LeastRecentlyUsed} {
+		pool := NewProxyPool([]*url.URL{a, b}, rotation)
+		var picked []*url.URL
+		for i := 0; i < 4; i++ {
+			p, _, err := pool.pick(base)
+			assert.Nil(t, err)
+			picked = append(picked, p.url)
+		}
+		assert.Equal(t, []*url.URL{a, b, a, b}, picked)
+	}
+}
+
+func TestProxyPool_done(t *testing.T) {
+	a, b := getURL("http://proxy-a:3128"), getURL("http://proxy-b:3128")
+	pool := NewProxyPool([]*url.URL{a, b}, RoundRobin)
+	base := &http.Transport{}
+
+	// a proxy is evicted after maxProxyFailures failures in a row
+	p, _, _ := pool.pick(base)
+	for i := 0; i < maxProxyFailures-1; i++ {
+		pool.done(p, true)
+	}
+	pool.done(p, false)
+	pool.done(p, true)
+	assert.Equal(t, []*url.URL{a, b}, pool.Proxies())
+	for i := 0; i < maxProxyFailures-1; i++ {
+		pool.done(p, true)
+	}
+	assert.Equal(t, []*url.URL{b}, pool.Proxies())
+
+	p, _, _ = pool.pick(base)
+	assert.Equal(t, b, p.url)
+	for i := 0; i < maxProxyFailures; i++ {
+		pool.done(p, true)
+	}
+	_, _, err := pool.pick(base)
+	assert.True(t, errors.Is(err, ErrNoProxy))
+}
+
+func Test_crawler_CrawlPages_WithProxyPool(t *testing.T) {
+	// the proxies serve the pages themselves
+	newProxy := func(requests *int32) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(requests, 1)
+			if r.URL.Path == "/index.html" {
+				fmt.Fprint(w, linksPage("index", "p1.html", "p2.html", "p3.html"))
+				return
+			}
+			fmt.Fprint(w, linksPage(r.URL.Path))
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	var requestsA, requestsB int32
+	a, b := newProxy(&requestsA), newProxy(&requestsB)
+
+	pool := NewProxyPool([]*url.URL{getURL(a.URL), getURL(b.URL)}, RoundRobin)
+	store := NewMemoryStore()
+	err := NewCrawler(WithProxyPool(pool), WithStore(store)).CrawlPages(context.Background(), getURL("http://my-web-site.com/index.html"), func(*Page) {})
+	assert.Nil(t, err)
+	assert.Equal(t, Stats{Pages: 4}, store.Stats())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requestsA))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requestsB))
+}
@@ -0,0 +1,110 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket: it holds up to burst tokens, refilled
+// at rate tokens per second, and every request consumes one token
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	// now is the clock of the limiter, overridden by tests
+	now func() time.Time
+}
+
+// newRateLimiter returns a rateLimiter allowing rate requests per second
+// with bursts of burst requests, the bucket starts full
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+// reserve consumes a token and returns how long the request must wait for
+// it. The token is taken even if not yet available, so that the concurrent
+// requests are queued and served in turns
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// refill adds the tokens accumulated since the last refill, up to the burst
+func (l *rateLimiter) refill() {
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
+
+// setRate changes the rate of the limiter, the tokens accumulated
+// so far are kept
+func (l *rateLimiter) setRate(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.rate = rate
+}
+
+// currentRate returns the rate of the limiter
+func (l *rateLimiter) currentRate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// cancel gives back a token taken by reserve that was not used
+func (l *rateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+}
+
+// wait blocks until a token is available, it returns the error
+// of ctx if it is cancelled while waiting
+func (l *rateLimiter) wait(ctx context.Context) error {
+	d := l.reserve()
+	if d == 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// rateLimitedTransport is a http.RoundTripper waiting for a token of
+// its limiter before handing every request to the next transport
+type rateLimitedTransport struct {
+	next    http.RoundTripper
+	limiter *rateLimiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
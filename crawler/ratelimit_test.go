@@ -0,0 +1,57 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func Test_rateLimiter_reserve(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(10, 2)
+	l.now = func() time.Time { return now }
+
+	// the burst is served right away, then the requests are queued
+	assert.Equal(t, time.Duration(0), l.reserve())
+	assert.Equal(t, time.Duration(0), l.reserve())
+	assert.Equal(t, 100*time.Millisecond, l.reserve())
+	assert.Equal(t, 200*time.Millisecond, l.reserve())
+
+	// the bucket refills at the rate, up to the burst
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Duration(0), l.reserve())
+	assert.Equal(t, time.Duration(0), l.reserve())
+	assert.Equal(t, 100*time.Millisecond, l.reserve())
+}
+
+func Test_rateLimiter_wait_Cancelation(t *testing.T) {
+	l := newRateLimiter(0.1, 1)
+	assert.Nil(t, l.wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.wait(ctx))
+	// the token of the cancelled request is given back
+	assert.InDelta(t, 0, l.tokens, 0.01)
+}
+
+func Test_crawler_Crawl_WithRateLimit(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html": linksPage("index", "p1.html", "p2.html", "p3.html", "p4.html"),
+		"/p1.html":    linksPage("p1"),
+		"/p2.html":    linksPage("p2"),
+		"/p3.html":    linksPage("p3"),
+		"/p4.html":    linksPage("p4"),
+	})
+
+	store := NewMemoryStore()
+	start := time.Now()
+	err := NewCrawler(WithRateLimit(50, 1), WithStore(store)).Crawl(context.Background(), getURL(site.URL+"/index.html"), func(*url.URL, *html.Node) {})
+	assert.Nil(t, err)
+	assert.Equal(t, Stats{Pages: 5}, store.Stats())
+	// 5 requests at 50 requests per second take at least 80ms
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(75*time.Millisecond))
+}
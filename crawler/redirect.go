@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"net/http"
+)
+
+// defaultMaxRedirects is the number of redirects followed by net/http
+const defaultMaxRedirects = 10
+
+// RedirectPolicy configures how the crawler follows the redirects (see
+// WithRedirectPolicy). A redirect that is not followed is the response of the
+// page, reported with its 3xx status code
+type RedirectPolicy struct {
+	// MaxRedirects is the maximum number of redirects followed for a page, 0
+	// means the default of net/http (10) and a negative value that none is
+	MaxRedirects int
+	// CrossDomain makes the crawler follow the redirects to another host
+	CrossDomain bool
+	// FinalURL makes the crawler use the URL a page was redirected to, instead of
+	// the requested one, as the URL of the page: it is reported under it, it is
+	// not visited again if it was already crawled and its links are resolved
+	// against it. The links of a page redirected to another host are not followed
+	FinalURL bool
+}
+
+// checkRedirect is the http.Client CheckRedirect function of p
+func (p RedirectPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	max := p.MaxRedirects
+	if max == 0 {
+		max = defaultMaxRedirects
+	}
+	if len(via) > max {
+		return http.ErrUseLastResponse
+	}
+	if !p.CrossDomain && !isSameDomain(via[0].URL, req.URL) {
+		return http.ErrUseLastResponse
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func Test_crawler_CrawlPages_WithRedirectPolicy(t *testing.T) {
+	// the other site is on another host
+	other := newTestSite(t, map[string]string{
+		"/index.html": linksPage("other", "page1.html"),
+		"/page1.html": linksPage("other page1"),
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, linksPage("index", "old.html", "final.html", "away.html"))
+		case "/old.html":
+			http.Redirect(w, r, "/final.html", http.StatusMovedPermanently)
+		case "/final.html":
+			fmt.Fprint(w, linksPage("final"))
+		case "/away.html":
+			http.Redirect(w, r, other.URL+"/index.html", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := map[string]struct {
+		opts []Option
+		// want are the crawled pages as path (or host for the other site) and status code
+		want []string
+	}{
+		"default": {
+			// the links of the other site are resolved against the requested URL
+			want: []string{"/away.html 200", "/final.html 200", "/index.html 200", "/old.html 200", "/page1.html 404"},
+		},
+		"not_followed": {
+			opts: []Option{WithRedirectPolicy(RedirectPolicy{MaxRedirects: -1})},
+			want: []string{"/away.html 302", "/final.html 200", "/index.html 200", "/old.html 301"},
+		},
+		"same_domain_only": {
+			opts: []Option{WithRedirectPolicy(RedirectPolicy{})},
+			want: []string{"/away.html 302", "/final.html 200", "/index.html 200", "/old.html 200"},
+		},
+		"final_url": {
+			opts: []Option{WithRedirectPolicy(RedirectPolicy{CrossDomain: true, FinalURL: true})},
+			// the links of the other site are not followed
+			want: []string{"/final.html 200", "/index.html 200", getURL(other.URL).Host + " 200"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var got []string
+			err := NewCrawler(tt.opts...).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+				mu.Lock()
+				defer mu.Unlock()
+				page := p.URL.Path
+				if p.URL.Host != getURL(srv.URL).Host {
+					page = p.URL.Host
+				}
+				got = append(got, fmt.Sprintf("%s %d", page, p.StatusCode))
+			})
+			assert.Nil(t, err)
+			sort.Strings(got)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRedirectPolicy_checkRedirect(t *testing.T) {
+	via := func(n int) []*http.Request {
+		reqs := make([]*http.Request, n)
+		for i := range reqs {
+			reqs[i], _ = http.NewRequest(http.MethodGet, "https://my-web-site.com/", nil)
+		}
+		return reqs
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://my-web-site.com/page.html", nil)
+	assert.Nil(t, RedirectPolicy{}.checkRedirect(req, via(defaultMaxRedirects)))
+	assert.Equal(t, http.ErrUseLastResponse, RedirectPolicy{}.checkRedirect(req, via(defaultMaxRedirects+1)))
+	assert.Nil(t, RedirectPolicy{MaxRedirects: 2}.checkRedirect(req, via(2)))
+	assert.Equal(t, http.ErrUseLastResponse, RedirectPolicy{MaxRedirects: 2}.checkRedirect(req, via(3)))
+}
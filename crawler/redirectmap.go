@@ -0,0 +1,206 @@
+package crawler
+
+import (
+	"context"
+	"encoding/csv"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// redirectMapHeader is the header row of the CSV written by RedirectMap.WriteTo
+var redirectMapHeader = []string{"source", "destination", "status", "final_status", "hops"}
+
+// RedirectMapRow is an entry of a redirect map
+type RedirectMapRow struct {
+	// Source is the url of the crawled page
+	Source string
+	// Destination is the url the page is finally redirected to, Source for
+	// the pages that are not redirected
+	Destination string
+	// Status is the status code the page is answered with
+	Status int
+	// FinalStatus is the status code of Destination, it is 0 when the redirects
+	// of the page loop or Destination was not crawled and cannot be fetched (see
+	// RedirectMap.Resolve)
+	FinalStatus int
+	// Hops is the number of redirects from Source to Destination
+	Hops int
+}
+
+// redirectMapEntry is the response of a crawled or fetched page
+type redirectMapEntry struct {
+	status int
+	// location is the normalized URL the page is redirected to, empty
+	// when it is not redirected
+	location string
+}
+
+// RedirectMap collects the crawled pages along with the URL they are finally
+// redirected to, e.g. to build the redirect map of a site migration. Its Visit
+// method can be used as the visit function of Crawler.CrawlPages, the crawler
+// following no redirect (see RedirectPolicy.MaxRedirects) so that the response
+// of each page is visited. Once the crawl is done, Resolve fetches the redirect
+// destinations that were not crawled. It is safe for concurrent use
+type RedirectMap struct {
+	client *http.Client
+
+	mut     sync.Mutex
+	entries map[string]redirectMapEntry
+	// crawled are the urls of the visited pages
+	crawled map[string]bool
+}
+
+// NewRedirectMap creates an empty RedirectMap, the redirect destinations that were
+// not crawled are fetched with client, e.g. the one built by NewHTTPClient, without
+// following their redirects. A nil client means http.DefaultClient
+func NewRedirectMap(client *http.Client) *RedirectMap {
+	return &RedirectMap{client: auditClient(client, true), entries: make(map[string]redirectMapEntry), crawled: make(map[string]bool)}
+}
+
+// Visit adds the page to the redirect map
+func (m *RedirectMap) Visit(p *Page) {
+	u := Normalize(p.URL).String()
+	entry := redirectMapEntry{status: p.StatusCode, location: redirectLocation(p.URL, p.StatusCode, p.Header)}
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.entries[u] = entry
+	m.crawled[u] = true
+}
+
+// Resolve fetches the redirect destinations that were not crawled, hop by hop,
+// until the destination of every page is known. It returns the error of ctx
+// if it is done before, the destinations that cannot be fetched are answered
+// with a 0 status code
+func (m *RedirectMap) Resolve(ctx context.Context) error {
+	// a redirect chain longer than defaultMaxRedirects is not followed
+	for hop := 0; hop <= defaultMaxRedirects; hop++ {
+		m.mut.Lock()
+		pending := m.pending()
+		m.mut.Unlock()
+		if len(pending) == 0 {
+			return nil
+		}
+		// the destinations are fetched without holding the lock
+		fetched := make(map[string]redirectMapEntry, len(pending))
+		for _, u := range pending {
+			entry, err := m.fetch(ctx, u)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				log.Warnf("error while fetching redirect destination %s - %v", u, err)
+			}
+			fetched[u] = entry
+		}
+		m.mut.Lock()
+		for u, entry := range fetched {
+			if _, ok := m.entries[u]; !ok {
+				m.entries[u] = entry
+			}
+		}
+		m.mut.Unlock()
+	}
+	return nil
+}
+
+// pending returns the sorted redirect destinations whose response is unknown
+func (m *RedirectMap) pending() []string {
+	seen := make(map[string]bool)
+	var pending []string
+	for _, entry := range m.entries {
+		if _, ok := m.entries[entry.location]; entry.location != "" && !ok && !seen[entry.location] {
+			seen[entry.location] = true
+			pending = append(pending, entry.location)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// fetch gets the response of the page at the normalized URL u
+func (m *RedirectMap) fetch(ctx context.Context, u string) (redirectMapEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return redirectMapEntry{}, err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return redirectMapEntry{}, err
+	}
+	resp.Body.Close()
+	return redirectMapEntry{status: resp.StatusCode, location: redirectLocation(resp.Request.URL, resp.StatusCode, resp.Header)}, nil
+}
+
+// Rows returns the entries of the redirect map sorted by source, following the
+// redirects of each page to its final destination. The destinations that were
+// neither crawled nor resolved have a 0 final status code
+func (m *RedirectMap) Rows() []RedirectMapRow {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	rows := make([]RedirectMapRow, 0, len(m.crawled))
+	for u := range m.crawled {
+		rows = append(rows, m.follow(u))
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Source < rows[j].Source
+	})
+	return rows
+}
+
+// follow walks the redirects from the crawled page at u
+func (m *RedirectMap) follow(u string) RedirectMapRow {
+	row := RedirectMapRow{Source: u, Destination: u, Status: m.entries[u].status}
+	seen := map[string]bool{u: true}
+	for entry := m.entries[u]; entry.location != ""; {
+		row.Destination = entry.location
+		row.Hops++
+		if seen[entry.location] || row.Hops > defaultMaxRedirects {
+			// the redirects loop, or are too many to be followed
+			row.FinalStatus = 0
+			return row
+		}
+		seen[entry.location] = true
+		// the destinations not resolved are unknown
+		entry = m.entries[entry.location]
+		row.FinalStatus = entry.status
+	}
+	if row.Hops == 0 {
+		row.FinalStatus = row.Status
+	}
+	return row
+}
+
+// WriteTo writes the redirect map into w as CSV, with a header row
+func (m *RedirectMap) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	enc := csv.NewWriter(cw)
+	if err := enc.Write(redirectMapHeader); err != nil {
+		return cw.n, err
+	}
+	for _, row := range m.Rows() {
+		record := []string{row.Source, row.Destination, strconv.Itoa(row.Status), strconv.Itoa(row.FinalStatus), strconv.Itoa(row.Hops)}
+		if err := enc.Write(record); err != nil {
+			return cw.n, err
+		}
+	}
+	enc.Flush()
+	return cw.n, enc.Error()
+}
+
+// redirectLocation returns the normalized URL a response of the page at u
+// redirects to, an empty string if it is not a redirect
+func redirectLocation(u *url.URL, status int, header http.Header) string {
+	if status < http.StatusMultipleChoices || status >= http.StatusBadRequest || header.Get("Location") == "" {
+		return ""
+	}
+	location, err := u.Parse(header.Get("Location"))
+	if err != nil {
+		return ""
+	}
+	return Normalize(location).String()
+}
@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_RedirectMap_Crawl(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			w.Write([]byte(linksPage("index", "old.html", "chain.html", "loop-a.html", "gone.html", "new.html")))
+		case "/new.html":
+			w.Write([]byte(linksPage("new")))
+		case "/old.html":
+			http.Redirect(w, r, "/new.html", http.StatusMovedPermanently)
+		case "/chain.html":
+			http.Redirect(w, r, "/old.html", http.StatusFound)
+		case "/gone.html":
+			http.Redirect(w, r, "/deleted.html", http.StatusMovedPermanently)
+		case "/loop-a.html":
+			http.Redirect(w, r, "/loop-b.html", http.StatusMovedPermanently)
+		case "/loop-b.html":
+			http.Redirect(w, r, "/loop-a.html", http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	m := NewRedirectMap(srv.Client())
+	c := NewCrawler(WithRedirectPolicy(RedirectPolicy{MaxRedirects: -1}))
+	err := c.CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), m.Visit)
+	assert.Nil(t, err)
+	assert.Nil(t, m.Resolve(context.Background()))
+	u := func(path string) string { return srv.URL + path }
+	// the redirect targets are crawled through the link of the redirect bodies
+	assert.Equal(t, []RedirectMapRow{
+		{Source: u("/chain.html"), Destination: u("/new.html"), Status: 302, FinalStatus: 200, Hops: 2},
+		{Source: u("/deleted.html"), Destination: u("/deleted.html"), Status: 404, FinalStatus: 404},
+		{Source: u("/gone.html"), Destination: u("/deleted.html"), Status: 301, FinalStatus: 404, Hops: 1},
+		{Source: u("/index.html"), Destination: u("/index.html"), Status: 200, FinalStatus: 200},
+		{Source: u("/loop-a.html"), Destination: u("/loop-a.html"), Status: 301, Hops: 2},
+		{Source: u("/loop-b.html"), Destination: u("/loop-b.html"), Status: 301, Hops: 2},
+		{Source: u("/new.html"), Destination: u("/new.html"), Status: 200, FinalStatus: 200},
+		{Source: u("/old.html"), Destination: u("/new.html"), Status: 301, FinalStatus: 200, Hops: 1},
+	}, m.Rows())
+
+	var b strings.Builder
+	n, err := m.WriteTo(&b)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(b.Len()), n)
+	assert.Equal(t, "source,destination,status,final_status,hops\n", strings.SplitAfter(b.String(), "\n")[0])
+	assert.Contains(t, b.String(), u("/old.html")+","+u("/new.html")+",301,200,1\n")
+}
+
+func Test_RedirectMap_FetchesDestinations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved.html" {
+			http.Redirect(w, r, "/new.html", http.StatusMovedPermanently)
+		}
+	}))
+	defer srv.Close()
+
+	m := NewRedirectMap(srv.Client())
+	m.Visit(&Page{URL: getURL(srv.URL + "/old.html"), StatusCode: 308, Header: http.Header{"Location": {"moved.html"}}})
+	// the destinations are only fetched by Resolve
+	assert.Equal(t, []RedirectMapRow{
+		{Source: srv.URL + "/old.html", Destination: srv.URL + "/moved.html", Status: 308, Hops: 1},
+	}, m.Rows())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, m.Resolve(ctx))
+
+	assert.Nil(t, m.Resolve(context.Background()))
+	assert.Equal(t, []RedirectMapRow{
+		{Source: srv.URL + "/old.html", Destination: srv.URL + "/new.html", Status: 308, FinalStatus: 200, Hops: 2},
+	}, m.Rows())
+}
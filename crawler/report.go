@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditRequestTimeout bounds the requests issued by the reports once the crawl is
+// done, unless the client they are given has its own timeout
+const auditRequestTimeout = 30 * time.Second
+
+// Report is an audit of a whole crawl, e.g. to detect the issues spanning several
+// pages. Its findings are reported at once when the crawl is done, unlike the ones
+// of a Validator. Consume is called concurrently, implementations must be safe for
+// concurrent use
+type Report interface {
+	// Consume audits a crawled page
+	Consume(p *Page)
+	// Finalize returns the findings of the consumed pages, it is called
+	// by the user of the crawler once the crawl is done
+	Finalize() ([]Finding, error)
+}
+
+// uniqueTitle is the Report built by UniqueTitle
+type uniqueTitle struct {
+	mut    sync.Mutex
+	titles map[string][]string
+}
+
+// UniqueTitle builds a Report flagging the pages answered with a 2xx status code
+// sharing their title with other pages. Pages without a title are ignored
+func UniqueTitle() Report {
+	return &uniqueTitle{titles: make(map[string][]string)}
+}
+
+func (r *uniqueTitle) Consume(p *Page) {
+	if p.StatusCode < http.StatusOK || p.StatusCode >= http.StatusMultipleChoices {
+		return
+	}
+	title := strings.TrimSpace(p.Title())
+	if title == "" {
+		return
+	}
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.titles[title] = append(r.titles[title], p.URL.String())
+}
+
+func (r *uniqueTitle) Finalize() ([]Finding, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	var findings []Finding
+	for title, urls := range r.titles {
+		if len(urls) < 2 {
+			continue
+		}
+		for _, u := range urls {
+			findings = append(findings, Finding{URL: u, Validator: "unique-title", Message: fmt.Sprintf("title %q shared with %d other pages", title, len(urls)-1), Severity: SeverityWarning})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].URL < findings[j].URL
+	})
+	return findings, nil
+}
+
+// auditClient returns a copy of client, http.DefaultClient when nil, for the requests
+// of a report: they are bounded by auditRequestTimeout when client has no timeout and,
+// with noRedirect, the redirects are not followed so that they can be reported
+func auditClient(client *http.Client, noRedirect bool) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	audit := *client
+	if audit.Timeout == 0 {
+		audit.Timeout = auditRequestTimeout
+	}
+	if noRedirect {
+		audit.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return &audit
+}
+
+// doRequest issues a request of a report without body to u with client, its
+// response being abandoned once ctx is done
+func doRequest(ctx context.Context, client *http.Client, method, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
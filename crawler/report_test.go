@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUniqueTitle(t *testing.T) {
+	r := UniqueTitle()
+	r.Consume(newPage(t, "https://my-web-site.com/a", 200, nil, "<html><head><title>Home</title></head></html>"))
+	r.Consume(newPage(t, "https://my-web-site.com/b", 200, nil, "<html><head><title> Home </title></head></html>"))
+	r.Consume(newPage(t, "https://my-web-site.com/c", 200, nil, "<html><head><title>About</title></head></html>"))
+	r.Consume(newPage(t, "https://my-web-site.com/d", 404, nil, "<html><head><title>Home</title></head></html>"))
+	r.Consume(newPage(t, "https://my-web-site.com/e", 200, nil, "<html></html>"))
+	r.Consume(newPage(t, "https://my-web-site.com/f", 200, nil, "<html></html>"))
+
+	findings, err := r.Finalize()
+	assert.Nil(t, err)
+	assert.Equal(t, []Finding{
+		{URL: "https://my-web-site.com/a", Validator: "unique-title", Message: `title "Home" shared with 1 other pages`, Severity: SeverityWarning},
+		{URL: "https://my-web-site.com/b", Validator: "unique-title", Message: `title "Home" shared with 1 other pages`, Severity: SeverityWarning},
+	}, findings)
+}
+
+func Test_crawler_CrawlPages_WithReports(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html": linksPage("index", "page1.html", "page2.html"),
+		"/page1.html": linksPage("page"),
+		"/page2.html": linksPage("page"),
+	})
+
+	r := UniqueTitle()
+	err := NewCrawler(WithReports(r), WithParseConcurrency(1, 1)).CrawlPages(context.Background(), getURL(site.URL+"/index.html"), func(*Page) {})
+	assert.Nil(t, err)
+	findings, err := r.Finalize()
+	assert.Nil(t, err)
+	assert.Len(t, findings, 2)
+}
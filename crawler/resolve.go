@@ -0,0 +1,77 @@
+package crawler
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// LinkResolver transforms the links of a page in absolute URIs. It computes
+// the page directory once and resolves the bare relative links, the vast
+// majority on most pages, without parsing them, so it should be preferred
+// over GetLinkAbsoluteUrl when resolving all the links of a page
+type LinkResolver struct {
+	parent *url.URL
+	dir    string
+	// plainDir reports whether dir is a rooted path that needs no escaping
+	plainDir bool
+}
+
+// NewLinkResolver returns a LinkResolver for the links found in the parent page
+func NewLinkResolver(parent *url.URL) LinkResolver {
+	dir := path.Dir(parent.Path)
+	return LinkResolver{
+		parent:   parent,
+		dir:      dir,
+		plainDir: parent.Opaque == "" && strings.HasPrefix(dir, "/") && isPlainPath(dir),
+	}
+}
+
+// Resolve returns the absolute form of link, with the same semantics as
+// GetLinkAbsoluteUrl
+func (r LinkResolver) Resolve(link string) (*url.URL, error) {
+	// a bare relative path joined to a plain directory is already clean
+	// and rooted, so that it is the path of the resolved URL
+	if r.plainDir && link != "" && link[0] != '/' && isPlainPath(link) {
+		return &url.URL{
+			Scheme: r.parent.Scheme,
+			User:   r.parent.User,
+			Host:   r.parent.Host,
+			Path:   path.Join(r.dir, link),
+		}, nil
+	}
+
+	l, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+
+	// if link is already an absolute link
+	if l.IsAbs() {
+		return l, nil
+	}
+
+	// if link is relative to server root
+	if strings.HasPrefix(link, "/") {
+		return r.parent.ResolveReference(l), nil
+	}
+
+	// if link is relative to parent page uri
+	return r.parent.Parse(path.Join(r.dir, link))
+}
+
+// isPlainPath reports whether s is made only of the characters that url.URL
+// keeps unescaped in a path, excluding the ':' that could make it look like a
+// scheme and the '%', '?' and '#' that url.Parse would interpret
+func isPlainPath(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("-._~/$&+,;=@", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
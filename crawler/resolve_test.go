@@ -0,0 +1,118 @@
+package crawler
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+)
+
+// parseAbsoluteUrl is the original single link resolution (one url.Parse of
+// the link plus one of the joined path), kept as reference for LinkResolver
+func parseAbsoluteUrl(parent *url.URL, link string) (*url.URL, error) {
+	r, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	if r.IsAbs() {
+		return r, nil
+	}
+	if strings.HasPrefix(link, "/") {
+		return parent.Parse(link)
+	}
+	return parent.Parse(path.Join(path.Dir(parent.Path), link))
+}
+
+func TestLinkResolver_Resolve(t *testing.T) {
+	parents := []string{
+		"https://my-web-site.com",
+		"https://my-web-site.com/",
+		"https://my-web-site.com/test",
+		"https://my-web-site.com/a/b/page.html?q=1#top",
+		"https://my-web-site.com/a%20b/c%2Fd/",
+		"https://my-web-site.com/a b/:c/",
+		"https://my-web-site.com/it's(1)/",
+		"mailto:someone@my-web-site.com",
+		"https://user:pw@my-web-site.com:8443/a/b",
+	}
+	links := []string{
+		"i/business",
+		"/i/business",
+		"https://other.com/x",
+		"//cdn.my-web-site.com/app.js",
+		"../up",
+		"./here/../there",
+		"page?x=1&y=2",
+		"page#section",
+		"page?",
+		"a%20b",
+		"a b",
+		"it's(1)!*",
+		"a&b=c;d,e+f@g$h~i",
+		"é/ü",
+		"a:b",
+		":colon",
+		"",
+		"?only=query",
+		"#only-fragment",
+		"://my-web-site.com/i/business",
+	}
+	for _, p := range parents {
+		parent, err := url.Parse(p)
+		assert.Nil(t, err)
+		resolver := NewLinkResolver(parent)
+		for _, link := range links {
+			t.Run(fmt.Sprintf("%s|%s", p, link), func(t *testing.T) {
+				want, wantErr := parseAbsoluteUrl(parent, link)
+				got, err := resolver.Resolve(link)
+				assert.Equal(t, wantErr != nil, err != nil)
+				assert.Equal(t, want, got)
+			})
+		}
+	}
+}
+
+// pageLinks returns n relative links as found on a large listing page
+func pageLinks(n int) []string {
+	links := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		switch i % 4 {
+		case 0:
+			links = append(links, fmt.Sprintf("item-%d.html", i))
+		case 1:
+			links = append(links, fmt.Sprintf("/catalog/category-%d/item-%d", i%50, i))
+		case 2:
+			links = append(links, fmt.Sprintf("../related/item-%d", i))
+		default:
+			links = append(links, fmt.Sprintf("https://my-web-site.com/item/%d?ref=listing", i))
+		}
+	}
+	return links
+}
+
+func BenchmarkGetLinkAbsoluteUrl(b *testing.B) {
+	parent, _ := url.Parse("https://my-web-site.com/catalog/listing/page.html")
+	links := pageLinks(5000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, link := range links {
+			_, _ = parseAbsoluteUrl(parent, link)
+		}
+	}
+}
+
+func BenchmarkLinkResolver_Resolve(b *testing.B) {
+	parent, _ := url.Parse("https://my-web-site.com/catalog/listing/page.html")
+	links := pageLinks(5000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resolver := NewLinkResolver(parent)
+		for _, link := range links {
+			_, _ = resolver.Resolve(link)
+		}
+	}
+}
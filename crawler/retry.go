@@ -0,0 +1,51 @@
+package crawler
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures how the crawler retries the requests failing with a
+// network error (e.g. DNS resolution or connection failures, timeouts set by
+// WithRequestTimeout). Responses, even with an error status code, are not
+// retried
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts of a request, first one included
+	Attempts int
+	// BaseDelay is the delay before the first retry, doubled at each retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts, 0 means no cap
+	MaxDelay time.Duration
+	// Jitter, between 0 and 1, is the fraction of each delay that is randomized
+	// so that failed requests are not retried all at once
+	Jitter float64
+}
+
+// delay returns the delay before the retry following the failed attempt
+// number attempt (starting from 1)
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay == 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(p.Jitter * rand.Float64() * float64(d))
+	}
+	return d
+}
+
+// sleep waits d, it returns false if ctx is cancelled while waiting
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
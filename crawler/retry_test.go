@@ -0,0 +1,131 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_delay(t *testing.T) {
+	tests := map[string]struct {
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		"first_retry": {
+			policy:  RetryPolicy{BaseDelay: 100 * time.Millisecond},
+			attempt: 1,
+			want:    100 * time.Millisecond,
+		},
+		"exponential": {
+			policy:  RetryPolicy{BaseDelay: 100 * time.Millisecond},
+			attempt: 4,
+			want:    800 * time.Millisecond,
+		},
+		"capped": {
+			policy:  RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 250 * time.Millisecond},
+			attempt: 3,
+			want:    250 * time.Millisecond,
+		},
+		"capped_many_retries": {
+			policy:  RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute},
+			attempt: 100,
+			want:    time.Minute,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.delay(tt.attempt))
+		})
+	}
+}
+
+func TestRetryPolicy_delay_Jitter(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		d := p.delay(1)
+		assert.GreaterOrEqual(t, int64(d), int64(50*time.Millisecond))
+		assert.LessOrEqual(t, int64(d), int64(100*time.Millisecond))
+	}
+}
+
+func Test_crawler_Crawl_WithRetry(t *testing.T) {
+	tests := map[string]struct {
+		policy RetryPolicy
+		want   Stats
+	}{
+		"no_retry": {
+			want: Stats{Errors: 1},
+		},
+		"retried": {
+			policy: RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond},
+			want:   Stats{Pages: 1},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// the first two requests fail with a closed connection
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&requests, 1) <= 2 {
+					conn, _, err := w.(http.Hijacker).Hijack()
+					assert.Nil(t, err)
+					conn.Close()
+					return
+				}
+				fmt.Fprint(w, linksPage("index"))
+			}))
+			defer srv.Close()
+
+			store := NewMemoryStore()
+			err := NewCrawler(WithRetry(tt.policy), WithStore(store)).Crawl(context.Background(), getURL(srv.URL+"/index.html"), func(*url.URL, *html.Node) {})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, store.Stats())
+		})
+	}
+}
+
+func Test_crawler_Crawl_WithRequestTimeout(t *testing.T) {
+	tests := map[string]struct {
+		policy RetryPolicy
+		want   Stats
+	}{
+		"timed_out": {
+			want: Stats{Errors: 1},
+		},
+		"retried": {
+			policy: RetryPolicy{Attempts: 2, BaseDelay: time.Millisecond},
+			want:   Stats{Pages: 1},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// the first request hangs past the request timeout
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&requests, 1) == 1 {
+					<-r.Context().Done()
+					return
+				}
+				fmt.Fprint(w, linksPage("index"))
+			}))
+			defer srv.Close()
+
+			store := NewMemoryStore()
+			c := NewCrawler(WithRequestTimeout(50*time.Millisecond), WithRetry(tt.policy), WithStore(store))
+			err := c.Crawl(context.Background(), getURL(srv.URL+"/index.html"), func(*url.URL, *html.Node) {})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, store.Stats())
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRequeues is the maximum number of times a page answered with a
+// Retry-After header is requeued, past it the response is kept as is
+const maxRequeues = 3
+
+// maxRetryAfter is the longest Retry-After delay honored, a page asked to be
+// fetched again later than that is dropped rather than holding the crawl
+const maxRetryAfter = 5 * time.Minute
+
+// retryAfterError is returned when a page is answered with a 429 or 503
+// status code and a Retry-After header: the page is to be fetched again
+// once the delay elapsed
+type retryAfterError struct {
+	status int
+	delay  time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("status %d, retry after %s", e.status, e.delay)
+}
+
+// getRetryAfter returns the retryAfterError of r, nil if r is not
+// asking to retry later
+func getRetryAfter(r *http.Response) *retryAfterError {
+	if r.StatusCode != http.StatusTooManyRequests && r.StatusCode != http.StatusServiceUnavailable {
+		return nil
+	}
+	d, ok := parseRetryAfter(r.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return nil
+	}
+	return &retryAfterError{status: r.StatusCode, delay: d}
+}
+
+// parseRetryAfter parses the value of a Retry-After header, either a number
+// of seconds or an HTTP date, into a delay from now
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := at.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// hostPauses holds the hosts that asked to delay the requests until a given
+// time, the zero value has no paused host
+type hostPauses struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+// pause delays the requests to host by d, an earlier pause is only extended
+func (p *hostPauses) pause(host string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.until == nil {
+		p.until = make(map[string]time.Time)
+	}
+	until := time.Now().Add(d)
+	if until.After(p.until[host]) {
+		p.until[host] = until
+	}
+}
+
+// wait blocks until the requests to host are no longer paused, it returns
+// false if ctx is cancelled while waiting
+func (p *hostPauses) wait(ctx context.Context, host string) bool {
+	// the pause might be extended while waiting
+	for {
+		p.mu.Lock()
+		until := p.until[host]
+		p.mu.Unlock()
+		d := time.Until(until)
+		if d <= 0 {
+			return true
+		}
+		if !sleep(ctx, d) {
+			return false
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 4, 18, 20, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		"seconds": {
+			value:  "120",
+			want:   2 * time.Minute,
+			wantOk: true,
+		},
+		"http_date": {
+			value:  "Sun, 18 Apr 2021 20:00:30 GMT",
+			want:   30 * time.Second,
+			wantOk: true,
+		},
+		"http_date_in_the_past": {
+			value:  "Sun, 18 Apr 2021 19:00:00 GMT",
+			wantOk: true,
+		},
+		"missing": {},
+		"negative": {
+			value: "-1",
+		},
+		"invalid": {
+			value: "soon",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_hostPauses_wait(t *testing.T) {
+	var p hostPauses
+	assert.True(t, p.wait(context.Background(), "my-web-site.com"))
+
+	p.pause("my-web-site.com", 50*time.Millisecond)
+	start := time.Now()
+	assert.True(t, p.wait(context.Background(), "other-web-site.com"))
+	assert.Less(t, int64(time.Since(start)), int64(50*time.Millisecond))
+	assert.True(t, p.wait(context.Background(), "my-web-site.com"))
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(45*time.Millisecond))
+
+	p.pause("my-web-site.com", time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, p.wait(ctx, "my-web-site.com"))
+}
+
+func Test_crawler_CrawlPages_RetryAfter(t *testing.T) {
+	tests := map[string]struct {
+		// limited is the number of 429 responses sent before the page
+		limited    int32
+		wantStatus int
+		wantTitle  string
+		wantReqs   int32
+	}{
+		"requeued_until_served": {
+			limited:    2,
+			wantStatus: http.StatusOK,
+			wantTitle:  "index",
+			wantReqs:   3,
+		},
+		"kept_after_max_requeues": {
+			limited:    10,
+			wantStatus: http.StatusTooManyRequests,
+			wantReqs:   maxRequeues + 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&requests, 1) <= tt.limited {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				fmt.Fprint(w, linksPage("index"))
+			}))
+			defer srv.Close()
+
+			var mu sync.Mutex
+			var pages []*Page
+			err := NewCrawler().CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+				mu.Lock()
+				defer mu.Unlock()
+				pages = append(pages, p)
+			})
+			assert.Nil(t, err)
+			assert.Len(t, pages, 1)
+			assert.Equal(t, tt.wantStatus, pages[0].StatusCode)
+			assert.Equal(t, tt.wantTitle, pages[0].Title())
+			assert.Equal(t, tt.wantReqs, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func Test_crawler_CrawlPages_RetryAfter_TooLong(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			fmt.Fprint(w, linksPage("index", "/limited.html", "/page.html"))
+		case "/limited.html":
+			atomic.AddInt32(&requests, 1)
+			w.Header().Set("Retry-After", strconv.Itoa(int((maxRetryAfter + time.Second).Seconds())))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, linksPage("page"))
+		}
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var pages []string
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := NewCrawler(WithMaxConcurrency(1)).CrawlPages(ctx, getURL(srv.URL+"/index.html"), func(p *Page) {
+		mu.Lock()
+		defer mu.Unlock()
+		pages = append(pages, p.URL.Path)
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, ctx.Err())
+	// the page is dropped, without pausing its host
+	assert.ElementsMatch(t, []string{"/index.html", "/page.html"}, pages)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,347 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// robotsRule is an Allow or Disallow line of a robots.txt group
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsRules are the robots.txt rules applying to the crawler user agent
+type robotsRules struct {
+	rules []robotsRule
+	// delay is the Crawl-delay between two requests to the host
+	delay time.Duration
+	// disallowAll is set when the robots.txt could not be retrieved,
+	// in which case the whole site is considered disallowed
+	disallowAll bool
+}
+
+// allowed reports whether the rules allow fetching u. The longest matching
+// rule wins, an Allow wins over a Disallow of the same length
+func (r *robotsRules) allowed(u *url.URL) bool {
+	if r.disallowAll {
+		return false
+	}
+	target := u.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	allow, length := true, -1
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, target) {
+			continue
+		}
+		if len(rule.pattern) > length || (len(rule.pattern) == length && rule.allow) {
+			allow, length = rule.allow, len(rule.pattern)
+		}
+	}
+	return allow
+}
+
+// matchRobotsPattern reports whether the robots.txt path pattern matches from
+// the beginning of target. In the pattern '*' matches any sequence of
+// characters and a trailing '$' anchors the pattern to the end of target
+func matchRobotsPattern(pattern, target string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(target, parts[0]) {
+		return false
+	}
+	target = target[len(parts[0]):]
+	for i, part := range parts[1:] {
+		// the last part of an anchored pattern must end target
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(target, part)
+		}
+		idx := strings.Index(target, part)
+		if idx < 0 {
+			return false
+		}
+		target = target[idx+len(part):]
+	}
+	return !anchored || target == ""
+}
+
+// parseRobots parses a robots.txt and returns the rules of the group that
+// best matches userAgent: the group with the longest user-agent contained in
+// userAgent (case insensitive), or the `*` group if none matches
+func parseRobots(r io.Reader, userAgent string) (*robotsRules, error) {
+	userAgent = strings.ToLower(userAgent)
+	groups := make(map[string]*robotsRules)
+	// agents are the user agents of the group being parsed, inGroupRules
+	// reports whether its rules started so that a new user-agent starts a new group
+	var agents []string
+	inGroupRules := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		switch key {
+		case "user-agent":
+			if inGroupRules {
+				agents, inGroupRules = nil, false
+			}
+			agent := strings.ToLower(value)
+			agents = append(agents, agent)
+			if _, ok := groups[agent]; !ok {
+				groups[agent] = &robotsRules{}
+			}
+		case "allow", "disallow":
+			inGroupRules = true
+			// an empty disallow allows everything, which is the default
+			if value == "" {
+				continue
+			}
+			for _, agent := range agents {
+				groups[agent].rules = append(groups[agent].rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			inGroupRules = true
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				continue
+			}
+			for _, agent := range agents {
+				groups[agent].delay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	best, bestLen := groups["*"], 0
+	for agent, rules := range groups {
+		if agent != "*" && len(agent) > bestLen && strings.Contains(userAgent, agent) {
+			best, bestLen = rules, len(agent)
+		}
+	}
+	if best == nil {
+		return &robotsRules{}, nil
+	}
+	return best, nil
+}
+
+const (
+	// robotsAttempts is the maximum number of attempts of a robots.txt retrieval
+	// failing with a network or server error, the pages of the host waiting for it
+	robotsAttempts = 3
+	// robotsRetryDelay is the delay between two attempts of a robots.txt retrieval,
+	// and the delay the host is disallowed for once they all failed
+	robotsRetryDelay = 5 * time.Second
+)
+
+// robotsCache fetches and caches the robots.txt rules of each crawled host
+// for the lifetime of a crawl. A nil robotsCache allows every URL
+type robotsCache struct {
+	// ctx is the context of the crawl the robots.txt files are retrieved with,
+	// so that a retrieval shared by several pages is not bound to one of them
+	ctx    context.Context
+	client *http.Client
+	// userAgent is matched against the user-agent groups,
+	// header is the User-Agent of the robots.txt requests
+	userAgent string
+	header    string
+	// maxDelay, when positive, caps the Crawl-delay of the hosts
+	maxDelay time.Duration
+	// retryDelay is the delay between two attempts of a robots.txt retrieval,
+	// and the delay a failed retrieval is cached for
+	retryDelay time.Duration
+	// timeout, when positive, bounds each attempt of a robots.txt retrieval
+	timeout time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+// robotsEntry holds the rules of a host, fetch makes concurrent
+// visitors of a host wait for a single robots.txt retrieval
+type robotsEntry struct {
+	fetch sync.Mutex
+	rules *robotsRules
+	// retryAt, once all the attempts of a retrieval failed, is the time the
+	// robots.txt is retrieved again, the host being disallowed until then
+	retryAt time.Time
+
+	// next is the earliest time of the next request to the host
+	// honoring its Crawl-delay
+	mu   sync.Mutex
+	next time.Time
+}
+
+// newRobotsCache returns the robots.txt cache of the crawl of ctx, nil when
+// the crawler does not honor robots.txt
+func (c *crawler) newRobotsCache(ctx context.Context) *robotsCache {
+	if !c.robots {
+		return nil
+	}
+	return &robotsCache{
+		ctx:        ctx,
+		retryDelay: c.robotsRetryDelay,
+		timeout:    c.requestTimeout,
+		client:     c.httpClient(),
+		userAgent:  c.robotsUserAgent,
+		header:     c.userAgent,
+		maxDelay:   c.maxCrawlDelay,
+		hosts:      make(map[string]*robotsEntry),
+	}
+}
+
+// admit reports whether u can be fetched: its host robots.txt allows it and
+// its Crawl-delay was waited (see wait)
+func (rc *robotsCache) admit(ctx context.Context, u *url.URL) bool {
+	if !rc.allowed(u) {
+		log.Infof("skipping page %s disallowed by robots.txt", u)
+		return false
+	}
+	return rc.wait(ctx, u)
+}
+
+// allowed reports whether the robots.txt of the host of u allows fetching it
+func (rc *robotsCache) allowed(u *url.URL) bool {
+	if rc == nil {
+		return true
+	}
+	_, rules := rc.entry(u)
+	return rules.allowed(u)
+}
+
+// wait blocks until a request to the host of u honors the Crawl-delay of its
+// robots.txt, capped to maxDelay, reserving the request slot. It returns false
+// if ctx is cancelled while waiting
+func (rc *robotsCache) wait(ctx context.Context, u *url.URL) bool {
+	if rc == nil {
+		return true
+	}
+	entry, rules := rc.entry(u)
+	delay := rules.delay
+	if rc.maxDelay > 0 && delay > rc.maxDelay {
+		delay = rc.maxDelay
+	}
+	if delay == 0 {
+		return true
+	}
+	entry.mu.Lock()
+	now := time.Now()
+	at := entry.next
+	if at.Before(now) {
+		at = now
+	}
+	entry.next = at.Add(delay)
+	entry.mu.Unlock()
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// entry returns the entry of the host of u and its rules, retrieving its
+// robots.txt on the first call for the host, and again on the first call
+// past retryAt once the retrieval failed. The concurrent calls for the host
+// wait for the retrieval
+func (rc *robotsCache) entry(u *url.URL) (*robotsEntry, *robotsRules) {
+	key := u.Scheme + "://" + u.Host
+	rc.mu.Lock()
+	entry, ok := rc.hosts[key]
+	if !ok {
+		entry = &robotsEntry{}
+		rc.hosts[key] = entry
+	}
+	rc.mu.Unlock()
+
+	entry.fetch.Lock()
+	defer entry.fetch.Unlock()
+	if entry.rules == nil || !entry.retryAt.IsZero() && !time.Now().Before(entry.retryAt) {
+		rules, err := rc.retrieve(u)
+		entry.retryAt = time.Time{}
+		if err != nil {
+			// the failure may be transient, it is not cached for the whole crawl
+			log.Errorf("failed to get robots.txt of %s, the site is considered disallowed for %s - %v", key, rc.retryDelay, err)
+			rules = &robotsRules{disallowAll: true}
+			entry.retryAt = time.Now().Add(rc.retryDelay)
+		}
+		entry.rules = rules
+	}
+	return entry, entry.rules
+}
+
+// retrieve gets the robots.txt of the host of u with the crawl context, the
+// attempts failing with a network or server error are retried robotsAttempts
+// times, retryDelay apart
+func (rc *robotsCache) retrieve(u *url.URL) (*robotsRules, error) {
+	for attempt := 1; ; attempt++ {
+		rules, err := rc.get(rc.ctx, u)
+		if err == nil || attempt >= robotsAttempts || rc.ctx.Err() != nil {
+			return rules, err
+		}
+		log.Warnf("failed attempt %d to get robots.txt of %s://%s, retrying in %s - %v", attempt, u.Scheme, u.Host, rc.retryDelay, err)
+		if !sleep(rc.ctx, rc.retryDelay) {
+			return nil, err
+		}
+	}
+}
+
+// get retrieves and parses the robots.txt of the host of u, bounded by the
+// request timeout. A missing robots.txt (4xx status) allows everything, a
+// server error is an error
+func (rc *robotsCache) get(ctx context.Context, u *url.URL) (*robotsRules, error) {
+	if rc.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rc.timeout)
+		defer cancel()
+	}
+	robotsURL := &url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host, Path: "/robots.txt"}
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error while preparing request - %v", err)
+	}
+	if rc.header != "" {
+		req.Header.Set("User-Agent", rc.header)
+	}
+	r, err := rc.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting %s - %v", robotsURL, err)
+	}
+	defer r.Body.Close()
+	switch {
+	case r.StatusCode >= 500:
+		return nil, fmt.Errorf("error while getting %s - status %d", robotsURL, r.StatusCode)
+	case r.StatusCode >= 400:
+		return &robotsRules{}, nil
+	}
+	// robots.txt files are not expected to exceed 500KiB
+	return parseRobots(io.LimitReader(r.Body, 500<<10), rc.userAgent)
+}
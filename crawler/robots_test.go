@@ -0,0 +1,331 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_parseRobots(t *testing.T) {
+	robotsTxt := `# comment
+User-agent: *
+Disallow: /private/
+Allow: /private/public.html
+Disallow: /*.pdf$
+Disallow: /search?*q=
+
+User-agent: web-crawler
+User-agent: other-bot
+Disallow: /crawler-only/
+Disallow:
+`
+	tests := map[string]struct {
+		userAgent string
+		path      string
+		want      bool
+	}{
+		"no_matching_rule": {
+			path: "/index.html",
+			want: true,
+		},
+		"disallowed_prefix": {
+			path: "/private/page.html",
+			want: false,
+		},
+		"longest_allow_wins": {
+			path: "/private/public.html",
+			want: true,
+		},
+		"wildcard_anchored": {
+			path: "/docs/guide.pdf",
+			want: false,
+		},
+		"wildcard_anchored_not_at_end": {
+			path: "/docs/guide.pdf.html",
+			want: true,
+		},
+		"query_wildcard": {
+			path: "/search?lang=en&q=go",
+			want: false,
+		},
+		"query_not_matching": {
+			path: "/search?lang=en",
+			want: true,
+		},
+		"specific_group_replaces_star_group": {
+			userAgent: "Mozilla/5.0 (compatible; Web-Crawler/1.0)",
+			path:      "/private/page.html",
+			want:      true,
+		},
+		"specific_group_rule": {
+			userAgent: "web-crawler",
+			path:      "/crawler-only/page.html",
+			want:      false,
+		},
+		"specific_group_shared_by_agents": {
+			userAgent: "other-bot",
+			path:      "/crawler-only/page.html",
+			want:      false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rules, err := parseRobots(strings.NewReader(robotsTxt), tt.userAgent)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, rules.allowed(getURL("https://my-web-site.com"+tt.path)))
+		})
+	}
+}
+
+func Test_crawler_Crawl_WithRobotsTxt(t *testing.T) {
+	pages := map[string]string{
+		"/robots.txt":         "User-agent: *\nDisallow: /private/\n",
+		"/index.html":         linksPage("index", "page1.html", "private/page2.html"),
+		"/page1.html":         linksPage("page1", "private/page3.html"),
+		"/private/page2.html": linksPage("page2"),
+		"/private/page3.html": linksPage("page3"),
+	}
+	var robotsRequests int32
+	var fetched sync.Map
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			atomic.AddInt32(&robotsRequests, 1)
+		} else {
+			fetched.Store(r.URL.Path, true)
+		}
+		fmt.Fprint(w, pages[r.URL.Path])
+	}))
+	defer srv.Close()
+
+	store := NewMemoryStore()
+	var mu sync.Mutex
+	var titles []string
+	err := NewCrawler(WithRobotsTxt("web-crawler"), WithStore(store)).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+		mu.Lock()
+		defer mu.Unlock()
+		titles = append(titles, p.Title())
+	})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"index", "page1"}, titles)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&robotsRequests))
+	_, ok := fetched.Load("/private/page2.html")
+	assert.False(t, ok)
+	assert.Equal(t, Stats{Pages: 2}, store.Stats())
+}
+
+func Test_crawler_Crawl_WithRobotsTxt_ServerError(t *testing.T) {
+	var robotsRequests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			atomic.AddInt32(&robotsRequests, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, linksPage("index"))
+	}))
+	defer srv.Close()
+
+	c := newCrawler(WithRobotsTxt(""))
+	c.robotsRetryDelay = time.Millisecond
+	visited := false
+	err := c.CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+		visited = true
+	})
+	assert.Nil(t, err)
+	// the site is not crawled once all the attempts failed
+	assert.False(t, visited)
+	assert.Equal(t, int32(robotsAttempts), atomic.LoadInt32(&robotsRequests))
+}
+
+func Test_crawler_Crawl_WithRobotsTxt_TransientError(t *testing.T) {
+	var robotsRequests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			// the first retrieval fails
+			if atomic.AddInt32(&robotsRequests, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		case "/index.html":
+			fmt.Fprint(w, linksPage("index", "/page.html", "/private/page.html"))
+		default:
+			fmt.Fprint(w, linksPage(r.URL.Path))
+		}
+	}))
+	defer srv.Close()
+
+	c := newCrawler(WithRobotsTxt(""))
+	c.robotsRetryDelay = 10 * time.Millisecond
+	var mu sync.Mutex
+	var visited []string
+	err := c.CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+		mu.Lock()
+		defer mu.Unlock()
+		visited = append(visited, p.URL.Path)
+	})
+	assert.Nil(t, err)
+	// the seed waits for the retried retrieval, whose rules are honored
+	assert.ElementsMatch(t, []string{"/index.html", "/page.html"}, visited)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&robotsRequests))
+}
+
+func Test_robotsCache_TransientError(t *testing.T) {
+	var robotsRequests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first retrieval fails
+		if atomic.AddInt32(&robotsRequests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+	}))
+	defer srv.Close()
+
+	rc := &robotsCache{ctx: context.Background(), client: srv.Client(), retryDelay: time.Millisecond, hosts: make(map[string]*robotsEntry)}
+	assert.True(t, rc.allowed(getURL(srv.URL+"/index.html")))
+	assert.False(t, rc.allowed(getURL(srv.URL+"/private/page.html")))
+	// the rules retrieved are cached
+	assert.Equal(t, int32(2), atomic.LoadInt32(&robotsRequests))
+}
+
+func Test_robotsCache_Unavailable(t *testing.T) {
+	var robotsRequests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&robotsRequests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	rc := &robotsCache{ctx: context.Background(), client: srv.Client(), retryDelay: 100 * time.Millisecond, hosts: make(map[string]*robotsEntry)}
+	assert.False(t, rc.allowed(getURL(srv.URL+"/index.html")))
+	// the failure is cached until the retry delay elapsed
+	assert.False(t, rc.allowed(getURL(srv.URL+"/index.html")))
+	assert.Equal(t, int32(robotsAttempts), atomic.LoadInt32(&robotsRequests))
+
+	time.Sleep(rc.retryDelay)
+	assert.False(t, rc.allowed(getURL(srv.URL+"/index.html")))
+	assert.Equal(t, int32(2*robotsAttempts), atomic.LoadInt32(&robotsRequests))
+}
+
+func Test_robotsCache_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	rc := &robotsCache{ctx: context.Background(), client: srv.Client(), retryDelay: time.Millisecond, timeout: 20 * time.Millisecond, hosts: make(map[string]*robotsEntry)}
+	start := time.Now()
+	// a hanging robots.txt does not hold the pages of the host
+	assert.False(t, rc.allowed(getURL(srv.URL+"/index.html")))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func Test_crawler_Crawl_WithRobotsTxt_Missing(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html": linksPage("index"),
+	})
+
+	visited := false
+	err := NewCrawler(WithRobotsTxt("")).CrawlPages(context.Background(), getURL(site.URL+"/index.html"), func(p *Page) {
+		visited = true
+	})
+	assert.Nil(t, err)
+	assert.True(t, visited)
+}
+
+func Test_parseRobots_CrawlDelay(t *testing.T) {
+	robotsTxt := "User-agent: *\nCrawl-delay: 2.5\n\nUser-agent: web-crawler\nCrawl-delay: invalid\n"
+
+	rules, err := parseRobots(strings.NewReader(robotsTxt), "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2500*time.Millisecond, rules.delay)
+
+	rules, err = parseRobots(strings.NewReader(robotsTxt), "web-crawler")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), rules.delay)
+}
+
+func Test_crawler_Crawl_WithRobotsTxt_CrawlDelay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	pages := map[string]string{
+		"/robots.txt": "User-agent: *\nCrawl-delay: 0.05\n",
+		"/index.html": linksPage("index", "page1.html", "page2.html", "page3.html"),
+		"/page1.html": linksPage("page1"),
+		"/page2.html": linksPage("page2"),
+		"/page3.html": linksPage("page3"),
+	}
+	var mu sync.Mutex
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+		}
+		fmt.Fprint(w, pages[r.URL.Path])
+	}))
+	defer srv.Close()
+
+	err := NewCrawler(WithRobotsTxt("")).Crawl(context.Background(), getURL(srv.URL+"/index.html"), func(u *url.URL, page *html.Node) {})
+	assert.Nil(t, err)
+	assert.Len(t, times, 4)
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	for i := 1; i < len(times); i++ {
+		// allow for the timers jitter
+		assert.GreaterOrEqual(t, int64(times[i].Sub(times[i-1])), int64(delay-5*time.Millisecond))
+	}
+}
+
+func Test_crawler_Crawl_WithRobotsTxt_CrawlDelay_Cancelation(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/robots.txt": "User-agent: *\nCrawl-delay: 60\n",
+		"/index.html": linksPage("index", "page1.html"),
+		"/page1.html": linksPage("page1"),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var visited int32
+	err := NewCrawler(WithRobotsTxt("")).Crawl(ctx, getURL(site.URL+"/index.html"), func(u *url.URL, page *html.Node) {
+		atomic.AddInt32(&visited, 1)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&visited))
+}
+
+func Test_crawler_Crawl_WithMaxCrawlDelay(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/robots.txt": "User-agent: *\nCrawl-delay: 60\n",
+		"/index.html": linksPage("index", "page1.html", "page2.html"),
+		"/page1.html": linksPage("page1"),
+		"/page2.html": linksPage("page2"),
+	})
+
+	// the 60s Crawl-delay is capped so that the crawl completes
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var visited int32
+	start := time.Now()
+	err := NewCrawler(WithRobotsTxt(""), WithMaxCrawlDelay(20*time.Millisecond)).Crawl(ctx, getURL(site.URL+"/index.html"), func(u *url.URL, page *html.Node) {
+		atomic.AddInt32(&visited, 1)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&visited))
+	// the requests are still spaced by the cap, allowing for the timers jitter
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(2*(20*time.Millisecond-5*time.Millisecond)))
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+)
+
+// semaphore limits the number of concurrent holders of its slots, its limit
+// can be changed while in use. A nil semaphore has no limit
+type semaphore struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+	// changed is closed, and replaced, every time a slot is
+	// released or the limit changes to wake up the waiters
+	changed chan struct{}
+}
+
+// newSemaphore returns a semaphore with n slots, a value
+// lower than 1 means no limit
+func newSemaphore(n int) *semaphore {
+	return &semaphore{limit: n, changed: make(chan struct{})}
+}
+
+// acquire takes one of the slots of s, blocking until one is available.
+// It returns false if ctx is cancelled while waiting
+func (s *semaphore) acquire(ctx context.Context) bool {
+	if s == nil {
+		return true
+	}
+	for {
+		s.mu.Lock()
+		if s.limit < 1 || s.used < s.limit {
+			s.used++
+			s.mu.Unlock()
+			return true
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// release gives back a slot taken with acquire
+func (s *semaphore) release() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.used--
+	s.notify()
+}
+
+// setLimit changes the number of slots of s, a value lower than 1 means no
+// limit. When the limit decreases the slots in use are not revoked: new slots
+// are only given once the holders are fewer than the new limit
+func (s *semaphore) setLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = n
+	s.notify()
+}
+
+// notify wakes up the waiters of s, s.mu must be held
+func (s *semaphore) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
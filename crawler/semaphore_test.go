@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_semaphore_setLimit(t *testing.T) {
+	s := newSemaphore(1)
+	assert.True(t, s.acquire(context.Background()))
+
+	// no slot left
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, s.acquire(ctx))
+
+	// a waiter gets a slot as soon as the limit increases
+	acquired := make(chan bool)
+	go func() {
+		acquired <- s.acquire(context.Background())
+	}()
+	s.setLimit(2)
+	assert.True(t, <-acquired)
+
+	// decreasing the limit does not revoke the slots in use
+	s.setLimit(1)
+	s.release()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, s.acquire(ctx))
+	s.release()
+	assert.True(t, s.acquire(context.Background()))
+
+	// no limit
+	s.setLimit(0)
+	for i := 0; i < 10; i++ {
+		assert.True(t, s.acquire(context.Background()))
+	}
+}
+
+func Test_crawler_Crawl_SetConcurrency(t *testing.T) {
+	var mut sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	pages := map[string]string{
+		"/index.html": linksPage("index", "p1.html", "p2.html", "p3.html", "p4.html", "p5.html", "p6.html"),
+	}
+	for i := 1; i <= 6; i++ {
+		pages[fmt.Sprintf("/p%d.html", i)] = linksPage(fmt.Sprintf("p%d", i))
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mut.Unlock()
+		// keep the request in flight long enough to overlap with the others
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, pages[r.URL.Path])
+		mut.Lock()
+		inFlight--
+		mut.Unlock()
+	}))
+	defer srv.Close()
+
+	c := NewCrawler(WithMaxConcurrency(1))
+	err := c.Crawl(context.Background(), getURL(srv.URL+"/index.html"), func(u *url.URL, page *html.Node) {
+		// the concurrency is raised while the crawl is running
+		if u.Path == "/index.html" {
+			c.SetConcurrency(3)
+		}
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, maxInFlight)
+}
@@ -0,0 +1,65 @@
+package crawler
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"sync"
+)
+
+// sessionRenewal renews the authenticated session of a crawler when its
+// requests start being denied (401 or 403 status code) after succeeding
+type sessionRenewal struct {
+	// login logs in again issuing its requests with client
+	login  func(ctx context.Context, client *http.Client) error
+	client func() *http.Client
+
+	mu sync.Mutex
+	// generation counts the renewals, the requests denied with
+	// an already renewed session are retried without renewing it
+	generation int
+	// succeeded reports whether a request succeeded with the session
+	succeeded bool
+}
+
+// current returns the generation of the session requests are issued with
+func (s *sessionRenewal) current() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation
+}
+
+// expired records the response r of a request and reports whether the
+// session expired: a request succeeded with the session but r was denied
+func (s *sessionRenewal) expired(r *http.Response) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.StatusCode >= http.StatusOK && r.StatusCode < http.StatusMultipleChoices:
+		s.succeeded = true
+		return false
+	case r.StatusCode == http.StatusUnauthorized || r.StatusCode == http.StatusForbidden:
+		return s.succeeded
+	}
+	return false
+}
+
+// renew logs in again unless the generation session was already renewed by a
+// concurrent request, it reports whether the denied request is to be retried
+func (s *sessionRenewal) renew(ctx context.Context, generation int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation != generation {
+		return true
+	}
+	// the concurrent requests denied wait for the renewal
+	s.generation++
+	// the session is to succeed again to be renewed
+	s.succeeded = false
+	if err := s.login(ctx, s.client()); err != nil {
+		log.Errorf("failed to renew the session - %v", err)
+		return false
+	}
+	log.Infof("session renewed")
+	return true
+}
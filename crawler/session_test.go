@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_crawler_CrawlPages_WithSessionRenewal(t *testing.T) {
+	tests := map[string]struct {
+		loginErr   error
+		wantStatus map[string]int
+		wantLogins int32
+	}{
+		"renewed": {
+			wantStatus: map[string]int{"/index.html": http.StatusOK, "/page1.html": http.StatusOK, "/page2.html": http.StatusOK},
+			wantLogins: 1,
+		},
+		"failing_login": {
+			loginErr:   errors.New("invalid credentials"),
+			wantStatus: map[string]int{"/index.html": http.StatusOK, "/page1.html": http.StatusUnauthorized, "/page2.html": http.StatusUnauthorized},
+			// the session is only renewed again once a request succeeded
+			wantLogins: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// the session expires once the index is served
+			var session int32 = 1
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-Session") != fmt.Sprint(atomic.LoadInt32(&session)) {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if r.URL.Path == "/index.html" {
+					atomic.AddInt32(&session, 1)
+				}
+				fmt.Fprint(w, linksPage("page", "page1.html", "page2.html"))
+			}))
+			defer srv.Close()
+
+			var current, logins int32 = 1, 0
+			login := func(ctx context.Context, client *http.Client) error {
+				assert.NotNil(t, client)
+				atomic.AddInt32(&logins, 1)
+				if tt.loginErr != nil {
+					return tt.loginErr
+				}
+				atomic.StoreInt32(&current, atomic.LoadInt32(&session))
+				return nil
+			}
+			withSession := OnRequest(func(req *http.Request) error {
+				req.Header.Set("X-Session", fmt.Sprint(atomic.LoadInt32(&current)))
+				return nil
+			})
+
+			var mu sync.Mutex
+			status := make(map[string]int)
+			c := NewCrawler(WithMiddlewares(withSession), WithSessionRenewal(login), WithMaxConcurrency(1))
+			err := c.CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+				mu.Lock()
+				defer mu.Unlock()
+				status[p.URL.Path] = p.StatusCode
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantLogins, atomic.LoadInt32(&logins))
+		})
+	}
+}
+
+func Test_sessionRenewal_expired(t *testing.T) {
+	s := &sessionRenewal{}
+	// a session that never succeeded is not renewed
+	assert.False(t, s.expired(&http.Response{StatusCode: http.StatusForbidden}))
+	assert.False(t, s.expired(&http.Response{StatusCode: http.StatusOK}))
+	assert.False(t, s.expired(&http.Response{StatusCode: http.StatusNotFound}))
+	assert.True(t, s.expired(&http.Response{StatusCode: http.StatusUnauthorized}))
+	assert.True(t, s.expired(&http.Response{StatusCode: http.StatusForbidden}))
+}
@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity is the importance of a Finding, the zero value is SeverityInfo
+type Severity int
+
+const (
+	// SeverityInfo findings are informative, e.g. an inventory
+	SeverityInfo Severity = iota
+	// SeverityWarning findings are issues worth fixing
+	SeverityWarning
+	// SeverityError findings are issues to be fixed
+	SeverityError
+)
+
+var severityNames = map[Severity]string{
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// ParseSeverity parses a severity name (info, warning or error)
+func ParseSeverity(name string) (Severity, error) {
+	for s, n := range severityNames {
+		if n == strings.ToLower(strings.TrimSpace(name)) {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q", name)
+}
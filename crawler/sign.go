@@ -0,0 +1,184 @@
+package crawler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RequestSigner signs the requests issued by the crawler, e.g. by adding
+// an authorization header computed out of the request
+type RequestSigner interface {
+	// Sign signs req in place, an error aborts the request
+	Sign(req *http.Request) error
+}
+
+// RequestSignerFunc adapts a function to a RequestSigner
+type RequestSignerFunc func(req *http.Request) error
+
+// Sign calls f(req)
+func (f RequestSignerFunc) Sign(req *http.Request) error {
+	return f(req)
+}
+
+// signingTransport is a http.RoundTripper signing every request, redirects included,
+// before handing it to the next transport
+type signingTransport struct {
+	next   http.RoundTripper
+	signer RequestSigner
+}
+
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request
+	signed := req.Clone(req.Context())
+	if err := t.signer.Sign(signed); err != nil {
+		return nil, fmt.Errorf("error while signing request - %v", err)
+	}
+	return t.next.RoundTrip(signed)
+}
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	sigV4DateFormat = "20060102"
+	// emptyPayloadHash is the hex encoded SHA-256 of an empty body,
+	// the crawler only issues requests without body
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// SigV4Signer is a RequestSigner implementing the AWS Signature Version 4, it allows to
+// crawl endpoints behind IAM (e.g. S3 website buckets). Only requests without body are supported
+type SigV4Signer struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is only required with temporary credentials
+	SessionToken string
+	Region       string
+	Service      string
+
+	// now returns the signing time, time.Now when nil
+	now func() time.Time
+}
+
+// Sign adds the X-Amz-Date and the Authorization headers to req
+// (and X-Amz-Security-Token and X-Amz-Content-Sha256 when needed)
+func (s *SigV4Signer) Sign(req *http.Request) error {
+	if req.Body != nil && req.Body != http.NoBody {
+		return fmt.Errorf("sigv4 signing of requests with a body is not supported")
+	}
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	amzDate := t.Format(sigV4TimeFormat)
+	date := t.Format(sigV4DateFormat)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	if s.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	}
+
+	canonicalHeaders, signedHeaders := sigV4CanonicalHeaders(req)
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		sigV4CanonicalQuery(req),
+		canonicalHeaders,
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.Region, s.Service, "aws4_request"}, "/")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// sigV4CanonicalHeaders returns the canonical headers block and the signed headers
+// list of req. The host and the x-amz-* headers are signed
+func sigV4CanonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": strings.TrimSpace(host)}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if !strings.HasPrefix(lower, "x-amz-") {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[lower] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// sigV4CanonicalQuery returns the query of req with its parameters
+// sorted and URI-encoded as required by the Signature Version 4
+func sigV4CanonicalQuery(req *http.Request) string {
+	query := req.URL.Query()
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, sigV4Escape(key)+"="+sigV4Escape(v))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// sigV4Escape URI-encodes every byte of s except the unreserved characters
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,94 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// Test_SigV4Signer uses the vectors of the AWS Signature Version 4 test suite
+func Test_SigV4Signer(t *testing.T) {
+	signer := &SigV4Signer{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "service",
+		now: func() time.Time {
+			return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+		},
+	}
+
+	tests := map[string]struct {
+		url  string
+		want string
+	}{
+		"get_vanilla": {
+			url:  "https://example.amazonaws.com/",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		"get_vanilla_query_order_key_case": {
+			url:  "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.url, nil)
+			assert.Nil(t, err)
+			assert.Nil(t, signer.Sign(req))
+			assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+			assert.Equal(t, tt.want, req.Header.Get("Authorization"))
+		})
+	}
+}
+
+func Test_SigV4Signer_S3AndSessionToken(t *testing.T) {
+	signer := &SigV4Signer{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+		Region:          "eu-west-1",
+		Service:         "s3",
+	}
+	req, err := http.NewRequest("GET", "https://bucket.s3.amazonaws.com/index.html", nil)
+	assert.Nil(t, err)
+	assert.Nil(t, signer.Sign(req))
+	assert.Equal(t, "token", req.Header.Get("X-Amz-Security-Token"))
+	assert.Equal(t, emptyPayloadHash, req.Header.Get("X-Amz-Content-Sha256"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,")
+}
+
+func Test_crawler_Crawl_WithRequestSigner(t *testing.T) {
+	var unsigned int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Signature") != "signed "+r.URL.Path {
+			unsigned++
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/index.html" {
+			w.Write([]byte(linksPage("index", "page1.html")))
+			return
+		}
+		w.Write([]byte(linksPage("page1")))
+	}))
+	defer srv.Close()
+
+	signer := RequestSignerFunc(func(req *http.Request) error {
+		req.Header.Set("X-Signature", "signed "+req.URL.Path)
+		return nil
+	})
+	var titles []string
+	err := NewCrawler(WithRequestSigner(signer), WithMaxConcurrency(1)).Crawl(context.Background(), getURL(srv.URL+"/index.html"), func(u *url.URL, page *html.Node) {
+		titles = append(titles, pageTitle(page))
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, unsigned)
+	assert.Equal(t, []string{"index", "page1"}, titles)
+}
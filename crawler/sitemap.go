@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// sitemapNamespace is the XML namespace of the sitemaps protocol
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// SitemapURL is an entry of a sitemap
+type SitemapURL struct {
+	// Loc is the url of the page
+	Loc string `xml:"loc"`
+	// LastMod is the W3C datetime of the last modification
+	// of the page, it is omitted when unknown
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap collects the crawled pages to generate a sitemap.xml. Only pages
+// answered with a 200 status code are listed, the last modification date is
+// taken from the Last-Modified response header. Its Visit method can be used
+// as the visit function of Crawler.CrawlPages. It is safe for concurrent use
+type Sitemap struct {
+	mut  sync.Mutex
+	urls map[string]SitemapURL
+}
+
+// NewSitemap creates an empty Sitemap
+func NewSitemap() *Sitemap {
+	return &Sitemap{urls: make(map[string]SitemapURL)}
+}
+
+// Visit adds the page to the sitemap if it was answered with a 200 status code
+func (s *Sitemap) Visit(p *Page) {
+	if p.StatusCode != http.StatusOK {
+		return
+	}
+	entry := SitemapURL{Loc: p.URL.String()}
+	if t, err := http.ParseTime(p.Header.Get("Last-Modified")); err == nil {
+		entry.LastMod = t.UTC().Format(time.RFC3339)
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.urls[entry.Loc] = entry
+}
+
+// URLs returns the entries of the sitemap sorted by url
+func (s *Sitemap) URLs() []SitemapURL {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	urls := make([]SitemapURL, 0, len(s.urls))
+	for _, u := range s.urls {
+		urls = append(urls, u)
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].Loc < urls[j].Loc
+	})
+	return urls
+}
+
+// WriteTo writes the sitemap.xml document into w
+func (s *Sitemap) WriteTo(w io.Writer) (int64, error) {
+	doc := struct {
+		XMLName xml.Name     `xml:"urlset"`
+		XMLNS   string       `xml:"xmlns,attr"`
+		URLs    []SitemapURL `xml:"url"`
+	}{XMLNS: sitemapNamespace, URLs: s.URLs()}
+
+	cw := &countingWriter{w: w}
+	if _, err := io.WriteString(cw, xml.Header); err != nil {
+		return cw.n, err
+	}
+	enc := xml.NewEncoder(cw)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return cw.n, err
+	}
+	_, err := io.WriteString(cw, "\n")
+	return cw.n, err
+}
+
+// countingWriter counts the bytes written into w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Sitemap(t *testing.T) {
+	s := NewSitemap()
+	s.Visit(&Page{URL: getURL("https://my-web-site.com/b"), StatusCode: 200, Header: http.Header{}})
+	s.Visit(&Page{URL: getURL("https://my-web-site.com/a"), StatusCode: 200, Header: http.Header{
+		"Last-Modified": {"Wed, 21 Oct 2015 07:28:00 GMT"},
+	}})
+	// pages not answered with a 200 are not listed
+	s.Visit(&Page{URL: getURL("https://my-web-site.com/missing"), StatusCode: 404, Header: http.Header{}})
+
+	var b strings.Builder
+	n, err := s.WriteTo(&b)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(b.Len()), n)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://my-web-site.com/a</loc>
+    <lastmod>2015-10-21T07:28:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://my-web-site.com/b</loc>
+  </url>
+</urlset>
+`, b.String())
+}
+
+func Test_Sitemap_Crawl(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+			w.Write([]byte(linksPage("index", "page.html", "missing.html")))
+		case "/page.html":
+			w.Write([]byte(linksPage("page", "index.html")))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewSitemap()
+	err := NewCrawler().CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), s.Visit)
+	assert.Nil(t, err)
+	assert.Equal(t, []SitemapURL{
+		{Loc: srv.URL + "/index.html", LastMod: "2015-10-21T07:28:00Z"},
+		{Loc: srv.URL + "/page.html"},
+	}, s.URLs())
+}
@@ -0,0 +1,156 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/net/html"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// socialTags are the social metadata tags expected on every page
+var socialTags = []string{"og:title", "og:description", "og:image", "twitter:card"}
+
+// socialMetadata is the Report built by SocialMetadata
+type socialMetadata struct {
+	ctx    context.Context
+	client *http.Client
+
+	mut      sync.Mutex
+	findings []Finding
+	// images are the pages of each og:image URL
+	images map[string][]string
+}
+
+// SocialMetadata builds a Report auditing the Open Graph and Twitter card metadata
+// of the HTML pages answered with a 2xx status code: it flags the pages missing one of
+// og:title, og:description, og:image or twitter:card and, once the crawl is done,
+// the pages whose og:image cannot be fetched with client, e.g. the one built by
+// NewHTTPClient. A nil client means http.DefaultClient. The images are fetched
+// with ctx, Finalize returning its error once it is done
+func SocialMetadata(ctx context.Context, client *http.Client) Report {
+	return &socialMetadata{ctx: ctx, client: auditClient(client, false), images: make(map[string][]string)}
+}
+
+func (r *socialMetadata) Consume(p *Page) {
+	if p.StatusCode < http.StatusOK || p.StatusCode >= http.StatusMultipleChoices || !isHTMLPage(p) {
+		return
+	}
+	tags := getSocialTags(p.Node)
+	var findings []Finding
+	for _, tag := range socialTags {
+		if strings.TrimSpace(tags[tag]) == "" {
+			findings = append(findings, Finding{URL: p.URL.String(), Validator: "social-metadata", Message: fmt.Sprintf("missing %s", tag), Severity: SeverityWarning})
+		}
+	}
+	var image string
+	if href := strings.TrimSpace(tags["og:image"]); href != "" {
+		u, err := NewLinkResolver(p.URL).Resolve(href)
+		if err != nil {
+			findings = append(findings, Finding{URL: p.URL.String(), Validator: "social-metadata", Message: fmt.Sprintf("invalid og:image URL %q", href), Severity: SeverityError})
+		} else {
+			image = u.String()
+		}
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.findings = append(r.findings, findings...)
+	if image != "" {
+		r.images[image] = append(r.images[image], p.URL.String())
+	}
+}
+
+func (r *socialMetadata) Finalize() ([]Finding, error) {
+	r.mut.Lock()
+	findings := append([]Finding(nil), r.findings...)
+	images := make(map[string][]string, len(r.images))
+	for image, pages := range r.images {
+		images[image] = append([]string(nil), pages...)
+	}
+	// the images are fetched without holding the lock
+	r.mut.Unlock()
+	// an image shared by several pages is only fetched once
+	for image, pages := range images {
+		problem := checkResource(r.ctx, r.client, image)
+		if r.ctx.Err() != nil {
+			return nil, r.ctx.Err()
+		}
+		if problem == "" {
+			continue
+		}
+		for _, page := range pages {
+			findings = append(findings, Finding{URL: page, Validator: "social-metadata", Message: fmt.Sprintf("broken og:image %s (%s)", image, problem), Severity: SeverityError})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].URL != findings[j].URL {
+			return findings[i].URL < findings[j].URL
+		}
+		return findings[i].Message < findings[j].Message
+	})
+	return findings, nil
+}
+
+// checkResource fetches the resource at u with client and ctx and describes why
+// it is broken, an empty string when it is served with a 2xx status code
+func checkResource(ctx context.Context, client *http.Client, u string) string {
+	resp, err := doRequest(ctx, client, http.MethodHead, u)
+	// some servers do not support HEAD requests
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = doRequest(ctx, client, http.MethodGet, u)
+	}
+	if err != nil {
+		return err.Error()
+	}
+	resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Sprintf("status %d", resp.StatusCode)
+	}
+	return ""
+}
+
+// getSocialTags returns the content of the first meta element of each Open Graph
+// (property attribute) and Twitter card (name or property attribute) tag of a page
+func getSocialTags(node *html.Node) map[string]string {
+	tags := make(map[string]string)
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "meta" {
+			for _, key := range []string{"property", "name"} {
+				tag := strings.ToLower(attr(node, key))
+				if strings.HasPrefix(tag, "og:") || strings.HasPrefix(tag, "twitter:") {
+					if _, ok := tags[tag]; !ok {
+						tags[tag] = attr(node, "content")
+					}
+					break
+				}
+			}
+		}
+		for n := node.FirstChild; n != nil; n = n.NextSibling {
+			walk(n)
+		}
+	}
+	if node != nil {
+		walk(node)
+	}
+	return tags
+}
+
+// isHTMLPage reports whether p is an HTML page, a page without
+// Content-Type header being considered as such
+func isHTMLPage(p *Page) bool {
+	contentType := p.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return ContentTypeStat{ContentType: strings.ToLower(mediaType)}.IsHTML()
+}
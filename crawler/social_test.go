@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSocialMetadata(t *testing.T) {
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.WriteHeader(http.StatusOK)
+		case "/no-head.png":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer images.Close()
+	social := func(image string) string {
+		return fmt.Sprintf(`<html><head>
+			<meta property="og:title" content="Home"><meta property="og:description" content="The home page">
+			<meta property="og:image" content="%s"><meta name="twitter:card" content="summary">
+			</head></html>`, image)
+	}
+
+	r := SocialMetadata(context.Background(), images.Client())
+	r.Consume(newPage(t, "https://my-web-site.com/complete", 200, nil, social(images.URL+"/image.png")))
+	r.Consume(newPage(t, "https://my-web-site.com/no-head", 200, nil, social(images.URL+"/no-head.png")))
+	r.Consume(newPage(t, "https://my-web-site.com/broken-a", 200, nil, social(images.URL+"/missing.png")))
+	r.Consume(newPage(t, "https://my-web-site.com/broken-b", 200, nil, social(images.URL+"/missing.png")))
+	r.Consume(newPage(t, "https://my-web-site.com/missing", 200, http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		`<html><head><meta property="og:title" content="Home"><meta property="og:description" content=" "></head></html>`))
+	// the pages that are not 2xx HTML pages are ignored
+	r.Consume(newPage(t, "https://my-web-site.com/not-found", 404, nil, "<html></html>"))
+	r.Consume(newPage(t, "https://my-web-site.com/data.json", 200, http.Header{"Content-Type": {"application/json"}}, ""))
+
+	const missing = "https://my-web-site.com/missing"
+	findings, err := r.Finalize()
+	assert.Nil(t, err)
+	assert.Equal(t, []Finding{
+		{URL: "https://my-web-site.com/broken-a", Validator: "social-metadata", Message: fmt.Sprintf("broken og:image %s/missing.png (status 404)", images.URL), Severity: SeverityError},
+		{URL: "https://my-web-site.com/broken-b", Validator: "social-metadata", Message: fmt.Sprintf("broken og:image %s/missing.png (status 404)", images.URL), Severity: SeverityError},
+		{URL: missing, Validator: "social-metadata", Message: "missing og:description", Severity: SeverityWarning},
+		{URL: missing, Validator: "social-metadata", Message: "missing og:image", Severity: SeverityWarning},
+		{URL: missing, Validator: "social-metadata", Message: "missing twitter:card", Severity: SeverityWarning},
+	}, findings)
+}
+
+func TestSocialMetadata_Canceled(t *testing.T) {
+	requests := 0
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer images.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := SocialMetadata(ctx, images.Client())
+	r.Consume(newPage(t, "https://my-web-site.com/", 200, nil, fmt.Sprintf(`<meta property="og:image" content="%s/image.png">`, images.URL)))
+	cancel()
+	// the images are no longer fetched once ctx is done
+	findings, err := r.Finalize()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, findings)
+	assert.Equal(t, 0, requests)
+}
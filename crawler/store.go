@@ -0,0 +1,118 @@
+package crawler
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"sync"
+)
+
+// Stats are the counters of a crawl
+type Stats struct {
+	// Pages is the number of pages successfully fetched
+	Pages int
+	// Errors is the number of pages that could not be fetched
+	Errors int
+	// Skipped is the number of pages not requested because they could not
+	// be fetched before the deadline of the crawl
+	Skipped int
+}
+
+// Store keeps track of the pages visited by a crawler and of the crawl Stats.
+// A Store provided with WithStore is shared by all the Crawl calls of a crawler,
+// so that pages already visited by a previous Crawl are not visited again.
+// Implementations must be safe for concurrent use
+type Store interface {
+	// Add marks key as visited, it returns false if key had already been visited
+	Add(key string) bool
+	// Contains reports whether key has been visited
+	Contains(key string) bool
+	// Record updates the stats with the result of fetching the page key, err is
+	// ErrDeadlineSkipped when the page was not requested because of the deadline
+	Record(key string, err error)
+	// Stats returns the stats recorded so far
+	Stats() Stats
+}
+
+// compactKey is the fixed size key under which a visited page is stored: the
+// 128 bits FNV-1a hash of the page key. Storing it instead of the URL string
+// keeps the memory of the visited set independent from the URLs length, the
+// probability of a collision is negligible even on multi-million-URL crawls
+type compactKey [16]byte
+
+const (
+	// fnv128OffsetHigh and fnv128OffsetLow are the FNV-128 offset basis
+	fnv128OffsetHigh = 0x6c62272e07bb0142
+	fnv128OffsetLow  = 0x62b821756295c58d
+	// the FNV-128 prime is 2^88 + fnv128PrimeLow
+	fnv128PrimeLow   = 0x13b
+	fnv128PrimeShift = 88 - 64
+)
+
+// newCompactKey computes the compactKey of key without allocating
+// (hash/fnv would allocate the hash and a copy of the key)
+func newCompactKey(key string) compactKey {
+	hi, lo := uint64(fnv128OffsetHigh), uint64(fnv128OffsetLow)
+	for i := 0; i < len(key); i++ {
+		lo ^= uint64(key[i])
+		// (hi, lo) * (2^88 + fnv128PrimeLow) mod 2^128
+		carry, newLo := bits.Mul64(lo, fnv128PrimeLow)
+		hi = hi*fnv128PrimeLow + carry + lo<<fnv128PrimeShift
+		lo = newLo
+	}
+	var k compactKey
+	binary.BigEndian.PutUint64(k[:8], hi)
+	binary.BigEndian.PutUint64(k[8:], lo)
+	return k
+}
+
+// memoryStore is an in-memory Store, the set of visited pages is
+// represented as a map[compactKey]struct{}
+type memoryStore struct {
+	rw      sync.RWMutex
+	visited map[compactKey]struct{}
+	stats   Stats
+}
+
+// NewMemoryStore creates an in-memory Store
+func NewMemoryStore() Store {
+	return &memoryStore{visited: make(map[compactKey]struct{})}
+}
+
+func (s *memoryStore) Add(key string) bool {
+	k := newCompactKey(key)
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	if _, ok := s.visited[k]; ok {
+		return false
+	}
+	s.visited[k] = struct{}{}
+	return true
+}
+
+func (s *memoryStore) Contains(key string) bool {
+	k := newCompactKey(key)
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+	_, ok := s.visited[k]
+	return ok
+}
+
+func (s *memoryStore) Record(_ string, err error) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	switch {
+	case errors.Is(err, ErrDeadlineSkipped):
+		s.stats.Skipped++
+	case err != nil:
+		s.stats.Errors++
+	default:
+		s.stats.Pages++
+	}
+}
+
+func (s *memoryStore) Stats() Stats {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+	return s.stats
+}
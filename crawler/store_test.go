@@ -0,0 +1,129 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"hash/fnv"
+	"net/url"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func Test_memoryStore(t *testing.T) {
+	s := NewMemoryStore()
+
+	assert.False(t, s.Contains("a"))
+	assert.True(t, s.Add("a"))
+	assert.False(t, s.Add("a"))
+	assert.True(t, s.Contains("a"))
+
+	s.Record("a", nil)
+	s.Record("b", errors.New("failed"))
+	s.Record("c", nil)
+	assert.Equal(t, Stats{Pages: 2, Errors: 1}, s.Stats())
+}
+
+func Test_newCompactKey(t *testing.T) {
+	for _, key := range []string{"", "a", "https://my-web-site.com/i/business?ref=navigation#top"} {
+		h := fnv.New128a()
+		h.Write([]byte(key))
+		var want compactKey
+		copy(want[:], h.Sum(nil))
+		assert.Equal(t, want, newCompactKey(key), key)
+	}
+}
+
+func Test_memoryStore_ConcurrentAdd(t *testing.T) {
+	s := NewMemoryStore()
+	var wg sync.WaitGroup
+	var mut sync.Mutex
+	added := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.Add("key") {
+				mut.Lock()
+				added++
+				mut.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, added)
+}
+
+// Test_crawler_Crawl_SharedStore_Integration depends on the startup of the attached `test_data/Dockerfile` container
+// two crawls sharing the same store visit each page only once: the second crawl, seeded with
+// the orphan page, only visits the orphan page as the rest of the site was already visited
+func Test_crawler_Crawl_SharedStore_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	store := NewMemoryStore()
+	c := NewCrawler(WithStore(store))
+
+	var mut sync.Mutex
+	var titles []string
+	visit := func(u *url.URL, page *html.Node) {
+		mut.Lock()
+		titles = append(titles, pageTitle(page))
+		mut.Unlock()
+	}
+
+	assert.Nil(t, c.Crawl(context.Background(), getURL(getBaseURLIndex()), visit))
+	assert.Equal(t, Stats{Pages: 5}, store.Stats())
+
+	titles = nil
+	assert.Nil(t, c.Crawl(context.Background(), getURL(getBaseURLStr()+"orphan/orphan11.html"), visit))
+	assert.True(t, reflect.DeepEqual([]string{"orphan1"}, titles))
+	assert.Equal(t, Stats{Pages: 6}, store.Stats())
+}
+
+// benchmarkURLs builds n distinct long URLs, similar to the ones found on large sites
+func benchmarkURLs(n int) []string {
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://my-web-site.com/i/business/catalog/category-%d/product-%d/details.html?ref=navigation&session=%d", i%100, i, i*7)
+	}
+	return urls
+}
+
+// BenchmarkMemoryStore_Add measures the cost of adding a million URLs to the
+// visited set, the heap retained by the store is reported in bytes/store
+func BenchmarkMemoryStore_Add(b *testing.B) {
+	urls := benchmarkURLs(1000000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		s := NewMemoryStore()
+		for _, u := range urls {
+			// as in a crawl, each key is a freshly built string (url.URL.String())
+			s.Add(string([]byte(u)))
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc), "bytes/store")
+		runtime.KeepAlive(s)
+	}
+}
+
+func BenchmarkMemoryStore_Contains(b *testing.B) {
+	urls := benchmarkURLs(100000)
+	s := NewMemoryStore()
+	for _, u := range urls {
+		s.Add(u)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Contains(urls[i%len(urls)])
+	}
+}
@@ -0,0 +1,153 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang.org/x/net/html"
+	"sort"
+	"strings"
+)
+
+// structuredDataRule lists the properties a schema.org entity type requires
+type structuredDataRule struct {
+	// required are the properties that must all be set
+	required []string
+	// anyOf are the properties of which at least one must be set
+	anyOf []string
+}
+
+// structuredDataRules are the rules of the schema.org types checked by StructuredData
+var structuredDataRules = map[string]structuredDataRule{
+	"Product":     {required: []string{"name"}, anyOf: []string{"offers", "review", "aggregateRating"}},
+	"Offer":       {required: []string{"price", "priceCurrency"}},
+	"Article":     {required: []string{"headline", "author", "datePublished"}},
+	"NewsArticle": {required: []string{"headline", "author", "datePublished"}},
+	"BlogPosting": {required: []string{"headline", "author", "datePublished"}},
+	"FAQPage":     {required: []string{"mainEntity"}},
+	"Question":    {required: []string{"name", "acceptedAnswer"}},
+	"Answer":      {required: []string{"text"}},
+}
+
+// StructuredData reports the JSON-LD scripts of a page that cannot be parsed and
+// the schema.org entities they describe missing a required property: the name and
+// one of offers, review or aggregateRating of a Product, the price and currency of
+// an Offer, the headline, author and publication date of an Article and the
+// questions and answers of an FAQPage. The nested entities (e.g. the `@graph` or
+// the offers of a product) are checked as well
+func StructuredData(p *Page) []Finding {
+	var findings []Finding
+	for _, script := range getJSONLD(p.Node) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(script), &data); err != nil {
+			findings = append(findings, Finding{URL: p.URL.String(), Validator: "structured-data", Message: fmt.Sprintf("invalid JSON-LD (%s)", err), Severity: SeverityError})
+			continue
+		}
+		for _, message := range checkStructuredData(data) {
+			findings = append(findings, Finding{URL: p.URL.String(), Validator: "structured-data", Message: message, Severity: SeverityError})
+		}
+	}
+	return findings
+}
+
+// checkStructuredData describes the violations of the entities found in the
+// decoded JSON-LD data
+func checkStructuredData(data interface{}) []string {
+	var messages []string
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			messages = append(messages, checkStructuredData(item)...)
+		}
+	case map[string]interface{}:
+		for _, t := range entityTypes(v["@type"]) {
+			rule, ok := structuredDataRules[t]
+			if !ok {
+				continue
+			}
+			for _, property := range rule.required {
+				if !hasProperty(v, property) {
+					messages = append(messages, fmt.Sprintf("%s entity missing %s", t, property))
+				}
+			}
+			if len(rule.anyOf) == 0 {
+				continue
+			}
+			found := false
+			for _, property := range rule.anyOf {
+				found = found || hasProperty(v, property)
+			}
+			if !found {
+				messages = append(messages, fmt.Sprintf("%s entity missing one of %s", t, strings.Join(rule.anyOf, ", ")))
+			}
+		}
+		// the map iteration order is random
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			messages = append(messages, checkStructuredData(v[k])...)
+		}
+	}
+	return messages
+}
+
+// entityTypes returns the schema.org types of an `@type` value, either a
+// type or a list of types, given by name or by URL
+func entityTypes(v interface{}) []string {
+	var values []interface{}
+	switch t := v.(type) {
+	case string:
+		values = []interface{}{t}
+	case []interface{}:
+		values = t
+	}
+	var types []string
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			types = append(types, strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(s, "http://"), "https://"), "schema.org/"))
+		}
+	}
+	return types
+}
+
+// hasProperty reports whether property of entity is set, a null value, an
+// empty string or an empty list being considered as not set
+func hasProperty(entity map[string]interface{}, property string) bool {
+	switch v := entity[property].(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	case []interface{}:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
+
+// getJSONLD returns the content of the JSON-LD scripts of a page
+func getJSONLD(node *html.Node) []string {
+	if node == nil {
+		return nil
+	}
+	if node.Type == html.ElementNode && node.Data == "script" {
+		mediaType := strings.ToLower(strings.TrimSpace(strings.Split(attr(node, "type"), ";")[0]))
+		if mediaType != "application/ld+json" {
+			return nil
+		}
+		var content strings.Builder
+		for n := node.FirstChild; n != nil; n = n.NextSibling {
+			if n.Type == html.TextNode {
+				content.WriteString(n.Data)
+			}
+		}
+		return []string{content.String()}
+	}
+	var scripts []string
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		scripts = append(scripts, getJSONLD(n)...)
+	}
+	return scripts
+}
@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStructuredData(t *testing.T) {
+	const u = "https://my-web-site.com/page"
+	tests := map[string]struct {
+		scripts []string
+		want    []string
+	}{
+		"no_structured_data": {},
+		"valid_product": {
+			scripts: []string{`{"@context": "https://schema.org", "@type": "Product", "name": "Shoes",
+				"offers": {"@type": "Offer", "price": "59.90", "priceCurrency": "EUR"}}`},
+		},
+		"invalid_product": {
+			scripts: []string{`{"@context": "https://schema.org", "@type": "https://schema.org/Product", "name": " ",
+				"offers": []}`},
+			want: []string{
+				"Product entity missing name",
+				"Product entity missing one of offers, review, aggregateRating",
+			},
+		},
+		"nested_offer": {
+			scripts: []string{`{"@type": "Product", "name": "Shoes", "offers": [{"@type": "Offer", "price": 59.9}]}`},
+			want:    []string{"Offer entity missing priceCurrency"},
+		},
+		"article_graph": {
+			scripts: []string{`{"@context": "https://schema.org", "@graph": [
+				{"@type": "WebSite", "name": "My web site"},
+				{"@type": ["BlogPosting", "Article"], "headline": "News", "author": {"@type": "Person", "name": "Me"}, "datePublished": null}]}`},
+			want: []string{"BlogPosting entity missing datePublished", "Article entity missing datePublished"},
+		},
+		"faq": {
+			scripts: []string{`{"@type": "FAQPage", "mainEntity": [
+				{"@type": "Question", "name": "Why?", "acceptedAnswer": {"@type": "Answer", "text": "Because."}},
+				{"@type": "Question", "name": "How?", "acceptedAnswer": {"@type": "Answer"}},
+				{"@type": "Question", "name": "When?"}]}`},
+			want: []string{"Answer entity missing text", "Question entity missing acceptedAnswer"},
+		},
+		"empty_faq": {
+			scripts: []string{`{"@type": "FAQPage", "mainEntity": []}`},
+			want:    []string{"FAQPage entity missing mainEntity"},
+		},
+		"several_scripts": {
+			scripts: []string{`{"@type": "Answer"}`, `{"@type": "Article", "headline": "News"`},
+			want:    []string{"Answer entity missing text", "invalid JSON-LD (unexpected end of JSON input)"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			html := `<html><head><script type="text/javascript">{"@type": "Answer"}</script>`
+			for _, script := range tt.scripts {
+				html += `<script type="application/ld+json">` + script + `</script>`
+			}
+			html += `</head></html>`
+			var got []string
+			for _, f := range StructuredData(newPage(t, u, 200, nil, html)) {
+				assert.Equal(t, u, f.URL)
+				assert.Equal(t, "structured-data", f.Validator)
+				assert.Equal(t, SeverityError, f.Severity)
+				got = append(got, f.Message)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
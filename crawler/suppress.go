@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// suppressionRule suppresses the findings of a validator on the pages
+// matching url, `*` matches any validator and a trailing `*` in url
+// matches any suffix
+type suppressionRule struct {
+	validator string
+	url       string
+}
+
+func (r suppressionRule) matches(f Finding) bool {
+	if r.validator != "*" && r.validator != f.Validator {
+		return false
+	}
+	if prefix := strings.TrimSuffix(r.url, "*"); prefix != r.url {
+		return strings.HasPrefix(f.URL, prefix)
+	}
+	return r.url == f.URL
+}
+
+// Suppressions are the known or accepted findings that are not to be reported
+// again, like the ignores of a linter. The zero value suppresses nothing
+type Suppressions struct {
+	ids   map[string]struct{}
+	rules []suppressionRule
+}
+
+// ParseSuppressions parses a suppression file. Blank lines and lines starting with
+// `#` are ignored, every other line is either the ID of a finding (see Finding.ID)
+// or a `<validator> <url>` pair suppressing the findings of a validator on a page.
+// The validator can be `*` to match all of them and a trailing `*` in the url
+// matches all the pages sharing its prefix (e.g. `title-present https://my-web-site.com/legacy/*`)
+func ParseSuppressions(r io.Reader) (*Suppressions, error) {
+	s := &Suppressions{ids: make(map[string]struct{})}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		switch len(fields) {
+		case 1:
+			s.ids[fields[0]] = struct{}{}
+		case 2:
+			s.rules = append(s.rules, suppressionRule{validator: fields[0], url: fields[1]})
+		default:
+			return nil, fmt.Errorf("invalid suppression at line %d: %q", line, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Suppressed reports whether f is suppressed
+func (s *Suppressions) Suppressed(f Finding) bool {
+	if s == nil {
+		return false
+	}
+	if _, ok := s.ids[f.ID()]; ok {
+		return true
+	}
+	for _, r := range s.rules {
+		if r.matches(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteBaseline writes findings as a suppression file of their IDs (see
+// ParseSuppressions), e.g. to only report the findings that are not in the
+// baseline of an existing site. Each ID is preceded by a comment describing
+// its finding
+func WriteBaseline(w io.Writer, findings []Finding) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range findings {
+		message := strings.Join(strings.Fields(f.Message), " ")
+		if _, err := fmt.Fprintf(bw, "# %s %s: %s\n%s\n", f.Validator, f.URL, message, f.ID()); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
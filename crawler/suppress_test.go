@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestFinding_ID(t *testing.T) {
+	f := Finding{URL: "https://my-web-site.com/a", Validator: "status-ok", Message: "unexpected status code 404"}
+	assert.Len(t, f.ID(), 12)
+	assert.Equal(t, f.ID(), f.ID())
+	// the severity is not part of the identity of a finding
+	assert.Equal(t, f.ID(), Finding{URL: f.URL, Validator: f.Validator, Message: f.Message, Severity: SeverityError}.ID())
+	assert.NotEqual(t, f.ID(), Finding{URL: f.URL, Validator: f.Validator, Message: "unexpected status code 500"}.ID())
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, s := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		got, err := ParseSeverity(strings.ToUpper(s.String()))
+		assert.Nil(t, err)
+		assert.Equal(t, s, got)
+	}
+	_, err := ParseSeverity("fatal")
+	assert.NotNil(t, err)
+}
+
+func TestSuppressions_Suppressed(t *testing.T) {
+	accepted := Finding{URL: "https://my-web-site.com/old", Validator: "status-ok", Message: "unexpected status code 410"}
+	s, err := ParseSuppressions(strings.NewReader(`
+# accepted findings
+` + accepted.ID() + `
+title-present https://my-web-site.com/legacy/*
+* https://my-web-site.com/draft.html
+`))
+	assert.Nil(t, err)
+
+	tests := map[string]struct {
+		finding Finding
+		want    bool
+	}{
+		"id": {
+			finding: accepted,
+			want:    true,
+		},
+		"id_of_another_message": {
+			finding: Finding{URL: accepted.URL, Validator: accepted.Validator, Message: "unexpected status code 500"},
+		},
+		"prefix": {
+			finding: Finding{URL: "https://my-web-site.com/legacy/page.html", Validator: "title-present"},
+			want:    true,
+		},
+		"prefix_of_another_validator": {
+			finding: Finding{URL: "https://my-web-site.com/legacy/page.html", Validator: "canonical-present"},
+		},
+		"any_validator": {
+			finding: Finding{URL: "https://my-web-site.com/draft.html", Validator: "canonical-present"},
+			want:    true,
+		},
+		"exact_url": {
+			finding: Finding{URL: "https://my-web-site.com/draft.html?v=2", Validator: "canonical-present"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.Suppressed(tt.finding))
+		})
+	}
+
+	var nilSuppressions *Suppressions
+	assert.False(t, nilSuppressions.Suppressed(accepted))
+
+	_, err = ParseSuppressions(strings.NewReader("status-ok https://my-web-site.com/ extra"))
+	assert.NotNil(t, err)
+}
+
+func TestWriteBaseline(t *testing.T) {
+	findings := []Finding{
+		{URL: "https://my-web-site.com/a", Validator: "status-ok", Message: "unexpected status code 404"},
+		{URL: "https://my-web-site.com/b", Validator: "title-present", Message: "missing or\nempty <title>"},
+	}
+	var b strings.Builder
+	assert.Nil(t, WriteBaseline(&b, findings))
+	assert.Equal(t, "# status-ok https://my-web-site.com/a: unexpected status code 404\n"+findings[0].ID()+"\n"+
+		"# title-present https://my-web-site.com/b: missing or empty <title>\n"+findings[1].ID()+"\n", b.String())
+
+	baseline, err := ParseSuppressions(strings.NewReader(b.String()))
+	assert.Nil(t, err)
+	for _, f := range findings {
+		assert.True(t, baseline.Suppressed(f))
+	}
+	assert.False(t, baseline.Suppressed(Finding{URL: "https://my-web-site.com/c", Validator: "status-ok", Message: "unexpected status code 404"}))
+}
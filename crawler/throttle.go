@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// latencySmoothing is the weight of the last response in the
+	// exponential moving average of the response times
+	latencySmoothing = 0.2
+	// slowLatencyFactor is how many times the average response time can
+	// exceed the fastest average observed before the server is considered
+	// overloaded
+	slowLatencyFactor = 2
+	// rateIncreaseSteps is the number of healthy responses needed to
+	// speed back up from the minimum to the maximum rate
+	rateIncreaseSteps = 20
+)
+
+// throttle adapts the rate of a rateLimiter to the health of the crawled
+// servers: it halves the rate on server errors (5xx status or failed
+// request) and on response times climbing above slowLatencyFactor times the
+// fastest average observed, and increases it back by steps on healthy responses
+type throttle struct {
+	limiter  *rateLimiter
+	min, max float64
+
+	mu sync.Mutex
+	// latency is the moving average of the response times,
+	// fastest is the lowest average observed
+	latency, fastest time.Duration
+}
+
+// newThrottle returns a throttle adapting limiter between min and max
+// requests per second, starting from max
+func newThrottle(limiter *rateLimiter, min, max float64) *throttle {
+	limiter.setRate(max)
+	return &throttle{limiter: limiter, min: min, max: max}
+}
+
+// observe adapts the rate of the limiter to the outcome of a request
+// that took latency to be answered with status (0 if it failed)
+func (t *throttle) observe(latency time.Duration, status int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if status == 0 || status >= 500 {
+		t.limiter.setRate(maxFloat(t.min, t.limiter.currentRate()/2))
+		return
+	}
+
+	if t.latency == 0 {
+		t.latency = latency
+	} else {
+		t.latency += time.Duration(latencySmoothing * float64(latency-t.latency))
+	}
+	if t.fastest == 0 || t.latency < t.fastest {
+		t.fastest = t.latency
+	}
+
+	rate := t.limiter.currentRate()
+	if t.latency > slowLatencyFactor*t.fastest {
+		t.limiter.setRate(maxFloat(t.min, rate/2))
+		return
+	}
+	t.limiter.setRate(minFloat(t.max, rate+(t.max-t.min)/rateIncreaseSteps))
+}
+
+// throttledTransport is a http.RoundTripper reporting the outcome
+// of every request handed to the next transport to its throttle
+type throttledTransport struct {
+	next     http.RoundTripper
+	throttle *throttle
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	r, err := t.next.RoundTrip(req)
+	// a cancelled request says nothing about the server health
+	if req.Context().Err() != nil {
+		return r, err
+	}
+	status := 0
+	if err == nil {
+		status = r.StatusCode
+	}
+	t.throttle.observe(time.Since(start), status)
+	return r, err
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func Test_throttle_observe(t *testing.T) {
+	type response struct {
+		latency time.Duration
+		status  int
+	}
+	tests := map[string]struct {
+		responses []response
+		want      float64
+	}{
+		"healthy_at_max": {
+			responses: []response{{10 * time.Millisecond, 200}, {10 * time.Millisecond, 200}},
+			want:      10,
+		},
+		"server_error_halves": {
+			responses: []response{{10 * time.Millisecond, 503}},
+			want:      5,
+		},
+		"failed_request_halves": {
+			responses: []response{{10 * time.Millisecond, 0}, {10 * time.Millisecond, 0}},
+			want:      2.5,
+		},
+		"capped_to_min": {
+			responses: []response{{0, 500}, {0, 500}, {0, 500}, {0, 500}, {0, 500}},
+			want:      1,
+		},
+		"client_errors_are_healthy": {
+			responses: []response{{10 * time.Millisecond, 503}, {10 * time.Millisecond, 404}},
+			want:      5.45,
+		},
+		"latency_climbing_halves": {
+			responses: []response{{10 * time.Millisecond, 200}, {200 * time.Millisecond, 200}},
+			want:      5,
+		},
+		"recovery_increases_by_steps": {
+			responses: []response{{0, 503}, {10 * time.Millisecond, 200}, {10 * time.Millisecond, 200}},
+			want:      5.9,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			th := newThrottle(newRateLimiter(1, 1), 1, 10)
+			for _, r := range tt.responses {
+				th.observe(r.latency, r.status)
+			}
+			assert.InDelta(t, tt.want, th.limiter.currentRate(), 0.001)
+		})
+	}
+}
+
+func Test_crawler_Crawl_WithAdaptiveRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.html" {
+			w.Write([]byte(linksPage("index", "p1.html", "p2.html", "p3.html")))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := NewCrawler(WithAdaptiveRateLimit(10, 1000, 1)).(*crawler)
+	err := c.Crawl(context.Background(), getURL(srv.URL+"/index.html"), func(*url.URL, *html.Node) {})
+	assert.Nil(t, err)
+	// each of the 3 server errors halved the rate
+	assert.InDelta(t, 125, c.limiter.currentRate(), 0.001)
+}
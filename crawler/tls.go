@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// tlsVersions maps the TLS versions to their names
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "1.0",
+	tls.VersionTLS11: "1.1",
+	tls.VersionTLS12: "1.2",
+	tls.VersionTLS13: "1.3",
+}
+
+// TLSVersionName returns the name of a TLS version (e.g. `1.2`)
+func TLSVersionName(version uint16) string {
+	if name, ok := tlsVersions[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
+
+// ParseTLSVersion parses a TLS version name (e.g. `1.2`), an empty name is the zero version
+func ParseTLSVersion(name string) (uint16, error) {
+	if name == "" {
+		return 0, nil
+	}
+	for version, n := range tlsVersions {
+		if n == strings.TrimPrefix(name, "TLS") {
+			return version, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown TLS version %q", name)
+}
+
+// TLSInventory builds a validator recording, for each host served over TLS, the
+// negotiated TLS version and cipher suite. One finding is reported per host, on
+// the first crawled page of the host; hosts negotiating a version older than
+// TLS 1.2 are flagged as deprecated with a warning. It is safe for concurrent use
+func TLSInventory() Validator {
+	var mut sync.Mutex
+	seen := make(map[string]struct{})
+	return func(p *Page) []Finding {
+		if p.TLS == nil {
+			return nil
+		}
+		mut.Lock()
+		_, ok := seen[p.URL.Host]
+		seen[p.URL.Host] = struct{}{}
+		mut.Unlock()
+		if ok {
+			return nil
+		}
+		msg := fmt.Sprintf("host %s negotiated TLS %s with %s", p.URL.Host, TLSVersionName(p.TLS.Version), tls.CipherSuiteName(p.TLS.CipherSuite))
+		severity := SeverityInfo
+		if p.TLS.Version < tls.VersionTLS12 {
+			msg += " (deprecated)"
+			severity = SeverityWarning
+		}
+		return []Finding{{URL: p.URL.String(), Validator: "tls", Message: msg, Severity: severity}}
+	}
+}
+
+// transportTLSConfig returns the TLS configuration of t, set to an empty one if missing
+func transportTLSConfig(t *http.Transport) *tls.Config {
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	return t.TLSClientConfig
+}
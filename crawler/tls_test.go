@@ -0,0 +1,178 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTLSTestSite starts a TLS web-server serving pages and accepting at
+// most maxVersion. It returns the server and the pool trusting its certificate
+func newTLSTestSite(t *testing.T, maxVersion uint16, pages map[string]string) (*httptest.Server, *x509.CertPool) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pages[r.URL.Path]))
+	}))
+	srv.TLS = &tls.Config{MaxVersion: maxVersion}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return srv, pool
+}
+
+func Test_ParseTLSVersion(t *testing.T) {
+	v, err := ParseTLSVersion("1.2")
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), v)
+
+	v, err = ParseTLSVersion("TLS1.3")
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), v)
+
+	v, err = ParseTLSVersion("")
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(0), v)
+
+	_, err = ParseTLSVersion("2.0")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "1.1", TLSVersionName(tls.VersionTLS11))
+}
+
+func Test_crawler_Crawl_TLSInventory(t *testing.T) {
+	srv, pool := newTLSTestSite(t, tls.VersionTLS12, map[string]string{
+		"/index.html": linksPage("index", "page1.html"),
+		"/page1.html": linksPage("page1"),
+	})
+
+	var mut sync.Mutex
+	var findings []Finding
+	onFinding := func(f Finding) {
+		mut.Lock()
+		findings = append(findings, f)
+		mut.Unlock()
+	}
+
+	c := NewCrawler(WithTLSVersions(tls.VersionTLS12, 0), WithRootCAs(pool), WithValidators(onFinding, TLSInventory()))
+	err := c.CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(*Page) {})
+	assert.Nil(t, err)
+
+	// a single finding for the host
+	assert.Len(t, findings, 1)
+	assert.Equal(t, "tls", findings[0].Validator)
+	assert.True(t, strings.HasPrefix(findings[0].Message, "host "+getURL(srv.URL).Host+" negotiated TLS 1.2 with "), findings[0].Message)
+}
+
+func Test_crawler_Crawl_TLSMinVersionNotSupported(t *testing.T) {
+	srv, pool := newTLSTestSite(t, tls.VersionTLS12, map[string]string{
+		"/index.html": linksPage("index"),
+	})
+
+	store := NewMemoryStore()
+	c := NewCrawler(WithTLSVersions(tls.VersionTLS13, 0), WithRootCAs(pool), WithStore(store))
+	err := c.CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(*Page) {})
+	assert.Nil(t, err)
+	assert.Equal(t, Stats{Errors: 1}, store.Stats())
+}
+
+func Test_crawler_Crawl_WithHTTP2(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, linksPage(r.Proto))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	tests := map[string]struct {
+		opts []Option
+		want string
+	}{
+		"default": {
+			want: "HTTP/2.0",
+		},
+		"enabled": {
+			opts: []Option{WithHTTP2(true)},
+			want: "HTTP/2.0",
+		},
+		"disabled": {
+			opts: []Option{WithHTTP2(false)},
+			want: "HTTP/1.1",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var protos []string
+			err := NewCrawler(append(tt.opts, WithRootCAs(pool))...).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(p *Page) {
+				mu.Lock()
+				defer mu.Unlock()
+				protos = append(protos, p.Title())
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, []string{tt.want}, protos)
+		})
+	}
+}
+
+func Test_crawler_Crawl_TLSConfig(t *testing.T) {
+	// the server requires a client certificate, any is accepted
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, linksPage("index"))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	// the certificate of the server is presented as client certificate
+	cert := srv.TLS.Certificates[0]
+
+	tests := map[string]struct {
+		opts []Option
+		want Stats
+	}{
+		"untrusted_server": {
+			opts: []Option{WithClientCertificates(cert)},
+			want: Stats{Errors: 1},
+		},
+		"no_client_certificate": {
+			opts: []Option{WithRootCAs(pool)},
+			want: Stats{Errors: 1},
+		},
+		"root_cas": {
+			opts: []Option{WithRootCAs(pool), WithClientCertificates(cert)},
+			want: Stats{Pages: 1},
+		},
+		"insecure_skip_verify": {
+			opts: []Option{WithInsecureSkipVerify(), WithClientCertificates(cert)},
+			want: Stats{Pages: 1},
+		},
+		"tls_config": {
+			opts: []Option{WithTLSConfig(&tls.Config{RootCAs: pool, Certificates: []tls.Certificate{cert}})},
+			want: Stats{Pages: 1},
+		},
+		"tls_config_amended": {
+			opts: []Option{WithTLSConfig(&tls.Config{RootCAs: pool}), WithClientCertificates(cert), WithTLSVersions(tls.VersionTLS12, 0)},
+			want: Stats{Pages: 1},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := NewMemoryStore()
+			err := NewCrawler(append(tt.opts, WithStore(store))...).CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(*Page) {})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, store.Stats())
+		})
+	}
+}
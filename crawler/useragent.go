@@ -0,0 +1,56 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// UserAgentRotation is the scope of the User-Agents rotated by WithUserAgents
+type UserAgentRotation int
+
+const (
+	// PerRequest sends the User-Agents in turn, one per request
+	PerRequest UserAgentRotation = iota
+	// PerHost assigns the User-Agents in turn to the hosts, the requests
+	// to a host are all sent with its User-Agent
+	PerHost
+)
+
+// ParseUserAgentRotation parses a User-Agent rotation name (request or host)
+func ParseUserAgentRotation(name string) (UserAgentRotation, error) {
+	switch name {
+	case "request":
+		return PerRequest, nil
+	case "host":
+		return PerHost, nil
+	}
+	return 0, fmt.Errorf("unknown user-agent rotation %q", name)
+}
+
+// userAgents rotates the User-Agents of the requests of a crawler
+type userAgents struct {
+	agents   []string
+	rotation UserAgentRotation
+
+	mu   sync.Mutex
+	next int
+	// hosts are the User-Agents assigned to the hosts with PerHost
+	hosts map[string]string
+}
+
+// pick returns the User-Agent of the next request to host
+func (a *userAgents) pick(host string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	host = strings.ToLower(host)
+	if agent, ok := a.hosts[host]; ok && a.rotation == PerHost {
+		return agent
+	}
+	agent := a.agents[a.next]
+	a.next = (a.next + 1) % len(a.agents)
+	if a.rotation == PerHost {
+		a.hosts[host] = agent
+	}
+	return agent
+}
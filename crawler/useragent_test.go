@@ -0,0 +1,70 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func Test_userAgents_pick(t *testing.T) {
+	tests := map[string]struct {
+		rotation UserAgentRotation
+		hosts    []string
+		want     []string
+	}{
+		"per_request": {
+			rotation: PerRequest,
+			hosts:    []string{"a.com", "a.com", "b.com", "a.com"},
+			want:     []string{"agent-1", "agent-2", "agent-3", "agent-1"},
+		},
+		"per_host": {
+			rotation: PerHost,
+			hosts:    []string{"a.com", "A.com", "b.com", "c.com", "d.com", "b.com"},
+			want:     []string{"agent-1", "agent-1", "agent-2", "agent-3", "agent-1", "agent-2"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := &userAgents{agents: []string{"agent-1", "agent-2", "agent-3"}, rotation: tt.rotation, hosts: make(map[string]string)}
+			var got []string
+			for _, host := range tt.hosts {
+				got = append(got, a.pick(host))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseUserAgentRotation(t *testing.T) {
+	rotation, err := ParseUserAgentRotation("host")
+	assert.Nil(t, err)
+	assert.Equal(t, PerHost, rotation)
+	_, err = ParseUserAgentRotation("random")
+	assert.NotNil(t, err)
+}
+
+func Test_crawler_CrawlPages_WithUserAgents(t *testing.T) {
+	var mu sync.Mutex
+	agents := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents[r.UserAgent()]++
+		mu.Unlock()
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, linksPage("page", "page1.html", "page2.html", "page3.html"))
+	}))
+	defer srv.Close()
+
+	c := NewCrawler(WithRobotsTxt("web-crawler"), WithUserAgents(PerRequest, "agent-1", "agent-2"))
+	err := c.CrawlPages(context.Background(), getURL(srv.URL+"/index.html"), func(*Page) {})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{DefaultUserAgent: 1, "agent-1": 2, "agent-2": 2}, agents)
+}
@@ -0,0 +1,145 @@
+package crawler
+
+import (
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/net/html"
+	"net/http"
+	"strings"
+)
+
+// Finding is an issue detected on a page by a Validator
+type Finding struct {
+	// URL is the url of the page
+	URL string
+	// Validator is the name of the validator that detected the issue
+	Validator string
+	// Message describes the issue
+	Message string
+	// Severity is the importance of the issue
+	Severity Severity
+}
+
+// ID returns the identifier of f, stable across crawls as long as the page,
+// the validator and the message of the finding are unchanged (e.g. to suppress
+// it, see Suppressions)
+func (f Finding) ID() string {
+	// the NUL separator keeps the fields apart
+	k := newCompactKey(f.Validator + "\x00" + f.URL + "\x00" + f.Message)
+	return hex.EncodeToString(k[:6])
+}
+
+// Validator checks a crawled page and returns the issues found on it
+type Validator func(p *Page) []Finding
+
+// StatusOK reports pages whose HTTP status code is not 2xx
+func StatusOK(p *Page) []Finding {
+	if p.StatusCode >= http.StatusOK && p.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+	return []Finding{{URL: p.URL.String(), Validator: "status-ok", Message: fmt.Sprintf("unexpected status code %d", p.StatusCode), Severity: SeverityError}}
+}
+
+// TitlePresent reports pages without a title or with an empty one
+func TitlePresent(p *Page) []Finding {
+	if p.Title() != "" {
+		return nil
+	}
+	return []Finding{{URL: p.URL.String(), Validator: "title-present", Message: "missing or empty <title>", Severity: SeverityWarning}}
+}
+
+// CanonicalPresent reports pages without a `<link rel="canonical" href="...">` element
+func CanonicalPresent(p *Page) []Finding {
+	if getCanonical(p.Node) != "" {
+		return nil
+	}
+	return []Finding{{URL: p.URL.String(), Validator: "canonical-present", Message: "missing canonical link", Severity: SeverityWarning}}
+}
+
+// NoNoindex builds a validator reporting the pages among urls (e.g. the pages listed
+// in a sitemap) that ask not to be indexed, either with a robots meta tag or with
+// the X-Robots-Tag header
+func NoNoindex(urls ...string) Validator {
+	set := make(map[string]struct{}, len(urls))
+	for _, u := range urls {
+		set[u] = struct{}{}
+	}
+	return func(p *Page) []Finding {
+		if _, ok := set[p.URL.String()]; !ok {
+			return nil
+		}
+		if !isNoindex(p) {
+			return nil
+		}
+		return []Finding{{URL: p.URL.String(), Validator: "no-noindex", Message: "listed page is marked noindex", Severity: SeverityError}}
+	}
+}
+
+// validate runs the validators of the crawler on page reporting the
+// findings, and passes it to the reports of the crawler
+func (c *crawler) validate(page *Page) {
+	for _, r := range c.reports {
+		r.Consume(page)
+	}
+	if c.onFinding == nil {
+		return
+	}
+	for _, v := range c.validators {
+		for _, f := range v(page) {
+			c.onFinding(f)
+		}
+	}
+}
+
+// isNoindex reports whether a page asks not to be indexed
+func isNoindex(p *Page) bool {
+	for _, v := range p.Header.Values("X-Robots-Tag") {
+		if hasNoindex(v) {
+			return true
+		}
+	}
+	return hasNoindex(getMeta(p.Node, "robots"))
+}
+
+// hasNoindex reports whether a robots directives list contains noindex
+func hasNoindex(directives string) bool {
+	for _, d := range strings.Split(directives, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d == "noindex" || d == "none" {
+			return true
+		}
+	}
+	return false
+}
+
+// getCanonical returns the href of the first canonical link element of a page
+func getCanonical(node *html.Node) string {
+	if node == nil {
+		return ""
+	}
+	if node.Type == html.ElementNode && node.Data == "link" && strings.EqualFold(attr(node, "rel"), "canonical") {
+		return attr(node, "href")
+	}
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		if href := getCanonical(n); href != "" {
+			return href
+		}
+	}
+	return ""
+}
+
+// getMeta returns the content of the first meta element of a page with the given name
+func getMeta(node *html.Node, name string) string {
+	if node == nil {
+		return ""
+	}
+	if node.Type == html.ElementNode && node.Data == "meta" && strings.EqualFold(attr(node, "name"), name) {
+		return attr(node, "content")
+	}
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		if content := getMeta(n, name); content != "" {
+			return content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newPage builds a Page out of an html string
+func newPage(t *testing.T, rawURL string, statusCode int, header http.Header, htmlStr string) *Page {
+	node, err := html.Parse(strings.NewReader(htmlStr))
+	assert.Nil(t, err)
+	return &Page{URL: getURL(rawURL), Node: node, StatusCode: statusCode, Header: header}
+}
+
+func Test_Validators(t *testing.T) {
+	const u = "https://my-web-site.com/page"
+	complete := `<html><head>
+					<title>page</title>
+					<link rel="canonical" href="https://my-web-site.com/page">
+				 </head><body></body></html>`
+
+	tests := map[string]struct {
+		validator Validator
+		page      *Page
+		want      []string
+	}{
+		"status_ok_passes": {
+			validator: StatusOK,
+			page:      newPage(t, u, 200, nil, complete),
+		},
+		"status_ok_fails_on_404": {
+			validator: StatusOK,
+			page:      newPage(t, u, 404, nil, complete),
+			want:      []string{"status-ok"},
+		},
+		"title_present_passes": {
+			validator: TitlePresent,
+			page:      newPage(t, u, 200, nil, complete),
+		},
+		"title_present_fails_on_empty_title": {
+			validator: TitlePresent,
+			page:      newPage(t, u, 200, nil, `<html><head><title> </title></head></html>`),
+			want:      []string{"title-present"},
+		},
+		"canonical_present_passes": {
+			validator: CanonicalPresent,
+			page:      newPage(t, u, 200, nil, complete),
+		},
+		"canonical_present_fails": {
+			validator: CanonicalPresent,
+			page:      newPage(t, u, 200, nil, `<html><head><link rel="stylesheet" href="s.css"></head></html>`),
+			want:      []string{"canonical-present"},
+		},
+		"no_noindex_ignores_unlisted_pages": {
+			validator: NoNoindex("https://my-web-site.com/other"),
+			page:      newPage(t, u, 200, nil, `<html><head><meta name="robots" content="noindex"></head></html>`),
+		},
+		"no_noindex_fails_on_meta": {
+			validator: NoNoindex(u),
+			page:      newPage(t, u, 200, nil, `<html><head><meta name="robots" content="follow, NOINDEX"></head></html>`),
+			want:      []string{"no-noindex"},
+		},
+		"no_noindex_fails_on_header": {
+			validator: NoNoindex(u),
+			page:      newPage(t, u, 200, http.Header{"X-Robots-Tag": {"none"}}, complete),
+			want:      []string{"no-noindex"},
+		},
+		"no_noindex_passes": {
+			validator: NoNoindex(u),
+			page:      newPage(t, u, 200, nil, `<html><head><meta name="robots" content="index,follow"></head></html>`),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			for _, f := range tt.validator(tt.page) {
+				assert.Equal(t, u, f.URL)
+				got = append(got, f.Validator)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_crawler_Crawl_WithValidators(t *testing.T) {
+	site := newTestSite(t, map[string]string{
+		"/index.html":   linksPage("index", "notitle.html", "missing.html"),
+		"/notitle.html": `<html><body><a href="index.html">index</a></body></html>`,
+	})
+
+	var mut sync.Mutex
+	var got []string
+	onFinding := func(f Finding) {
+		mut.Lock()
+		defer mut.Unlock()
+		path := getURL(f.URL).Path
+		got = append(got, path+" "+f.Validator)
+	}
+	// a user-defined validator along with the built-in ones
+	custom := func(p *Page) []Finding {
+		if strings.HasSuffix(p.URL.Path, "index.html") {
+			return []Finding{{URL: p.URL.String(), Validator: "custom", Message: "index page"}}
+		}
+		return nil
+	}
+
+	c := NewCrawler(WithValidators(onFinding, StatusOK, TitlePresent, custom))
+	err := c.Crawl(context.Background(), getURL(site.URL+"/index.html"), func(*url.URL, *html.Node) {})
+	assert.Nil(t, err)
+
+	sort.Strings(got)
+	assert.Equal(t, []string{
+		"/index.html custom",
+		"/missing.html status-ok",
+		"/missing.html title-present",
+		"/notitle.html title-present",
+	}, got)
+}
@@ -0,0 +1,125 @@
+package crawler
+
+import (
+	"container/heap"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// PageWeight is the size of the body of a crawled page
+type PageWeight struct {
+	// URL is the URL of the page
+	URL string
+	// Bytes is the size of the body of the page
+	Bytes int64
+}
+
+// SectionWeight is the total size of the pages of a section (path prefix) of a site
+type SectionWeight struct {
+	// Prefix is the path prefix of the section (e.g. `/docs/`)
+	Prefix string
+	// Pages is the number of pages crawled under the section
+	Pages int
+	// Bytes is the total size of their bodies
+	Bytes int64
+}
+
+// Weights tracks the largest pages and the heaviest sections of the crawls
+// using it (see WithWeights), e.g. to check performance budgets. The sections
+// are all the directories of the pages paths but the root one. It is safe for
+// concurrent use
+type Weights struct {
+	top      int
+	mu       sync.Mutex
+	largest  pageWeights
+	sections map[string]*SectionWeight
+}
+
+// NewWeights creates a Weights reporting the top largest pages and heaviest sections
+func NewWeights(top int) *Weights {
+	return &Weights{top: top, sections: make(map[string]*SectionWeight)}
+}
+
+// record tracks the body of bytes bytes of the page at u
+func (w *Weights) record(u *url.URL, bytes int64) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.top > 0 {
+		// largest is a min-heap of the top largest pages
+		if len(w.largest) < w.top {
+			heap.Push(&w.largest, PageWeight{URL: u.String(), Bytes: bytes})
+		} else if bytes > w.largest[0].Bytes {
+			w.largest[0] = PageWeight{URL: u.String(), Bytes: bytes}
+			heap.Fix(&w.largest, 0)
+		}
+	}
+	for i := 1; i < len(u.Path); i++ {
+		if u.Path[i] != '/' {
+			continue
+		}
+		prefix := u.Path[:i+1]
+		section, ok := w.sections[prefix]
+		if !ok {
+			section = &SectionWeight{Prefix: prefix}
+			w.sections[prefix] = section
+		}
+		section.Pages++
+		section.Bytes += bytes
+	}
+}
+
+// Largest returns the top largest pages, largest first
+func (w *Weights) Largest() []PageWeight {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	largest := append([]PageWeight(nil), w.largest...)
+	sort.Slice(largest, func(i, j int) bool {
+		if largest[i].Bytes != largest[j].Bytes {
+			return largest[i].Bytes > largest[j].Bytes
+		}
+		return largest[i].URL < largest[j].URL
+	})
+	return largest
+}
+
+// Heaviest returns the top sections with the highest total size, heaviest first
+func (w *Weights) Heaviest() []SectionWeight {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sections := make([]SectionWeight, 0, len(w.sections))
+	for _, section := range w.sections {
+		sections = append(sections, *section)
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		if sections[i].Bytes != sections[j].Bytes {
+			return sections[i].Bytes > sections[j].Bytes
+		}
+		return sections[i].Prefix < sections[j].Prefix
+	})
+	if len(sections) > w.top {
+		sections = sections[:w.top]
+	}
+	return sections
+}
+
+// pageWeights is a min-heap of PageWeight, it implements heap.Interface
+type pageWeights []PageWeight
+
+func (h pageWeights) Len() int           { return len(h) }
+func (h pageWeights) Less(i, j int) bool { return h[i].Bytes < h[j].Bytes }
+func (h pageWeights) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *pageWeights) Push(x interface{}) {
+	*h = append(*h, x.(PageWeight))
+}
+
+func (h *pageWeights) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWeights(t *testing.T) {
+	w := NewWeights(2)
+	w.record(getURL("https://my-web-site.com/index.html"), 10)
+	w.record(getURL("https://my-web-site.com/docs/a.html"), 100)
+	w.record(getURL("https://my-web-site.com/docs/api/b.html"), 300)
+	w.record(getURL("https://my-web-site.com/blog/c.html"), 200)
+	w.record(getURL("https://my-web-site.com/blog/d.html"), 50)
+
+	assert.Equal(t, []PageWeight{
+		{URL: "https://my-web-site.com/docs/api/b.html", Bytes: 300},
+		{URL: "https://my-web-site.com/blog/c.html", Bytes: 200},
+	}, w.Largest())
+	assert.Equal(t, []SectionWeight{
+		{Prefix: "/docs/", Pages: 2, Bytes: 400},
+		{Prefix: "/docs/api/", Pages: 1, Bytes: 300},
+	}, w.Heaviest())
+
+	var nilWeights *Weights
+	nilWeights.record(getURL("https://my-web-site.com/"), 1)
+}
+
+func Test_crawler_CrawlPages_WithWeights(t *testing.T) {
+	index := linksPage("index", "docs/page1.html")
+	page1 := linksPage("page1")
+	site := newTestSite(t, map[string]string{
+		"/index.html":      index,
+		"/docs/page1.html": page1,
+	})
+
+	w := NewWeights(10)
+	err := NewCrawler(WithWeights(w), WithParseConcurrency(1, 1)).CrawlPages(context.Background(), getURL(site.URL+"/index.html"), func(*Page) {})
+	assert.Nil(t, err)
+	assert.Equal(t, []PageWeight{
+		{URL: site.URL + "/index.html", Bytes: int64(len(index))},
+		{URL: site.URL + "/docs/page1.html", Bytes: int64(len(page1))},
+	}, w.Largest())
+	assert.Equal(t, []SectionWeight{{Prefix: "/docs/", Pages: 1, Bytes: int64(len(page1))}}, w.Heaviest())
+}
@@ -0,0 +1,191 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/rbroggi/crawler/crawler"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Rule is a named pattern identifying a secret or a token
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// DefaultRules are the built-in rules used by the Scanner
+var DefaultRules = []Rule{
+	{Name: "aws-access-key-id", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "aws-secret-access-key", Pattern: regexp.MustCompile(`(?i)aws_?secret_?access_?key["'\s:=]+[A-Za-z0-9/+=]{40}`)},
+	{Name: "google-api-key", Pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{Name: "slack-token", Pattern: regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z\-]{10,}\b`)},
+	{Name: "github-token", Pattern: regexp.MustCompile(`\bgh[pousr]_[0-9A-Za-z]{36}\b`)},
+	{Name: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`)},
+	{Name: "private-key", Pattern: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH )?PRIVATE KEY-----`)},
+	{Name: "generic-api-key", Pattern: regexp.MustCompile(`(?i)\b(?:api|access|secret)[_\-]?(?:key|token)["'\s:=]+[A-Za-z0-9_\-]{16,}`)},
+}
+
+// Finding is a match of a Rule found while scanning a page
+type Finding struct {
+	// Page is the url of the crawled page
+	Page string
+	// Source is the url of the scanned resource: the page itself
+	// or a javascript file linked by the page
+	Source string
+	// Rule is the name of the matched rule
+	Rule string
+	// Match is the matched text
+	Match string
+}
+
+// Scanner detects leaked secrets and tokens in crawled pages. Its Visit method
+// can be used as the visit function of a crawler.Crawler. The html of the page
+// (inline javascript included) and the same-domain javascript files linked by the
+// page are scanned. It is safe for concurrent use
+type Scanner struct {
+	ctx    context.Context
+	client *http.Client
+	rules  []Rule
+
+	mut      sync.Mutex
+	scripts  map[string]struct{}
+	findings map[Finding]struct{}
+}
+
+// NewScanner creates a Scanner using the DefaultRules, the linked javascript files
+// are downloaded with client, e.g. the one built by crawler.NewHTTPClient so that
+// they are requested the way the pages are, and ctx is used to cancel the downloads.
+// A nil client means http.DefaultClient
+func NewScanner(ctx context.Context, client *http.Client) *Scanner {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Scanner{
+		ctx:      ctx,
+		client:   client,
+		rules:    DefaultRules,
+		scripts:  make(map[string]struct{}),
+		findings: make(map[Finding]struct{}),
+	}
+}
+
+// Visit scans a page and its same-domain linked javascript files
+func (s *Scanner) Visit(u *url.URL, page *html.Node) {
+	var b bytes.Buffer
+	if err := html.Render(&b, page); err != nil {
+		log.Errorf("failed to render page %s", u)
+		return
+	}
+	s.scan(u.String(), u.String(), b.Bytes())
+
+	resolver := crawler.NewLinkResolver(u)
+	for _, src := range getScriptSources(page) {
+		abs, err := resolver.Resolve(src)
+		if err != nil {
+			log.Errorf("failed to get absolute link on page %s with script source %s", u, src)
+			continue
+		}
+		// external scripts are out of scope
+		if abs.Host != u.Host || !s.markScript(abs.String()) {
+			continue
+		}
+		content, err := s.getScript(abs.String())
+		if err != nil {
+			log.Errorf("failed to get script %s - %v", abs, err)
+			continue
+		}
+		s.scan(u.String(), abs.String(), content)
+	}
+}
+
+// Findings returns the findings collected so far sorted by page, source and rule
+func (s *Scanner) Findings() []Finding {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	findings := make([]Finding, 0, len(s.findings))
+	for f := range s.findings {
+		findings = append(findings, f)
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Page != b.Page {
+			return a.Page < b.Page
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Match < b.Match
+	})
+	return findings
+}
+
+// scan applies all the rules to content and records the findings
+func (s *Scanner) scan(page, source string, content []byte) {
+	for _, r := range s.rules {
+		for _, m := range r.Pattern.FindAll(content, -1) {
+			s.mut.Lock()
+			s.findings[Finding{Page: page, Source: source, Rule: r.Name, Match: string(m)}] = struct{}{}
+			s.mut.Unlock()
+		}
+	}
+}
+
+// markScript records a script as scanned, it returns false
+// if the script had already been scanned
+func (s *Scanner) markScript(src string) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if _, ok := s.scripts[src]; ok {
+		return false
+	}
+	s.scripts[src] = struct{}{}
+	return true
+}
+
+// getScript downloads the raw content of a javascript file
+func (s *Scanner) getScript(src string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(s.ctx, "GET", src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error while preparing request - %v", err)
+	}
+	r, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting script - %v", err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", r.StatusCode)
+	}
+	return ioutil.ReadAll(r.Body)
+}
+
+// getScriptSources retrieves the src attribute of all the
+// <script> elements of a page
+func getScriptSources(node *html.Node) []string {
+	var srcs []string
+	if node == nil {
+		return srcs
+	}
+	if node.Type == html.ElementNode && node.Data == "script" {
+		for _, a := range node.Attr {
+			if a.Key == "src" && a.Val != "" {
+				srcs = append(srcs, a.Val)
+			}
+		}
+	}
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		srcs = append(srcs, getScriptSources(n)...)
+	}
+	return srcs
+}